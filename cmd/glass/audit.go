@@ -0,0 +1,59 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"time"
+
+	"github.com/timgst1/glass/internal/audit"
+	"github.com/timgst1/glass/internal/storage/sqlite"
+)
+
+func runAudit(args []string) error {
+	if len(args) == 0 || args[0] != "verify" {
+		return fmt.Errorf("usage: glass audit verify [--db path] [--expect-head seq:hash]")
+	}
+
+	fs := flag.NewFlagSet("audit verify", flag.ContinueOnError)
+	dbPath := fs.String("db", getenvDefault("SQLITE_PATH", "./data/glass.db"), "Path to sqlite db file")
+	expectHead := fs.String("expect-head", "", "Previously recorded head (seq:hash); detects truncated entries at the end")
+
+	if err := fs.Parse(args[1:]); err != nil {
+		return err
+	}
+
+	db, err := sqlite.Open(*dbPath)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Minute)
+	defer cancel()
+
+	res, err := audit.Verify(ctx, db)
+	if err != nil {
+		return fmt.Errorf("audit verify failed after %d valid entries: %w", res.Entries, err)
+	}
+
+	head := fmt.Sprintf("%d:%s", res.HeadSeq, res.HeadHash)
+	if *expectHead != "" {
+		var seq int64
+		var hash string
+		if _, err := fmt.Sscanf(*expectHead, "%d:%s", &seq, &hash); err != nil {
+			return fmt.Errorf("invalid --expect-head (want seq:hash)")
+		}
+		if seq > res.HeadSeq {
+			return fmt.Errorf("audit verify failed: %w: log ends at seq %d, expected at least %d (entries truncated)", audit.ErrTampered, res.HeadSeq, seq)
+		}
+		// die Kette ist bis HeadSeq geprüft -> der frühere Head muss noch mit gleichem Hash enthalten sein
+		var got string
+		if err := db.QueryRowContext(ctx, `SELECT hash FROM audit_log WHERE seq = ?`, seq).Scan(&got); err != nil || got != hash {
+			return fmt.Errorf("audit verify failed: %w: entry %d differs from expected head", audit.ErrTampered, seq)
+		}
+	}
+
+	fmt.Printf("audit log ok: entries=%d head=%s\n", res.Entries, head)
+	return nil
+}
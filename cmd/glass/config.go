@@ -0,0 +1,39 @@
+package main
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/timgst1/glass/internal/app"
+)
+
+func runConfig(args []string) error {
+	if len(args) == 0 || args[0] != "check" {
+		return fmt.Errorf("usage: glass config check [--config file] [--<setting> value ...]")
+	}
+
+	cfg, err := app.LoadConfig(args[1:])
+	if err != nil {
+		return err
+	}
+	if err := cfg.WriteEffective(os.Stdout); err != nil {
+		return err
+	}
+	fmt.Println()
+
+	results := app.CheckConfig(cfg)
+	failed := 0
+	for _, r := range results {
+		if r.Err != nil {
+			failed++
+			fmt.Printf("FAIL %s: %v\n", r.Name, r.Err)
+			continue
+		}
+		fmt.Printf("ok   %s\n", r.Name)
+	}
+	if failed > 0 {
+		return fmt.Errorf("config check failed: %d of %d checks", failed, len(results))
+	}
+	fmt.Println("config ok")
+	return nil
+}
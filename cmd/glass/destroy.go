@@ -0,0 +1,80 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/timgst1/glass/internal/service"
+	"github.com/timgst1/glass/internal/storage/sqlite"
+)
+
+func runDestroy(args []string) error {
+	fs := flag.NewFlagSet("destroy", flag.ContinueOnError)
+
+	dbPath := fs.String("db", getenvDefault("SQLITE_PATH", "./data/glass.db"), "Path to sqlite db file")
+	key := fs.String("key", "", "Secret key [required]")
+	versions := fs.String("versions", "", "Comma separated list of versions to destroy (e.g. 1,2,5)")
+	all := fs.Bool("all", false, "Destroy all versions of the key")
+	by := fs.String("by", "cli:admin", "Recorded as destroyed_by")
+	dryRun := fs.Bool("dry-run", false, "Only report how many versions would be destroyed")
+
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	*key = strings.TrimPrefix(strings.TrimSpace(*key), "/")
+	if *key == "" {
+		return fmt.Errorf("--key is required")
+	}
+	if *all == (*versions != "") {
+		return fmt.Errorf("exactly one of --versions or --all is required")
+	}
+
+	var vers []int64
+	if *versions != "" {
+		for _, p := range strings.Split(*versions, ",") {
+			v, err := strconv.ParseInt(strings.TrimSpace(p), 10, 64)
+			if err != nil || v < 1 {
+				return fmt.Errorf("invalid version %q in --versions", p)
+			}
+			vers = append(vers, v)
+		}
+	}
+
+	db, err := sqlite.Open(*dbPath)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	// Ensure schema has the destroy columns
+	if err := sqlite.Migrate(db); err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Minute)
+	defer cancel()
+
+	res, err := service.DestroyVersions(ctx, db, service.DestroyOptions{
+		Key:         *key,
+		Versions:    vers,
+		All:         *all,
+		DestroyedBy: *by,
+		DryRun:      *dryRun,
+	})
+	if err != nil {
+		return err
+	}
+
+	if *dryRun {
+		fmt.Printf("dry-run: would destroy %d versions of key=%q\n", res.Matched, *key)
+		return nil
+	}
+
+	fmt.Printf("destroy complete: matched=%d destroyed=%d key=%q\n", res.Matched, res.Destroyed, *key)
+	return nil
+}
@@ -6,34 +6,60 @@ import (
 	"net/http"
 	"os"
 	"os/signal"
+	"strings"
 	"syscall"
 	"time"
 
 	"github.com/timgst1/glass/internal/app"
+	"github.com/timgst1/glass/internal/logging"
 )
 
 func main() {
-	if len(os.Args) > 1 {
+	// ohne Subcommand (oder nur mit Flags wie --config) startet der Server
+	if len(os.Args) > 1 && !strings.HasPrefix(os.Args[1], "-") {
 		switch os.Args[1] {
 		case "rewrap-kek":
 			if err := runRewrapKek(os.Args[2:]); err != nil {
 				log.Fatal(err)
 			}
 			return
+		case "destroy":
+			if err := runDestroy(os.Args[2:]); err != nil {
+				log.Fatal(err)
+			}
+			return
+		case "prune":
+			if err := runPrune(os.Args[2:]); err != nil {
+				log.Fatal(err)
+			}
+			return
+		case "audit":
+			if err := runAudit(os.Args[2:]); err != nil {
+				log.Fatal(err)
+			}
+			return
+		case "config":
+			if err := runConfig(os.Args[2:]); err != nil {
+				log.Fatal(err)
+			}
+			return
 		default:
-			log.Fatalf("unkown command: %s (supported: rewrap-kek)", os.Args[1])
+			log.Fatalf("unkown command: %s (supported: rewrap-kek, destroy, prune, audit, config)", os.Args[1])
 		}
 	}
-	if err := runServer(); err != nil {
+	if err := runServer(os.Args[1:]); err != nil {
 		log.Fatal(err)
 	}
 }
 
-func runServer() error {
-	cfg, err := app.LoadConfig()
+func runServer(args []string) error {
+	cfg, err := app.LoadConfig(args)
 	if err != nil {
 		return err
 	}
+	if err := logging.Setup(cfg.LOG_LEVEL, cfg.LOG_FORMAT, os.Stderr); err != nil {
+		return err
+	}
 
 	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
 	defer stop()
@@ -42,21 +68,43 @@ func runServer() error {
 	if err != nil {
 		return err
 	}
-	if rt.DB != nil {
-		defer rt.DB.Close()
-	}
-
-	// Shutdown on signal
+	// Shutdown on signal; done schließt erst, wenn Server gedrained, Audit/DB geschlossen und Spans exportiert sind
+	done := make(chan struct{})
 	go func() {
+		defer close(done)
 		<-ctx.Done()
-		shutdownCtx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		timeout, _ := time.ParseDuration(cfg.SHUTDOWN_TIMEOUT)
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), timeout)
 		defer cancel()
 		_ = rt.Server.Shutdown(shutdownCtx)
+		if rt.MetricsServer != nil {
+			_ = rt.MetricsServer.Shutdown(shutdownCtx)
+		}
+		// Jobs, Audit und DB erst nach dem Draining beenden: laufende Requests schreiben noch Audit-Einträge
+		rt.Stop()
+		if rt.Audit != nil {
+			_ = rt.Audit.Close()
+		}
+		if rt.DB != nil {
+			_ = rt.DB.Close()
+		}
+		_ = rt.ShutdownTracing(shutdownCtx)
 	}()
 
+	if rt.MetricsServer != nil {
+		go func() {
+			if err := rt.MetricsServer.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+				log.Printf("metrics listener: %v", err)
+			}
+		}()
+	}
+
+	// ListenAndServe kehrt zurück, sobald Shutdown beginnt – erst nach dem geordneten Shutdown beenden
 	err = rt.Server.ListenAndServe()
-	if err != nil && err != http.ErrServerClosed {
-		return err
+	if err == http.ErrServerClosed {
+		err = nil
 	}
-	return nil
+	stop()
+	<-done
+	return err
 }
@@ -0,0 +1,63 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/timgst1/glass/internal/retention"
+	"github.com/timgst1/glass/internal/storage/sqlite"
+)
+
+func runPrune(args []string) error {
+	fs := flag.NewFlagSet("prune", flag.ContinueOnError)
+
+	dbPath := fs.String("db", getenvDefault("SQLITE_PATH", "./data/glass.db"), "Path to sqlite db file")
+	rulesPath := fs.String("rules", os.Getenv("RETENTION_FILE"), "Retention rules file (YAML) [required]")
+	batch := fs.Int("batch", 500, "Batch size (keys per transaction)")
+	dryRun := fs.Bool("dry-run", false, "Only report how many versions would be removed")
+
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	if *rulesPath == "" {
+		return fmt.Errorf("--rules (or env RETENTION_FILE) is required")
+	}
+
+	rules, err := retention.LoadFromFile(*rulesPath)
+	if err != nil {
+		return err
+	}
+
+	db, err := sqlite.Open(*dbPath)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	if err := sqlite.Migrate(db); err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Minute)
+	defer cancel()
+
+	res, err := retention.Prune(ctx, db, rules, retention.PruneOptions{
+		BatchSize: *batch,
+		DryRun:    *dryRun,
+	})
+	if err != nil {
+		return err
+	}
+
+	if *dryRun {
+		fmt.Printf("dry-run: would prune %d versions across %d keys\n", res.Pruned, res.Keys)
+		return nil
+	}
+
+	fmt.Printf("prune complete: pruned=%d keys=%d\n", res.Pruned, res.Keys)
+	return nil
+}
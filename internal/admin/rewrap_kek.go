@@ -6,6 +6,7 @@ import (
 	"fmt"
 
 	"github.com/timgst1/glass/internal/crypto/envelope"
+	"github.com/timgst1/glass/internal/webhook"
 )
 
 type RewrapKEKOptions struct {
@@ -48,9 +49,17 @@ func RewrapKEK(ctx context.Context, db *sql.DB, env *envelope.Envelope, opt Rewr
 		return RewrapKEKResult{}, err
 	}
 
+	var webhookTotal int
+	if err := db.QueryRowContext(ctx,
+		`SELECT COUNT(*) FROM webhook_subscriptions WHERE secret_enc=1 AND secret_kek_id=?`,
+		opt.FromKEKID,
+	).Scan(&webhookTotal); err != nil {
+		return RewrapKEKResult{}, err
+	}
+
 	// Dry-run: just report
 	if opt.DryRun {
-		return RewrapKEKResult{Matched: total, Updated: 0}, nil
+		return RewrapKEKResult{Matched: total + webhookTotal, Updated: 0}, nil
 	}
 
 	res := RewrapKEKResult{Matched: total, Updated: 0}
@@ -146,5 +155,70 @@ WHERE key=? AND version=?;`,
 		}
 	}
 
+	if webhookTotal > 0 {
+		res.Matched += webhookTotal
+		n, err := rewrapWebhookSecrets(ctx, db, env, opt)
+		res.Updated += n
+		if err != nil {
+			return res, err
+		}
+	}
 	return res, nil
 }
+
+// rewrapWebhookSecrets: HMAC-Secrets der Webhook-Subscriptions (wenige Zeilen, eine Tx)
+func rewrapWebhookSecrets(ctx context.Context, db *sql.DB, env *envelope.Envelope, opt RewrapKEKOptions) (int, error) {
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, err
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	rows, err := tx.QueryContext(ctx, `
+SELECT id, secret_wrapped_dek, secret_wrap_nonce
+FROM webhook_subscriptions
+WHERE secret_enc=1 AND secret_kek_id=?
+ORDER BY id;`, opt.FromKEKID)
+	if err != nil {
+		return 0, err
+	}
+	type row struct {
+		ID         string
+		WrappedDEK string
+		WrapNonce  string
+	}
+	var batch []row
+	for rows.Next() {
+		var r row
+		if err := rows.Scan(&r.ID, &r.WrappedDEK, &r.WrapNonce); err != nil {
+			rows.Close()
+			return 0, err
+		}
+		batch = append(batch, r)
+	}
+	if err := rows.Err(); err != nil {
+		rows.Close()
+		return 0, err
+	}
+	_ = rows.Close()
+
+	for _, r := range batch {
+		ev := envelope.EncryptedValue{Enc: 1, KekID: opt.FromKEKID, WrappedDEK: r.WrappedDEK, WrapNonce: r.WrapNonce}
+		newEV, err := env.RewrapDEK(webhook.SecretAADKey(r.ID), 0, ev, opt.ToKEKID)
+		if err != nil {
+			return 0, fmt.Errorf("rewrap webhook %s: %w", r.ID, err)
+		}
+		if _, err := tx.ExecContext(ctx, `
+UPDATE webhook_subscriptions
+SET secret_wrapped_dek=?, secret_wrap_nonce=?, secret_kek_id=?
+WHERE id=?;`,
+			newEV.WrappedDEK, newEV.WrapNonce, newEV.KekID, r.ID,
+		); err != nil {
+			return 0, err
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		return 0, err
+	}
+	return len(batch), nil
+}
@@ -4,25 +4,55 @@ import (
 	"context"
 	"database/sql"
 	"fmt"
+	"io"
 	"net/http"
+	"strconv"
 	"time"
 
+	"github.com/timgst1/glass/internal/audit"
 	"github.com/timgst1/glass/internal/authn"
 	"github.com/timgst1/glass/internal/authz"
 	"github.com/timgst1/glass/internal/crypto/envelope"
+	"github.com/timgst1/glass/internal/health"
 	"github.com/timgst1/glass/internal/httpapi"
+	"github.com/timgst1/glass/internal/httpapi/handlers"
+	"github.com/timgst1/glass/internal/metrics"
 	"github.com/timgst1/glass/internal/policy"
+	"github.com/timgst1/glass/internal/retention"
+	"github.com/timgst1/glass/internal/rotation"
 	"github.com/timgst1/glass/internal/service"
 	"github.com/timgst1/glass/internal/storage/sqlite"
+	"github.com/timgst1/glass/internal/tracing"
+	"github.com/timgst1/glass/internal/webhook"
 )
 
 type Runtime struct {
 	Server        *http.Server
 	PolicyManager *policy.Manager
 	DB            *sql.DB
+	// MetricsServer liefert /metrics auf METRICS_ADDR (nil = /metrics am Haupt-Listener)
+	MetricsServer *http.Server
+	// ShutdownTracing exportiert offene Spans und beendet den Exporter
+	ShutdownTracing func(context.Context) error
+	// Audit leert und schließt die Audit-Sinks beim Beenden (nil ohne AUDIT_SINKS_FILE)
+	Audit io.Closer
+	// Stop beendet die Hintergrundjobs (Policy-Watcher, Pruner, Reaper, Dispatcher, Rotator) vor dem Schließen der DB
+	Stop context.CancelFunc
 }
 
-func Build(ctx context.Context, cfg Config) (*Runtime, error) {
+func Build(parent context.Context, cfg Config) (_ *Runtime, err error) {
+	// schlägt Build nach dem Start von Jobs oder dem Öffnen der DB fehl, wird alles bis dahin Gestartete wieder beendet
+	ctx, cancel := context.WithCancel(parent)
+	var cleanup []func()
+	defer func() {
+		if err != nil {
+			cancel()
+			for i := len(cleanup) - 1; i >= 0; i-- {
+				cleanup[i]()
+			}
+		}
+	}()
+
 	var a authn.Authenticator
 
 	switch cfg.AUTH_MODE {
@@ -38,13 +68,49 @@ func Build(ctx context.Context, cfg Config) (*Runtime, error) {
 		return nil, fmt.Errorf("invalid AUTH_MODE: %q", cfg.AUTH_MODE)
 	}
 
+	sampleRatio, _ := strconv.ParseFloat(cfg.TRACING_SAMPLE_RATIO, 64)
+	otlpHeaders, _ := tracing.ParseHeaders(cfg.TRACING_OTLP_HEADERS)
+	shutdownTracing, err := tracing.Setup(ctx, tracing.Options{
+		Exporter:    cfg.TRACING_EXPORTER,
+		SampleRatio: sampleRatio,
+		IncludeKeys: cfg.TRACING_INCLUDE_KEYS == "true",
+		Endpoint:    cfg.TRACING_OTLP_ENDPOINT,
+		Headers:     otlpHeaders,
+	})
+	if err != nil {
+		return nil, err
+	}
+	cleanup = append(cleanup, func() { _ = shutdownTracing(context.Background()) })
+
 	pm := policy.NewManager(cfg.POLICY_FILE)
 	if err := pm.Start(ctx); err != nil {
 		return nil, err
 	}
 
+	// Audit-Sinks vor dem Storage starten: fail-closed Sinks sollen ab dem ersten Request greifen
+	var auditSinks []*audit.BufferedSink
+	if cfg.AUDIT_SINKS_FILE != "" {
+		sinkCfgs, err := audit.LoadSinksFromFile(cfg.AUDIT_SINKS_FILE)
+		if err != nil {
+			return nil, err
+		}
+		cleanup = append(cleanup, func() { _ = audit.NewMulti(nil, auditSinks).Close() })
+		for _, sc := range sinkCfgs {
+			sink, err := audit.NewSink(sc)
+			if err != nil {
+				return nil, err
+			}
+			auditSinks = append(auditSinks, sink)
+		}
+	}
+
 	var db *sql.DB
 	var secretSvc service.SecretService
+	var reaper service.ExpiryReaper
+	var webhooks *webhook.Store
+	var sqliteAudit *audit.SQLiteLog
+	readyChecks := []health.Check{health.Policy(pm)}
+	var secretStats metrics.SecretStatsFunc
 
 	switch cfg.STORAGE_BACKEND {
 	case "sqlite":
@@ -52,8 +118,8 @@ func Build(ctx context.Context, cfg Config) (*Runtime, error) {
 		if err != nil {
 			return nil, err
 		}
+		cleanup = append(cleanup, func() { _ = d.Close() })
 		if err := sqlite.Migrate(d); err != nil {
-			_ = d.Close()
 			return nil, err
 		}
 		db = d
@@ -62,43 +128,136 @@ func Build(ctx context.Context, cfg Config) (*Runtime, error) {
 		if cfg.ENCRYPTION_MODE == "envelope" {
 			kr, err := envelope.LoadKeyring(cfg.KEK_DIR, cfg.ACTIVE_KEK_ID)
 			if err != nil {
-				_ = d.Close()
 				return nil, err
 			}
 			enc = envelope.New(kr)
+			readyChecks = append(readyChecks, health.Keyring(enc))
 		}
+		minFreeMB, _ := strconv.ParseUint(cfg.READINESS_MIN_FREE_MB, 10, 64)
+		readyChecks = append(readyChecks, health.SQLite(db), health.DiskSpace(cfg.SQLITE_PATH, minFreeMB<<20))
 
-		secretSvc = service.NewSQLiteSecretService(db, enc)
+		sqliteSvc := service.NewSQLiteSecretService(db, enc)
+		secretSvc = sqliteSvc
+		reaper = sqliteSvc
+		secretStats = sqliteSvc.Stats
+
+		if cfg.RETENTION_FILE != "" {
+			rules, err := retention.LoadFromFile(cfg.RETENTION_FILE)
+			if err != nil {
+				return nil, err
+			}
+			interval, _ := time.ParseDuration(cfg.RETENTION_INTERVAL)
+			retention.NewPruner(db, rules, interval).Start(ctx)
+		}
+
+		// Webhooks: Outbox liegt in SQLite, daher nur mit diesem Backend
+		var fileSubs []webhook.Subscription
+		if cfg.WEBHOOKS_FILE != "" {
+			fileSubs, err = webhook.LoadFromFile(cfg.WEBHOOKS_FILE)
+			if err != nil {
+				return nil, err
+			}
+		}
+		if cfg.AUDIT_ENABLED == "true" {
+			sqliteAudit = audit.NewSQLiteLog(db)
+		}
+
+		webhooks = webhook.NewStore(db, fileSubs, enc)
+		interval, _ := time.ParseDuration(cfg.WEBHOOK_INTERVAL)
+		webhook.NewDispatcher(db, webhooks, sqliteSvc, interval).Start(ctx)
 
 	case "memory":
-		secretSvc = service.NewMemorySecretService(map[string]string{"demo": "hello"})
+		memSvc := service.NewMemorySecretService(map[string]string{"demo": "hello"})
+		secretSvc = memSvc
+		reaper = memSvc
+		secretStats = memSvc.Stats
 
 	default:
 		return nil, fmt.Errorf("invalid STORAGE_BACKEND: %q", cfg.STORAGE_BACKEND)
 	}
 
+	metrics.SetSecretStats(secretStats)
+
+	reapInterval, _ := time.ParseDuration(cfg.EXPIRY_REAP_INTERVAL)
+	service.StartExpiryReaper(ctx, reaper, reapInterval)
+
+	var rotationRules *rotation.Rules
+	if cfg.ROTATION_FILE != "" {
+		rules, err := rotation.LoadFromFile(cfg.ROTATION_FILE)
+		if err != nil {
+			return nil, err
+		}
+		rotationRules = rules
+		// Rotator arbeitet auf dem ungesicherten Service (vor NewSecuredSecretService)
+		interval, _ := time.ParseDuration(cfg.ROTATION_INTERVAL)
+		rotation.NewRotator(secretSvc, rules, interval).Start(ctx)
+	}
+
+	var auditLog audit.Logger
+	var auditCloser io.Closer
+	switch {
+	case len(auditSinks) > 0:
+		m := audit.NewMulti(sqliteAudit, auditSinks)
+		auditLog, auditCloser = m, m
+		readyChecks = append(readyChecks, health.AuditSinks(m))
+	case sqliteAudit != nil:
+		auditLog = sqliteAudit
+	}
+
 	az := authz.NewRuntimeAuthorizer(pm)
 	secretSvc = service.NewSecuredSecretService(secretSvc, az)
 
-	h := httpapi.NewRouter(httpapi.Deps{
+	// offene Watch-Streams beim Shutdown beenden, sonst wartet Shutdown bis zum Timeout
+	watchDone := make(chan struct{})
+
+	deps := httpapi.Deps{
 		SecretService: secretSvc,
 		Authenticator: a,
-	})
+		Rotation:      rotationRules,
+		Watch:         handlers.WatchOptions{Done: watchDone},
+		Audit:         auditLog,
+		Readiness:     health.NewChecker(cfg.READINESS_STRICT == "true", readyChecks...),
+	}
+	var metricsSrv *http.Server
+	if cfg.METRICS_ADDR == "" {
+		deps.Metrics = metrics.Handler()
+	} else {
+		mux := http.NewServeMux()
+		mux.Handle("/metrics", metrics.Handler())
+		metricsSrv = &http.Server{Addr: cfg.METRICS_ADDR, Handler: mux, ReadTimeout: 10 * time.Second, WriteTimeout: 10 * time.Second}
+	}
+	if webhooks != nil {
+		deps.Webhooks = webhook.NewSecuredRegistry(webhooks, az)
+	}
+	h := httpapi.NewRouter(deps)
 
 	srv := BuildServer(cfg, h)
+	srv.RegisterOnShutdown(func() { close(watchDone) })
 
 	return &Runtime{
-		Server:        srv,
-		PolicyManager: pm,
-		DB:            db,
+		Server:          srv,
+		PolicyManager:   pm,
+		DB:              db,
+		MetricsServer:   metricsSrv,
+		ShutdownTracing: shutdownTracing,
+		Audit:           auditCloser,
+		Stop:            cancel,
 	}, nil
 }
 
+// BuildServer setzt die Timeouts aus HTTP_*_TIMEOUT; Streaming-Routen (/v1/watch) heben den WriteTimeout pro
+// Verbindung über http.ResponseController wieder auf.
 func BuildServer(cfg Config, h http.Handler) *http.Server {
+	readTimeout, _ := time.ParseDuration(cfg.HTTP_READ_TIMEOUT)
+	readHeaderTimeout, _ := time.ParseDuration(cfg.HTTP_READ_HEADER_TIMEOUT)
+	writeTimeout, _ := time.ParseDuration(cfg.HTTP_WRITE_TIMEOUT)
+	idleTimeout, _ := time.ParseDuration(cfg.HTTP_IDLE_TIMEOUT)
 	return &http.Server{
-		Addr:         cfg.HTTP_ADDR,
-		Handler:      h,
-		ReadTimeout:  10 * time.Second,
-		WriteTimeout: 10 * time.Second,
+		Addr:              cfg.HTTP_ADDR,
+		Handler:           h,
+		ReadTimeout:       readTimeout,
+		ReadHeaderTimeout: readHeaderTimeout,
+		WriteTimeout:      writeTimeout,
+		IdleTimeout:       idleTimeout,
 	}
 }
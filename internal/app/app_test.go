@@ -0,0 +1,35 @@
+package app_test
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+
+	"github.com/timgst1/glass/internal/app"
+)
+
+func TestBuild_TwiceInOneProcessAndCleanupOnError(t *testing.T) {
+	dir := t.TempDir()
+	t.Setenv("AUTH_MODE", "noop")
+	t.Setenv("POLICY_FILE", writeFile(t, dir, "policy.yaml", "apiVersion: glass/v1\nkind: Policy\n"))
+	t.Setenv("SQLITE_PATH", filepath.Join(dir, "glass.db"))
+
+	cfg, err := app.LoadConfig(nil)
+	if err != nil {
+		t.Fatalf("LoadConfig: %v", err)
+	}
+	for i := 0; i < 2; i++ {
+		rt, err := app.Build(context.Background(), cfg)
+		if err != nil {
+			t.Fatalf("Build #%d: %v", i+1, err)
+		}
+		rt.Stop()
+		_ = rt.DB.Close()
+	}
+
+	// Fehler nach dem Öffnen der DB: Build liefert den Fehler und räumt DB und Jobs selbst ab
+	cfg.ROTATION_FILE = filepath.Join(dir, "missing-rotation.yaml")
+	if _, err := app.Build(context.Background(), cfg); err == nil {
+		t.Fatalf("expected error for missing rotation file")
+	}
+}
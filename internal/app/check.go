@@ -0,0 +1,107 @@
+package app
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/timgst1/glass/internal/audit"
+	"github.com/timgst1/glass/internal/authn"
+	"github.com/timgst1/glass/internal/crypto/envelope"
+	"github.com/timgst1/glass/internal/policy"
+	"github.com/timgst1/glass/internal/retention"
+	"github.com/timgst1/glass/internal/rotation"
+	"github.com/timgst1/glass/internal/webhook"
+)
+
+// CheckResult ist eine Prüfung von `glass config check` (Err == nil = ok)
+type CheckResult struct {
+	Name string
+	Err  error
+}
+
+// CheckConfig lädt alles, was Build aus cfg referenziert, ohne etwas zu starten: Token-Datei, Policy, Keyring,
+// Regel-Dateien und Schreibrechte auf den DB-Pfad. Die DB selbst wird weder angelegt noch migriert.
+func CheckConfig(cfg Config) []CheckResult {
+	var res []CheckResult
+	add := func(name string, err error) {
+		res = append(res, CheckResult{Name: name, Err: err})
+	}
+
+	if cfg.AUTH_MODE == "bearer" {
+		_, err := authn.NewBearerFromFile(cfg.AUTH_TOKEN_FILE)
+		add("auth token file "+cfg.AUTH_TOKEN_FILE, err)
+	}
+	_, err := policy.LoadFromFile(cfg.POLICY_FILE)
+	add("policy "+cfg.POLICY_FILE, err)
+
+	// wie Build: Keyring, Retention und Webhooks gibt es nur mit SQLite
+	if cfg.STORAGE_BACKEND == "sqlite" {
+		add("sqlite path "+cfg.SQLITE_PATH, checkWritable(cfg.SQLITE_PATH))
+		if cfg.ENCRYPTION_MODE == "envelope" {
+			_, err := envelope.LoadKeyring(cfg.KEK_DIR, cfg.ACTIVE_KEK_ID)
+			add("keyring "+cfg.KEK_DIR, err)
+		}
+		if cfg.RETENTION_FILE != "" {
+			_, err := retention.LoadFromFile(cfg.RETENTION_FILE)
+			add("retention "+cfg.RETENTION_FILE, err)
+		}
+		if cfg.WEBHOOKS_FILE != "" {
+			_, err := webhook.LoadFromFile(cfg.WEBHOOKS_FILE)
+			add("webhooks "+cfg.WEBHOOKS_FILE, err)
+		}
+	}
+	if cfg.ROTATION_FILE != "" {
+		_, err := rotation.LoadFromFile(cfg.ROTATION_FILE)
+		add("rotation "+cfg.ROTATION_FILE, err)
+	}
+	if cfg.AUDIT_SINKS_FILE != "" {
+		_, err := audit.LoadSinksFromFile(cfg.AUDIT_SINKS_FILE)
+		add("audit sinks "+cfg.AUDIT_SINKS_FILE, err)
+	}
+	return res
+}
+
+// checkWritable: SQLite braucht neben der Datei auch das Verzeichnis (WAL- und SHM-Dateien).
+// Fehlende Verzeichnisse legt sqlite.Open an, dann zählt der nächste vorhandene Vorfahr.
+func checkWritable(path string) error {
+	if st, err := os.Stat(path); err == nil {
+		if st.IsDir() {
+			return fmt.Errorf("%s is a directory", path)
+		}
+		f, err := os.OpenFile(path, os.O_WRONLY, 0)
+		if err != nil {
+			return err
+		}
+		_ = f.Close()
+	} else if !os.IsNotExist(err) {
+		return err
+	}
+
+	dir := filepath.Dir(path)
+	for {
+		st, err := os.Stat(dir)
+		if err == nil {
+			if !st.IsDir() {
+				return fmt.Errorf("%s is not a directory", dir)
+			}
+			break
+		}
+		if !os.IsNotExist(err) {
+			return err
+		}
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return err
+		}
+		dir = parent
+	}
+
+	f, err := os.CreateTemp(dir, ".glass-check-*")
+	if err != nil {
+		return fmt.Errorf("directory %s not writable: %w", dir, err)
+	}
+	name := f.Name()
+	_ = f.Close()
+	return os.Remove(name)
+}
@@ -2,65 +2,162 @@ package app
 
 import (
 	"fmt"
-	"os"
+	"net/url"
+	"strconv"
 	"strings"
+	"time"
+
+	"github.com/timgst1/glass/internal/tracing"
 )
 
 type Config struct {
-	HTTP_ADDR        string
-	HTTP_PORT        string
-	LOG_LEVEL        string
+	HTTP_ADDR string
+	HTTP_PORT string
+	// HTTP_*_TIMEOUT: Timeouts des Haupt-Listeners (Go-Durations)
+	HTTP_READ_TIMEOUT        string
+	HTTP_READ_HEADER_TIMEOUT string
+	HTTP_WRITE_TIMEOUT       string
+	HTTP_IDLE_TIMEOUT        string
+	LOG_LEVEL                string
+	LOG_FORMAT               string
+	// SHUTDOWN_TIMEOUT: Wartezeit auf laufende Requests beim Beenden (Duration; Zahl ohne Einheit = Sekunden)
 	SHUTDOWN_TIMEOUT string
 	READINESS_STRICT string
-	AUTH_TOKEN_FILE  string
-	AUTH_MODE        string
-	POLICY_FILE      string
-	STORAGE_BACKEND  string
-	SQLITE_PATH      string
+	// READINESS_MIN_FREE_MB: freier Platz auf dem DB-Volume, darunter ist /readyz degraded
+	READINESS_MIN_FREE_MB string
+	AUTH_TOKEN_FILE       string
+	AUTH_MODE             string
+	POLICY_FILE           string
+	STORAGE_BACKEND       string
+	SQLITE_PATH           string
 
 	ENCRYPTION_MODE string
 	KEK_DIR         string
 	ACTIVE_KEK_ID   string
+
+	RETENTION_FILE     string
+	RETENTION_INTERVAL string
+
+	EXPIRY_REAP_INTERVAL string
+
+	ROTATION_FILE     string
+	ROTATION_INTERVAL string
+
+	WEBHOOKS_FILE    string
+	WEBHOOK_INTERVAL string
+
+	AUDIT_ENABLED    string
+	AUDIT_SINKS_FILE string
+
+	METRICS_ADDR string
+
+	TRACING_EXPORTER     string
+	TRACING_SAMPLE_RATIO string
+	TRACING_INCLUDE_KEYS string
+	// TRACING_OTLP_*: überschreiben OTEL_EXPORTER_OTLP_ENDPOINT/_HEADERS; Headers enthalten meist Credentials
+	TRACING_OTLP_ENDPOINT string
+	TRACING_OTLP_HEADERS  string
+
+	// file und sources nur für `glass config check` (Herkunft der Werte)
+	file    string
+	sources map[string]string
 }
 
-func LoadConfig() (Config, error) {
-	var cfg Config
+// LoadConfig liest die Einstellungen mit steigender Priorität aus Defaults, Config-Datei (--config bzw.
+// CONFIG_FILE), Env-Variablen und Flags (--http-addr, --log-level, …; siehe settings).
+func LoadConfig(args []string) (Config, error) {
+	src, err := newSources(args)
+	if err != nil {
+		return Config{}, err
+	}
+	cfg := Config{file: src.path, sources: src.origin}
 
 	//HTTP_PORT Parsing
-	cfg.HTTP_PORT = os.Getenv("HTTP_PORT")
+	cfg.HTTP_PORT = src.get("HTTP_PORT")
 	if cfg.HTTP_PORT == "" {
 		cfg.HTTP_PORT = "8080"
 	}
 
 	//HTTP_ADDR Parsing
-	cfg.HTTP_ADDR = os.Getenv("HTTP_ADDR")
+	cfg.HTTP_ADDR = src.get("HTTP_ADDR")
 	if cfg.HTTP_ADDR == "" {
 		cfg.HTTP_ADDR = "0.0.0.0:" + cfg.HTTP_PORT
 	}
 
+	//HTTP Timeouts
+	for _, t := range []struct {
+		field     *string
+		name, def string
+	}{
+		{&cfg.HTTP_READ_TIMEOUT, "HTTP_READ_TIMEOUT", "10s"},
+		{&cfg.HTTP_READ_HEADER_TIMEOUT, "HTTP_READ_HEADER_TIMEOUT", "5s"},
+		{&cfg.HTTP_WRITE_TIMEOUT, "HTTP_WRITE_TIMEOUT", "10s"},
+		{&cfg.HTTP_IDLE_TIMEOUT, "HTTP_IDLE_TIMEOUT", "60s"},
+	} {
+		*t.field = src.get(t.name)
+		if *t.field == "" {
+			*t.field = t.def
+		}
+		if d, err := time.ParseDuration(*t.field); err != nil || d <= 0 {
+			return Config{}, fmt.Errorf("invalid %s: %q", t.name, *t.field)
+		}
+	}
+
 	//LOG_LEVEL Parsing
-	cfg.LOG_LEVEL = os.Getenv("LOG_LEVEL")
+	cfg.LOG_LEVEL = src.get("LOG_LEVEL")
 	if cfg.LOG_LEVEL == "" {
 		cfg.LOG_LEVEL = "info"
 	}
+	switch cfg.LOG_LEVEL {
+	case "debug", "info", "warn", "error":
+	default:
+		return Config{}, fmt.Errorf("invalid LOG_LEVEL: %q (allowed: debug, info, warn, error)", cfg.LOG_LEVEL)
+	}
+
+	//LOG_FORMAT: text (Default) oder json
+	cfg.LOG_FORMAT = src.get("LOG_FORMAT")
+	if cfg.LOG_FORMAT == "" {
+		cfg.LOG_FORMAT = "text"
+	}
+	switch cfg.LOG_FORMAT {
+	case "text", "json":
+	default:
+		return Config{}, fmt.Errorf("invalid LOG_FORMAT: %q (allowed: text, json)", cfg.LOG_FORMAT)
+	}
 
 	//SHUTDOWN_TIMEOUT Parsing
-	cfg.SHUTDOWN_TIMEOUT = os.Getenv("SHUTDOWN_TIMEOUT")
+	cfg.SHUTDOWN_TIMEOUT = src.get("SHUTDOWN_TIMEOUT")
 	if cfg.SHUTDOWN_TIMEOUT == "" {
-		cfg.SHUTDOWN_TIMEOUT = "10"
+		cfg.SHUTDOWN_TIMEOUT = "10s"
+	}
+	cfg.SHUTDOWN_TIMEOUT = bareSeconds(cfg.SHUTDOWN_TIMEOUT)
+	if d, err := time.ParseDuration(cfg.SHUTDOWN_TIMEOUT); err != nil || d <= 0 {
+		return Config{}, fmt.Errorf("invalid SHUTDOWN_TIMEOUT: %q", cfg.SHUTDOWN_TIMEOUT)
 	}
 
 	//READINESS_STRICT
-	cfg.READINESS_STRICT = os.Getenv("READINESS_STRICT")
+	cfg.READINESS_STRICT = src.get("READINESS_STRICT")
 	if cfg.READINESS_STRICT == "" {
 		cfg.READINESS_STRICT = "true"
 	}
+	switch cfg.READINESS_STRICT {
+	case "true", "false":
+	default:
+		return Config{}, fmt.Errorf("invalid READINESS_STRICT: %q (allowed: true, false)", cfg.READINESS_STRICT)
+	}
+	cfg.READINESS_MIN_FREE_MB = src.get("READINESS_MIN_FREE_MB")
+	if cfg.READINESS_MIN_FREE_MB == "" {
+		cfg.READINESS_MIN_FREE_MB = "100"
+	}
+	if _, err := strconv.ParseUint(cfg.READINESS_MIN_FREE_MB, 10, 64); err != nil {
+		return Config{}, fmt.Errorf("invalid READINESS_MIN_FREE_MB: %q", cfg.READINESS_MIN_FREE_MB)
+	}
 
 	//AUTH_TOKEN_FILE
-	cfg.AUTH_TOKEN_FILE = os.Getenv("AUTH_TOKEN_FILE")
+	cfg.AUTH_TOKEN_FILE = src.get("AUTH_TOKEN_FILE")
 
 	//AUTH_MODE
-	cfg.AUTH_MODE = os.Getenv("AUTH_MODE")
+	cfg.AUTH_MODE = src.get("AUTH_MODE")
 	if cfg.AUTH_MODE == "" {
 		cfg.AUTH_MODE = "bearer"
 	}
@@ -76,24 +173,24 @@ func LoadConfig() (Config, error) {
 	}
 
 	//POLICY_FILE
-	cfg.POLICY_FILE = os.Getenv("POLICY_FILE")
+	cfg.POLICY_FILE = src.get("POLICY_FILE")
 	if cfg.POLICY_FILE == "" {
 		return Config{}, fmt.Errorf("POLICY_FILE is required")
 	}
 
 	//STORAGE_BACKEND
-	cfg.STORAGE_BACKEND = os.Getenv("STORAGE_BACKEND")
+	cfg.STORAGE_BACKEND = src.get("STORAGE_BACKEND")
 	if cfg.STORAGE_BACKEND == "" {
 		cfg.STORAGE_BACKEND = "sqlite"
 	}
 
 	//SQLITE_PATH
-	cfg.SQLITE_PATH = os.Getenv("SQLITE_PATH")
+	cfg.SQLITE_PATH = src.get("SQLITE_PATH")
 	if cfg.SQLITE_PATH == "" {
 		cfg.SQLITE_PATH = "./data/glass.db"
 	}
 
-	cfg.ENCRYPTION_MODE = os.Getenv("ENCRYPTION_MODE")
+	cfg.ENCRYPTION_MODE = src.get("ENCRYPTION_MODE")
 	if cfg.ENCRYPTION_MODE == "" {
 		cfg.ENCRYPTION_MODE = "none"
 	}
@@ -103,8 +200,8 @@ func LoadConfig() (Config, error) {
 		return Config{}, fmt.Errorf("invalid ENCRYPTION_MODE: %q (allowed: none, envelope)", cfg.ENCRYPTION_MODE)
 	}
 
-	cfg.KEK_DIR = os.Getenv("KEK_DIR")
-	cfg.ACTIVE_KEK_ID = os.Getenv("ACTIVE_KEK_ID")
+	cfg.KEK_DIR = src.get("KEK_DIR")
+	cfg.ACTIVE_KEK_ID = src.get("ACTIVE_KEK_ID")
 	if cfg.ACTIVE_KEK_ID == "" {
 		cfg.ACTIVE_KEK_ID = "default"
 	}
@@ -113,5 +210,114 @@ func LoadConfig() (Config, error) {
 			return Config{}, fmt.Errorf("KEK_DIR is required when ENCRYPTION_MODE=envelope")
 		}
 	}
+
+	//RETENTION_FILE (optional, nur sqlite)
+	cfg.RETENTION_FILE = src.get("RETENTION_FILE")
+	cfg.RETENTION_INTERVAL = src.get("RETENTION_INTERVAL")
+	if cfg.RETENTION_INTERVAL == "" {
+		cfg.RETENTION_INTERVAL = "1h"
+	}
+	if d, err := time.ParseDuration(cfg.RETENTION_INTERVAL); err != nil || d <= 0 {
+		return Config{}, fmt.Errorf("invalid RETENTION_INTERVAL: %q", cfg.RETENTION_INTERVAL)
+	}
+
+	//EXPIRY_REAP_INTERVAL: wie oft abgelaufene Secrets getombstoned werden
+	cfg.EXPIRY_REAP_INTERVAL = src.get("EXPIRY_REAP_INTERVAL")
+	if cfg.EXPIRY_REAP_INTERVAL == "" {
+		cfg.EXPIRY_REAP_INTERVAL = "1m"
+	}
+	if d, err := time.ParseDuration(cfg.EXPIRY_REAP_INTERVAL); err != nil || d <= 0 {
+		return Config{}, fmt.Errorf("invalid EXPIRY_REAP_INTERVAL: %q", cfg.EXPIRY_REAP_INTERVAL)
+	}
+
+	//ROTATION_FILE (optional): Regeln für automatische Rotation, ROTATION_INTERVAL = Prüfintervall
+	cfg.ROTATION_FILE = src.get("ROTATION_FILE")
+	cfg.ROTATION_INTERVAL = src.get("ROTATION_INTERVAL")
+	if cfg.ROTATION_INTERVAL == "" {
+		cfg.ROTATION_INTERVAL = "1m"
+	}
+	if d, err := time.ParseDuration(cfg.ROTATION_INTERVAL); err != nil || d <= 0 {
+		return Config{}, fmt.Errorf("invalid ROTATION_INTERVAL: %q", cfg.ROTATION_INTERVAL)
+	}
+
+	//WEBHOOKS_FILE (optional, nur sqlite): Subscriptions aus Datei, WEBHOOK_INTERVAL = Zustellintervall der Outbox
+	cfg.WEBHOOKS_FILE = src.get("WEBHOOKS_FILE")
+	cfg.WEBHOOK_INTERVAL = src.get("WEBHOOK_INTERVAL")
+	if cfg.WEBHOOK_INTERVAL == "" {
+		cfg.WEBHOOK_INTERVAL = "5s"
+	}
+	if d, err := time.ParseDuration(cfg.WEBHOOK_INTERVAL); err != nil || d <= 0 {
+		return Config{}, fmt.Errorf("invalid WEBHOOK_INTERVAL: %q", cfg.WEBHOOK_INTERVAL)
+	}
+	if cfg.WEBHOOKS_FILE != "" && cfg.STORAGE_BACKEND != "sqlite" {
+		return Config{}, fmt.Errorf("WEBHOOKS_FILE requires STORAGE_BACKEND=sqlite")
+	}
+
+	//AUDIT_ENABLED: hash-verkettetes Audit-Log in SQLite (Tabelle audit_log)
+	cfg.AUDIT_ENABLED = src.get("AUDIT_ENABLED")
+	if cfg.AUDIT_ENABLED == "" {
+		cfg.AUDIT_ENABLED = "true"
+	}
+	switch cfg.AUDIT_ENABLED {
+	case "true", "false":
+	default:
+		return Config{}, fmt.Errorf("invalid AUDIT_ENABLED: %q (allowed: true, false)", cfg.AUDIT_ENABLED)
+	}
+
+	//AUDIT_SINKS_FILE (optional): zusätzliche Audit-Ziele (file, stdout, syslog), unabhängig vom Backend
+	cfg.AUDIT_SINKS_FILE = src.get("AUDIT_SINKS_FILE")
+
+	//METRICS_ADDR (optional): eigener Listener für /metrics (z.B. "0.0.0.0:9090"); leer = /metrics am Haupt-Listener
+	cfg.METRICS_ADDR = src.get("METRICS_ADDR")
+	if cfg.METRICS_ADDR != "" && cfg.METRICS_ADDR == cfg.HTTP_ADDR {
+		return Config{}, fmt.Errorf("METRICS_ADDR must differ from HTTP_ADDR")
+	}
+
+	//TRACING_EXPORTER: none, otlp (Ziel über OTEL_EXPORTER_OTLP_ENDPOINT) oder stdout (lokal testen)
+	cfg.TRACING_EXPORTER = src.get("TRACING_EXPORTER")
+	if cfg.TRACING_EXPORTER == "" {
+		cfg.TRACING_EXPORTER = "none"
+	}
+	switch cfg.TRACING_EXPORTER {
+	case "none", "otlp", "stdout":
+	default:
+		return Config{}, fmt.Errorf("invalid TRACING_EXPORTER: %q (allowed: none, otlp, stdout)", cfg.TRACING_EXPORTER)
+	}
+	cfg.TRACING_SAMPLE_RATIO = src.get("TRACING_SAMPLE_RATIO")
+	if cfg.TRACING_SAMPLE_RATIO == "" {
+		cfg.TRACING_SAMPLE_RATIO = "1"
+	}
+	if r, err := strconv.ParseFloat(cfg.TRACING_SAMPLE_RATIO, 64); err != nil || r < 0 || r > 1 {
+		return Config{}, fmt.Errorf("invalid TRACING_SAMPLE_RATIO: %q (0..1)", cfg.TRACING_SAMPLE_RATIO)
+	}
+	//TRACING_INCLUDE_KEYS: Secret-Keys als Span-Attribut (Werte nie)
+	cfg.TRACING_INCLUDE_KEYS = src.get("TRACING_INCLUDE_KEYS")
+	if cfg.TRACING_INCLUDE_KEYS == "" {
+		cfg.TRACING_INCLUDE_KEYS = "false"
+	}
+	switch cfg.TRACING_INCLUDE_KEYS {
+	case "true", "false":
+	default:
+		return Config{}, fmt.Errorf("invalid TRACING_INCLUDE_KEYS: %q (allowed: true, false)", cfg.TRACING_INCLUDE_KEYS)
+	}
+	cfg.TRACING_OTLP_ENDPOINT = src.get("TRACING_OTLP_ENDPOINT")
+	if cfg.TRACING_OTLP_ENDPOINT != "" {
+		if u, err := url.Parse(cfg.TRACING_OTLP_ENDPOINT); err != nil || u.Scheme == "" || u.Host == "" {
+			return Config{}, fmt.Errorf("invalid TRACING_OTLP_ENDPOINT: %q (want URL, e.g. https://otel-collector:4318)", cfg.TRACING_OTLP_ENDPOINT)
+		}
+	}
+	cfg.TRACING_OTLP_HEADERS = src.get("TRACING_OTLP_HEADERS")
+	// Wert nicht in die Fehlermeldung: enthält typischerweise Tokens
+	if _, err := tracing.ParseHeaders(cfg.TRACING_OTLP_HEADERS); err != nil {
+		return Config{}, fmt.Errorf("invalid TRACING_OTLP_HEADERS: %w", err)
+	}
 	return cfg, nil
 }
+
+// bareSeconds: früher nur Sekunden – "10" bleibt gültig und wird zu "10s"
+func bareSeconds(v string) string {
+	if n, err := strconv.Atoi(v); err == nil {
+		return strconv.Itoa(n) + "s"
+	}
+	return v
+}
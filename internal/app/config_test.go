@@ -0,0 +1,144 @@
+package app_test
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/timgst1/glass/internal/app"
+)
+
+func writeFile(t *testing.T, dir, name, content string) string {
+	t.Helper()
+	p := filepath.Join(dir, name)
+	if err := os.WriteFile(p, []byte(content), 0o600); err != nil {
+		t.Fatalf("WriteFile: %v", err)
+	}
+	return p
+}
+
+func TestLoadConfig_FileEnvFlagPrecedence(t *testing.T) {
+	dir := t.TempDir()
+	cfgPath := writeFile(t, dir, "glass.yaml", `
+http:
+  port: 9000
+  readTimeout: 30s
+shutdownTimeout: 45s
+log:
+  level: debug
+  format: json
+auth:
+  mode: noop
+policy:
+  file: /etc/glass/policy.yaml
+readiness:
+  strict: false
+  minFreeMB: 0
+tracing:
+  otlpHeaders: "authorization=Bearer s3cr3t"
+`)
+	t.Setenv("LOG_LEVEL", "warn")
+	t.Setenv("SHUTDOWN_TIMEOUT", "20")
+
+	cfg, err := app.LoadConfig([]string{"--config", cfgPath, "--http-write-timeout", "1m"})
+	if err != nil {
+		t.Fatalf("LoadConfig: %v", err)
+	}
+	if cfg.HTTP_ADDR != "0.0.0.0:9000" || cfg.HTTP_READ_TIMEOUT != "30s" || cfg.HTTP_WRITE_TIMEOUT != "1m" || cfg.HTTP_IDLE_TIMEOUT != "60s" {
+		t.Fatalf("unexpected http settings: %+v", cfg)
+	}
+	// Env schlägt Datei; Sekunden ohne Einheit bleiben gültig
+	if cfg.LOG_LEVEL != "warn" || cfg.LOG_FORMAT != "json" || cfg.SHUTDOWN_TIMEOUT != "20s" {
+		t.Fatalf("unexpected overrides: level=%q format=%q shutdown=%q", cfg.LOG_LEVEL, cfg.LOG_FORMAT, cfg.SHUTDOWN_TIMEOUT)
+	}
+	if cfg.READINESS_STRICT != "false" || cfg.READINESS_MIN_FREE_MB != "0" || cfg.POLICY_FILE != "/etc/glass/policy.yaml" {
+		t.Fatalf("unexpected file values: %+v", cfg)
+	}
+
+	var buf bytes.Buffer
+	if err := cfg.WriteEffective(&buf); err != nil {
+		t.Fatalf("WriteEffective: %v", err)
+	}
+	out := buf.String()
+	for _, want := range []string{
+		"level: warn # LOG_LEVEL (env)",
+		"writeTimeout: 1m # HTTP_WRITE_TIMEOUT (flag)",
+		"readTimeout: 30s # HTTP_READ_TIMEOUT (file)",
+		"idleTimeout: 60s # HTTP_IDLE_TIMEOUT (default)",
+		"otlpHeaders: <redacted>",
+	} {
+		if !strings.Contains(out, want) {
+			t.Fatalf("expected %q in effective config:\n%s", want, out)
+		}
+	}
+	if strings.Contains(out, "s3cr3t") {
+		t.Fatalf("effective config must not contain secrets:\n%s", out)
+	}
+}
+
+func TestLoadConfig_ShutdownTimeoutSecondsInFile(t *testing.T) {
+	t.Setenv("AUTH_MODE", "noop")
+	t.Setenv("POLICY_FILE", "/etc/glass/policy.yaml")
+
+	// wie SHUTDOWN_TIMEOUT=10 aus Env oder Flag: Zahl ohne Einheit = Sekunden
+	cfgPath := writeFile(t, t.TempDir(), "glass.yaml", "shutdownTimeout: 10\n")
+	cfg, err := app.LoadConfig([]string{"--config", cfgPath})
+	if err != nil {
+		t.Fatalf("LoadConfig: %v", err)
+	}
+	if cfg.SHUTDOWN_TIMEOUT != "10s" {
+		t.Fatalf("expected shutdown timeout 10s, got %q", cfg.SHUTDOWN_TIMEOUT)
+	}
+}
+
+func TestLoadConfig_RejectsInvalidFile(t *testing.T) {
+	t.Setenv("AUTH_MODE", "noop")
+	t.Setenv("POLICY_FILE", "/etc/glass/policy.yaml")
+	dir := t.TempDir()
+
+	for content, want := range map[string]string{
+		"http:\n  readTimout: 10s\n":  `line 2: unknown key "http.readTimout"`,
+		"readiness:\n  strict: yes\n": "readiness.strict: expected bool",
+		"http:\n  idleTimeout: 60\n":  "http.idleTimeout: expected duration",
+		"audit:\n  sinksFile: [a]\n":  "audit.sinksFile: expected string",
+		"log:\n  level: verbose\n":    "invalid LOG_LEVEL",
+	} {
+		_, err := app.LoadConfig([]string{"--config", writeFile(t, dir, "glass.yaml", content)})
+		if err == nil || !strings.Contains(err.Error(), want) {
+			t.Fatalf("config %q: expected error containing %q, got %v", content, want, err)
+		}
+	}
+}
+
+func TestCheckConfig_ReportsEachReference(t *testing.T) {
+	dir := t.TempDir()
+	t.Setenv("AUTH_TOKEN_FILE", writeFile(t, dir, "token", "ci:tok\n"))
+	t.Setenv("POLICY_FILE", writeFile(t, dir, "policy.yaml", "apiVersion: glass/v1\n"))
+	t.Setenv("SQLITE_PATH", filepath.Join(dir, "data", "glass.db"))
+	t.Setenv("ENCRYPTION_MODE", "envelope")
+	t.Setenv("KEK_DIR", filepath.Join(dir, "keks"))
+
+	cfg, err := app.LoadConfig(nil)
+	if err != nil {
+		t.Fatalf("LoadConfig: %v", err)
+	}
+	got := map[string]error{}
+	for _, r := range app.CheckConfig(cfg) {
+		got[strings.Fields(r.Name)[0]] = r.Err
+	}
+	if len(got) != 4 {
+		t.Fatalf("expected auth, policy, sqlite and keyring checks, got %v", got)
+	}
+	if got["auth"] != nil || got["sqlite"] != nil {
+		t.Fatalf("expected token file and db path ok, got %v", got)
+	}
+	// Policy ohne kind, KEK_DIR existiert nicht
+	if got["policy"] == nil || got["keyring"] == nil {
+		t.Fatalf("expected policy and keyring failures, got %v", got)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "data")); !os.IsNotExist(err) {
+		t.Fatalf("check must not create the db directory")
+	}
+}
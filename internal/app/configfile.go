@@ -0,0 +1,295 @@
+package app
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+type settingKind int
+
+const (
+	kindString settingKind = iota
+	kindDuration
+	kindBool
+	kindUint
+	kindFloat
+)
+
+func (k settingKind) String() string {
+	switch k {
+	case kindDuration:
+		return "duration (e.g. 30s)"
+	case kindBool:
+		return "bool"
+	case kindUint:
+		return "unsigned integer"
+	case kindFloat:
+		return "number"
+	default:
+		return "string"
+	}
+}
+
+// setting verbindet ein Config-Feld (Name = Env-Variable) mit seinem Pfad in der Config-Datei
+type setting struct {
+	env  string
+	path string
+	kind settingKind
+	// secret: wird von `glass config check` nicht ausgegeben
+	secret bool
+	// seconds: Zahl ohne Einheit = Sekunden (Altlast, gilt für Datei, Env und Flag gleich)
+	seconds bool
+}
+
+// settings legt Reihenfolge und Struktur der Config-Datei fest; aus env entsteht auch das Flag (HTTP_ADDR -> --http-addr)
+var settings = []setting{
+	{env: "HTTP_ADDR", path: "http.addr"},
+	{env: "HTTP_PORT", path: "http.port"},
+	{env: "HTTP_READ_TIMEOUT", path: "http.readTimeout", kind: kindDuration},
+	{env: "HTTP_READ_HEADER_TIMEOUT", path: "http.readHeaderTimeout", kind: kindDuration},
+	{env: "HTTP_WRITE_TIMEOUT", path: "http.writeTimeout", kind: kindDuration},
+	{env: "HTTP_IDLE_TIMEOUT", path: "http.idleTimeout", kind: kindDuration},
+	{env: "SHUTDOWN_TIMEOUT", path: "shutdownTimeout", kind: kindDuration, seconds: true},
+	{env: "LOG_LEVEL", path: "log.level"},
+	{env: "LOG_FORMAT", path: "log.format"},
+	{env: "READINESS_STRICT", path: "readiness.strict", kind: kindBool},
+	{env: "READINESS_MIN_FREE_MB", path: "readiness.minFreeMB", kind: kindUint},
+	{env: "AUTH_MODE", path: "auth.mode"},
+	{env: "AUTH_TOKEN_FILE", path: "auth.tokenFile"},
+	{env: "POLICY_FILE", path: "policy.file"},
+	{env: "STORAGE_BACKEND", path: "storage.backend"},
+	{env: "SQLITE_PATH", path: "storage.sqlitePath"},
+	{env: "ENCRYPTION_MODE", path: "encryption.mode"},
+	{env: "KEK_DIR", path: "encryption.kekDir"},
+	{env: "ACTIVE_KEK_ID", path: "encryption.activeKekId"},
+	{env: "RETENTION_FILE", path: "retention.file"},
+	{env: "RETENTION_INTERVAL", path: "retention.interval", kind: kindDuration},
+	{env: "EXPIRY_REAP_INTERVAL", path: "expiry.reapInterval", kind: kindDuration},
+	{env: "ROTATION_FILE", path: "rotation.file"},
+	{env: "ROTATION_INTERVAL", path: "rotation.interval", kind: kindDuration},
+	{env: "WEBHOOKS_FILE", path: "webhooks.file"},
+	{env: "WEBHOOK_INTERVAL", path: "webhooks.interval", kind: kindDuration},
+	{env: "AUDIT_ENABLED", path: "audit.enabled", kind: kindBool},
+	{env: "AUDIT_SINKS_FILE", path: "audit.sinksFile"},
+	{env: "METRICS_ADDR", path: "metrics.addr"},
+	{env: "TRACING_EXPORTER", path: "tracing.exporter"},
+	{env: "TRACING_SAMPLE_RATIO", path: "tracing.sampleRatio", kind: kindFloat},
+	{env: "TRACING_INCLUDE_KEYS", path: "tracing.includeKeys", kind: kindBool},
+	{env: "TRACING_OTLP_ENDPOINT", path: "tracing.otlpEndpoint"},
+	{env: "TRACING_OTLP_HEADERS", path: "tracing.otlpHeaders", secret: true},
+}
+
+func flagName(env string) string {
+	return strings.ToLower(strings.ReplaceAll(env, "_", "-"))
+}
+
+// sources liefert pro Einstellung den Wert mit der höchsten Priorität: Flag > Env > Datei
+type sources struct {
+	path   string
+	flags  map[string]string
+	file   map[string]string
+	origin map[string]string
+}
+
+func newSources(args []string) (*sources, error) {
+	s := &sources{flags: map[string]string{}, file: map[string]string{}, origin: map[string]string{}}
+
+	fs := flag.NewFlagSet("glass", flag.ContinueOnError)
+	fs.StringVar(&s.path, "config", os.Getenv("CONFIG_FILE"), "Config file (YAML), env CONFIG_FILE")
+	for _, st := range settings {
+		fs.Func(flagName(st.env), fmt.Sprintf("%s (env %s)", st.path, st.env), func(v string) error {
+			s.flags[st.env] = v
+			return nil
+		})
+	}
+	if err := fs.Parse(args); err != nil {
+		return nil, err
+	}
+	if fs.NArg() > 0 {
+		return nil, fmt.Errorf("unexpected argument: %q", fs.Arg(0))
+	}
+
+	if s.path != "" {
+		if err := s.loadFile(); err != nil {
+			return nil, err
+		}
+	}
+	return s, nil
+}
+
+// get behandelt leere Env-Variablen wie nicht gesetzt (wie bisher os.Getenv)
+func (s *sources) get(env string) string {
+	if v, ok := s.flags[env]; ok {
+		s.origin[env] = "flag"
+		return v
+	}
+	if v := os.Getenv(env); v != "" {
+		s.origin[env] = "env"
+		return v
+	}
+	if v, ok := s.file[env]; ok {
+		s.origin[env] = "file"
+		return v
+	}
+	return ""
+}
+
+// loadFile prüft Schlüssel und Typen; die Werte landen als Strings in s.file und durchlaufen danach
+// dieselbe Validierung wie Env-Variablen
+func (s *sources) loadFile() error {
+	b, err := os.ReadFile(s.path)
+	if err != nil {
+		return err
+	}
+	var doc yaml.Node
+	if err := yaml.Unmarshal(b, &doc); err != nil {
+		return fmt.Errorf("config file %s: %w", s.path, err)
+	}
+	if len(doc.Content) == 0 {
+		return nil
+	}
+	if doc.Content[0].Kind != yaml.MappingNode {
+		return fmt.Errorf("config file %s: top level must be a mapping", s.path)
+	}
+
+	byPath := make(map[string]setting, len(settings))
+	for _, st := range settings {
+		byPath[st.path] = st
+	}
+	return s.walk(doc.Content[0], "", byPath)
+}
+
+func (s *sources) walk(n *yaml.Node, prefix string, byPath map[string]setting) error {
+	for i := 0; i+1 < len(n.Content); i += 2 {
+		k, v := n.Content[i], n.Content[i+1]
+		key := k.Value
+		if prefix != "" {
+			key = prefix + "." + key
+		}
+
+		if v.Kind == yaml.MappingNode {
+			if err := s.walk(v, key, byPath); err != nil {
+				return err
+			}
+			continue
+		}
+		st, ok := byPath[key]
+		if !ok {
+			return fmt.Errorf("config file %s: line %d: unknown key %q", s.path, k.Line, key)
+		}
+		if v.Kind != yaml.ScalarNode {
+			return fmt.Errorf("config file %s: line %d: %s: expected %s", s.path, v.Line, key, st.kind)
+		}
+		if v.Tag == "!!null" {
+			continue
+		}
+		val, err := scalarValue(v, st)
+		if err != nil {
+			return fmt.Errorf("config file %s: line %d: %s: expected %s, got %q", s.path, v.Line, key, st.kind, v.Value)
+		}
+		s.file[st.env] = val
+	}
+	return nil
+}
+
+func scalarValue(v *yaml.Node, st setting) (string, error) {
+	switch st.kind {
+	case kindDuration:
+		val := v.Value
+		if st.seconds {
+			val = bareSeconds(val)
+		}
+		if _, err := time.ParseDuration(val); err != nil {
+			return "", err
+		}
+		return val, nil
+	case kindBool:
+		// nur echte YAML-Bools: "yes"/"on" oder gequotete Werte sind eher Tippfehler
+		var b bool
+		if v.Tag != "!!bool" {
+			return "", fmt.Errorf("not a bool")
+		}
+		if err := v.Decode(&b); err != nil {
+			return "", err
+		}
+		return strconv.FormatBool(b), nil
+	case kindUint:
+		var u uint64
+		if v.Tag != "!!int" {
+			return "", fmt.Errorf("not an integer")
+		}
+		if err := v.Decode(&u); err != nil {
+			return "", err
+		}
+		return strconv.FormatUint(u, 10), nil
+	case kindFloat:
+		var f float64
+		if v.Tag != "!!float" && v.Tag != "!!int" {
+			return "", fmt.Errorf("not a number")
+		}
+		if err := v.Decode(&f); err != nil {
+			return "", err
+		}
+		return strconv.FormatFloat(f, 'g', -1, 64), nil
+	default:
+		return v.Value, nil
+	}
+}
+
+// WriteEffective schreibt die wirksame Konfiguration im Format der Config-Datei; der Kommentar nennt
+// Env-Variable und Herkunft. Secrets werden durch <redacted> ersetzt.
+func (cfg Config) WriteEffective(w io.Writer) error {
+	root := &yaml.Node{Kind: yaml.MappingNode}
+	sections := map[string]*yaml.Node{}
+	rv := reflect.ValueOf(cfg)
+
+	for _, st := range settings {
+		parent, key := root, st.path
+		if sec, rest, ok := strings.Cut(st.path, "."); ok {
+			parent = sections[sec]
+			if parent == nil {
+				parent = &yaml.Node{Kind: yaml.MappingNode}
+				sections[sec] = parent
+				root.Content = append(root.Content, &yaml.Node{Kind: yaml.ScalarNode, Value: sec}, parent)
+			}
+			key = rest
+		}
+
+		value := rv.FieldByName(st.env).String()
+		if st.secret && value != "" {
+			value = "<redacted>"
+		}
+		origin := cfg.sources[st.env]
+		if origin == "" {
+			origin = "default"
+		}
+		val := &yaml.Node{Kind: yaml.ScalarNode, Value: value, LineComment: st.env + " (" + origin + ")"}
+		// Strings explizit taggen, sonst würde z.B. der Port "8080" als Zahl ausgegeben
+		if st.kind == kindString || st.kind == kindDuration || value == "<redacted>" {
+			val.Tag = "!!str"
+		}
+		parent.Content = append(parent.Content, &yaml.Node{Kind: yaml.ScalarNode, Value: key}, val)
+	}
+
+	header := "# effective configuration (no config file)\n"
+	if cfg.file != "" {
+		header = "# effective configuration (config file: " + cfg.file + ")\n"
+	}
+	if _, err := io.WriteString(w, header); err != nil {
+		return err
+	}
+	enc := yaml.NewEncoder(w)
+	enc.SetIndent(2)
+	if err := enc.Encode(root); err != nil {
+		return err
+	}
+	return enc.Close()
+}
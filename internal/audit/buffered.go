@@ -0,0 +1,196 @@
+package audit
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+var ErrSinkUnavailable = errors.New("audit sink unavailable")
+
+// SinkWriter schreibt einen einzelnen Eintrag; wird nur aus der Schreib-Goroutine eines BufferedSink aufgerufen
+type SinkWriter interface {
+	WriteEntry(e Entry) error
+	Close() error
+}
+
+// BufferedSink entkoppelt Requests vom (evtl. langsamen) Ziel: Append legt in einen Puffer,
+// eine Goroutine schreibt und wiederholt fehlgeschlagene Einträge mit Backoff.
+// Ist der Puffer voll, verwirft FailOpen den Eintrag, FailClosed wartet bis BlockTimeout.
+type BufferedSink struct {
+	name string
+	w    SinkWriter
+	mode FailureMode
+
+	ch      chan Entry
+	closing chan struct{}
+	done    chan struct{}
+	once    sync.Once
+
+	mu      sync.Mutex
+	lastErr error
+	dropped atomic.Int64
+
+	// BlockTimeout: so lange wartet Append (FailClosed) auf Platz im Puffer
+	BlockTimeout time.Duration
+	// RetryMin/RetryMax: Backoff zwischen Schreibversuchen desselben Eintrags
+	RetryMin time.Duration
+	RetryMax time.Duration
+
+	log *slog.Logger
+}
+
+// NewBufferedSink startet die Schreib-Goroutine; Close beendet sie
+func NewBufferedSink(name string, w SinkWriter, mode FailureMode, size int) *BufferedSink {
+	if size < 1 {
+		size = 1
+	}
+	s := &BufferedSink{
+		name:         name,
+		w:            w,
+		mode:         mode,
+		ch:           make(chan Entry, size),
+		closing:      make(chan struct{}),
+		done:         make(chan struct{}),
+		BlockTimeout: time.Second,
+		RetryMin:     100 * time.Millisecond,
+		RetryMax:     5 * time.Second,
+		log:          slog.Default().With("audit_sink", name),
+	}
+	go s.loop()
+	return s
+}
+
+func (s *BufferedSink) Name() string      { return s.name }
+func (s *BufferedSink) Mode() FailureMode { return s.mode }
+
+// Dropped zählt Einträge, die FailOpen wegen vollem Puffer verworfen hat
+func (s *BufferedSink) Dropped() int64 { return s.dropped.Load() }
+
+func (s *BufferedSink) Append(ctx context.Context, e Entry) error {
+	select {
+	case <-s.closing:
+		return fmt.Errorf("%w: %s: closed", ErrSinkUnavailable, s.name)
+	default:
+	}
+
+	select {
+	case s.ch <- e:
+		return nil
+	default:
+	}
+
+	if s.mode != FailClosed {
+		if n := s.dropped.Add(1); n == 1 || n%1000 == 0 {
+			s.log.Warn("audit sink buffer full, dropping entries", "dropped_total", n)
+		}
+		return fmt.Errorf("%w: %s: buffer full", ErrSinkUnavailable, s.name)
+	}
+
+	// Backpressure: kurz warten, dann aufgeben (Request ist schon beantwortet, nachfolgende werden abgewiesen)
+	t := time.NewTimer(s.BlockTimeout)
+	defer t.Stop()
+	select {
+	case s.ch <- e:
+		return nil
+	case <-t.C:
+		return fmt.Errorf("%w: %s: buffer full", ErrSinkUnavailable, s.name)
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-s.closing:
+		return fmt.Errorf("%w: %s: closed", ErrSinkUnavailable, s.name)
+	}
+}
+
+// Healthy liefert einen Fehler, solange das Ziel nicht schreibbar oder der Puffer voll ist
+func (s *BufferedSink) Healthy() error {
+	s.mu.Lock()
+	err := s.lastErr
+	s.mu.Unlock()
+	if err != nil {
+		return fmt.Errorf("%w: %s: %v", ErrSinkUnavailable, s.name, err)
+	}
+	if len(s.ch) == cap(s.ch) {
+		return fmt.Errorf("%w: %s: buffer full", ErrSinkUnavailable, s.name)
+	}
+	return nil
+}
+
+// Close schreibt den Puffer (je ein Versuch pro Eintrag) und schließt das Ziel; wartet höchstens 5s
+func (s *BufferedSink) Close() error {
+	s.once.Do(func() { close(s.closing) })
+	select {
+	case <-s.done:
+		return nil
+	case <-time.After(5 * time.Second):
+		return fmt.Errorf("audit sink %s: close timed out with %d entries pending", s.name, len(s.ch))
+	}
+}
+
+func (s *BufferedSink) loop() {
+	defer close(s.done)
+	for {
+		select {
+		case e := <-s.ch:
+			s.write(e)
+		case <-s.closing:
+			for {
+				select {
+				case e := <-s.ch:
+					if err := s.w.WriteEntry(e); err != nil {
+						s.log.Error("audit entry dropped during shutdown", "err", err, "seq", e.Seq, "request_id", e.RequestID)
+					}
+				default:
+					if err := s.w.Close(); err != nil {
+						s.log.Error("audit sink close failed", "err", err)
+					}
+					return
+				}
+			}
+		}
+	}
+}
+
+// write wiederholt denselben Eintrag, bis er geschrieben ist (Reihenfolge bleibt erhalten); beim Shutdown
+// gibt es noch genau einen Versuch
+func (s *BufferedSink) write(e Entry) {
+	backoff := s.RetryMin
+	for {
+		err := s.w.WriteEntry(e)
+		s.setErr(err)
+		if err == nil {
+			return
+		}
+		select {
+		case <-time.After(backoff):
+		case <-s.closing:
+			// letzter Versuch beim Shutdown; scheitert er, geht der Eintrag verloren – dann wenigstens im Log
+			if err := s.w.WriteEntry(e); err != nil {
+				s.log.Error("audit entry dropped during shutdown", "err", err, "seq", e.Seq, "request_id", e.RequestID)
+			}
+			return
+		}
+		backoff *= 2
+		if backoff > s.RetryMax {
+			backoff = s.RetryMax
+		}
+	}
+}
+
+func (s *BufferedSink) setErr(err error) {
+	s.mu.Lock()
+	prev := s.lastErr
+	s.lastErr = err
+	s.mu.Unlock()
+
+	switch {
+	case err != nil && prev == nil:
+		s.log.Error("audit sink unavailable", "err", err, "failure_mode", string(s.mode))
+	case err == nil && prev != nil:
+		s.log.Info("audit sink recovered")
+	}
+}
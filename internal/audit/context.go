@@ -0,0 +1,55 @@
+package audit
+
+import (
+	"context"
+	"sync"
+
+	"github.com/timgst1/glass/internal/authz"
+)
+
+type recordKey struct{}
+
+// Record sammelt während eines Requests die AuthZ-Entscheidung für den Audit-Eintrag
+type Record struct {
+	mu       sync.Mutex
+	set      bool
+	action   string
+	key      string
+	decision authz.Decision
+}
+
+// WithRecord hängt einen leeren Record an den Context (macht die Audit-Middleware)
+func WithRecord(ctx context.Context) (context.Context, *Record) {
+	rec := &Record{}
+	return context.WithValue(ctx, recordKey{}, rec), rec
+}
+
+// NoteDecision merkt sich eine AuthZ-Entscheidung: die erste zählt, eine spätere Ablehnung ersetzt sie
+// (z.B. Batch-Write, bei dem erst der dritte Key verweigert wird). Ohne Record im Context ein No-op.
+func NoteDecision(ctx context.Context, action, key string, dec authz.Decision) {
+	rec, ok := ctx.Value(recordKey{}).(*Record)
+	if !ok {
+		return
+	}
+	rec.mu.Lock()
+	defer rec.mu.Unlock()
+	if rec.set && (dec.Allowed || !rec.decision.Allowed) {
+		return
+	}
+	rec.set = true
+	rec.action, rec.key, rec.decision = action, key, dec
+}
+
+// Apply überträgt die gemerkte Entscheidung in e
+func (r *Record) Apply(e *Entry) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if !r.set {
+		return
+	}
+	e.Action, e.Key, e.Reason = r.action, r.key, r.decision.Reason
+	e.Decision = DecisionDeny
+	if r.decision.Allowed {
+		e.Decision = DecisionAllow
+	}
+}
@@ -0,0 +1,50 @@
+package audit
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+)
+
+// Entry ist ein Audit-Eintrag pro authentifiziertem Request
+type Entry struct {
+	Seq       int64  `json:"seq"`
+	Time      string `json:"time"`
+	RequestID string `json:"request_id"`
+	Subject   string `json:"subject"`
+	Method    string `json:"method"`
+	Path      string `json:"path"`
+	// Action/Key/Decision/Reason stammen aus der (ersten bzw. verweigernden) AuthZ-Entscheidung des Requests
+	Action   string `json:"action,omitempty"`
+	Key      string `json:"key,omitempty"`
+	Decision string `json:"decision,omitempty"`
+	Reason   string `json:"reason,omitempty"`
+	Status   int    `json:"status"`
+	SourceIP string `json:"source_ip"`
+
+	PrevHash string `json:"prev_hash"`
+	Hash     string `json:"hash"`
+}
+
+const (
+	DecisionAllow = "allow"
+	DecisionDeny  = "deny"
+)
+
+// Logger nimmt Audit-Einträge entgegen; Seq/PrevHash/Hash setzt der Logger
+type Logger interface {
+	Append(ctx context.Context, e Entry) error
+}
+
+// ComputeHash = hex(sha256(prev_hash + "\n" + JSON(Eintrag ohne hash))).
+// Feldreihenfolge ist durch das Struct fest, das JSON also deterministisch.
+func ComputeHash(e Entry) string {
+	e.Hash = ""
+	b, _ := json.Marshal(e)
+	h := sha256.New()
+	h.Write([]byte(e.PrevHash))
+	h.Write([]byte("\n"))
+	h.Write(b)
+	return hex.EncodeToString(h.Sum(nil))
+}
@@ -0,0 +1,64 @@
+package audit
+
+import (
+	"context"
+	"errors"
+)
+
+// Checker wird von Loggern mit fail-closed Sinks implementiert: solange Ready einen Fehler liefert,
+// weist die Audit-Middleware Requests mit 503 ab.
+type Checker interface {
+	Ready() error
+}
+
+// Multi schreibt zuerst ins SQLite-Log (optional, setzt Seq/Hash) und reicht den Eintrag dann an alle Sinks weiter
+type Multi struct {
+	primary *SQLiteLog
+	sinks   []*BufferedSink
+}
+
+func NewMulti(primary *SQLiteLog, sinks []*BufferedSink) *Multi {
+	return &Multi{primary: primary, sinks: sinks}
+}
+
+// Append liefert Fehler des SQLite-Logs und der fail-closed Sinks; fail-open Sinks protokollieren selbst
+func (m *Multi) Append(ctx context.Context, e Entry) error {
+	var errs []error
+	if m.primary != nil {
+		written, err := m.primary.append(ctx, e)
+		if err != nil {
+			errs = append(errs, err)
+		} else {
+			e = written
+		}
+	}
+	for _, s := range m.sinks {
+		if err := s.Append(ctx, e); err != nil && s.Mode() == FailClosed {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+func (m *Multi) Ready() error {
+	for _, s := range m.sinks {
+		if s.Mode() != FailClosed {
+			continue
+		}
+		if err := s.Healthy(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Close leert und schließt alle Sinks (das SQLite-Log gehört der DB)
+func (m *Multi) Close() error {
+	var errs []error
+	for _, s := range m.sinks {
+		if err := s.Close(); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
@@ -0,0 +1,157 @@
+package audit
+
+import (
+	"fmt"
+	"os"
+	"regexp"
+
+	"gopkg.in/yaml.v3"
+)
+
+// FailureMode legt fest, was passiert, wenn ein Sink nicht schreiben kann
+type FailureMode string
+
+const (
+	// FailOpen: Einträge werden bei vollem Puffer verworfen, Requests laufen weiter
+	FailOpen FailureMode = "open"
+	// FailClosed: solange der Sink hängt, werden Requests mit 503 abgewiesen
+	FailClosed FailureMode = "closed"
+)
+
+// SinkConfig beschreibt einen Audit-Sink aus AUDIT_SINKS_FILE
+type SinkConfig struct {
+	Name string `yaml:"name"`
+	// Type: file, stdout oder syslog
+	Type        string      `yaml:"type"`
+	FailureMode FailureMode `yaml:"failureMode"`
+	// BufferSize: Einträge, die zwischengepuffert werden, bevor Backpressure greift
+	BufferSize int `yaml:"bufferSize"`
+
+	// file: JSONL, rotiert bei MaxSizeMB, behält MaxFiles alte Dateien (path.1 … path.N)
+	Path      string `yaml:"path"`
+	MaxSizeMB int    `yaml:"maxSizeMB"`
+	MaxFiles  int    `yaml:"maxFiles"`
+
+	// syslog: RFC 5424 über udp, tcp oder tls (tcp/tls mit Octet-Counting nach RFC 6587)
+	Network    string `yaml:"network"`
+	Address    string `yaml:"address"`
+	CAFile     string `yaml:"caFile"`
+	ServerName string `yaml:"serverName"`
+	AppName    string `yaml:"appName"`
+}
+
+// SinksConfig ist das Format von AUDIT_SINKS_FILE
+type SinksConfig struct {
+	Sinks []SinkConfig `yaml:"sinks"`
+}
+
+func LoadSinksFromFile(path string) ([]SinkConfig, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var c SinksConfig
+	if err := yaml.Unmarshal(b, &c); err != nil {
+		return nil, err
+	}
+
+	seen := map[string]bool{}
+	for i := range c.Sinks {
+		s := &c.Sinks[i]
+		applySinkDefaults(s)
+		if err := ValidateSink(*s); err != nil {
+			return nil, err
+		}
+		if seen[s.Name] {
+			return nil, fmt.Errorf("audit sink: duplicate name %q", s.Name)
+		}
+		seen[s.Name] = true
+	}
+	return c.Sinks, nil
+}
+
+func applySinkDefaults(s *SinkConfig) {
+	if s.FailureMode == "" {
+		s.FailureMode = FailOpen
+	}
+	if s.BufferSize == 0 {
+		s.BufferSize = 1024
+	}
+	if s.Type == "file" {
+		if s.MaxSizeMB == 0 {
+			s.MaxSizeMB = 100
+		}
+		if s.MaxFiles == 0 {
+			s.MaxFiles = 5
+		}
+	}
+	if s.Type == "syslog" && s.AppName == "" {
+		s.AppName = "glass"
+	}
+}
+
+var sinkNamePattern = regexp.MustCompile(`^[A-Za-z0-9_-]{1,64}$`)
+
+func ValidateSink(s SinkConfig) error {
+	if !sinkNamePattern.MatchString(s.Name) {
+		return fmt.Errorf("audit sink: invalid name %q (allowed: A-Z a-z 0-9 _ -, max 64)", s.Name)
+	}
+	switch s.FailureMode {
+	case FailOpen, FailClosed:
+	default:
+		return fmt.Errorf("audit sink %q: invalid failureMode %q (allowed: open, closed)", s.Name, s.FailureMode)
+	}
+	if s.BufferSize < 1 {
+		return fmt.Errorf("audit sink %q: bufferSize must be >= 1", s.Name)
+	}
+
+	switch s.Type {
+	case "stdout":
+	case "file":
+		if s.Path == "" {
+			return fmt.Errorf("audit sink %q: path is required for type file", s.Name)
+		}
+		if s.MaxSizeMB < 1 || s.MaxFiles < 1 {
+			return fmt.Errorf("audit sink %q: maxSizeMB and maxFiles must be >= 1", s.Name)
+		}
+	case "syslog":
+		switch s.Network {
+		case "udp", "tcp", "tls":
+		default:
+			return fmt.Errorf("audit sink %q: invalid network %q (allowed: udp, tcp, tls)", s.Name, s.Network)
+		}
+		if s.Address == "" {
+			return fmt.Errorf("audit sink %q: address is required for type syslog", s.Name)
+		}
+		if s.CAFile != "" && s.Network != "tls" {
+			return fmt.Errorf("audit sink %q: caFile requires network tls", s.Name)
+		}
+	default:
+		return fmt.Errorf("audit sink %q: invalid type %q (allowed: file, stdout, syslog)", s.Name, s.Type)
+	}
+	return nil
+}
+
+// NewSink baut den Writer zur Konfiguration und startet den Puffer davor
+func NewSink(c SinkConfig) (*BufferedSink, error) {
+	var w SinkWriter
+	switch c.Type {
+	case "stdout":
+		w = NewStreamWriter(os.Stdout)
+	case "file":
+		fw, err := NewFileWriter(c.Path, int64(c.MaxSizeMB)<<20, c.MaxFiles)
+		if err != nil {
+			return nil, fmt.Errorf("audit sink %q: %w", c.Name, err)
+		}
+		w = fw
+	case "syslog":
+		sw, err := NewSyslogWriter(c.Network, c.Address, c.AppName, c.CAFile, c.ServerName)
+		if err != nil {
+			return nil, fmt.Errorf("audit sink %q: %w", c.Name, err)
+		}
+		w = sw
+	default:
+		return nil, fmt.Errorf("audit sink %q: invalid type %q", c.Name, c.Type)
+	}
+	return NewBufferedSink(c.Name, w, c.FailureMode, c.BufferSize), nil
+}
@@ -0,0 +1,288 @@
+package audit_test
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/timgst1/glass/internal/audit"
+)
+
+// fakeWriter schlägt fehl, solange fail gesetzt ist; block hält jeden Schreibversuch an
+type fakeWriter struct {
+	mu      sync.Mutex
+	fail    bool
+	written []audit.Entry
+	block   chan struct{}
+}
+
+func (w *fakeWriter) WriteEntry(e audit.Entry) error {
+	if w.block != nil {
+		<-w.block
+	}
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.fail {
+		return errors.New("connection refused")
+	}
+	w.written = append(w.written, e)
+	return nil
+}
+
+func (w *fakeWriter) Close() error { return nil }
+
+func (w *fakeWriter) setFail(v bool) {
+	w.mu.Lock()
+	w.fail = v
+	w.mu.Unlock()
+}
+
+func (w *fakeWriter) count() int {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return len(w.written)
+}
+
+func waitFor(t *testing.T, cond func() bool) {
+	t.Helper()
+	deadline := time.Now().Add(2 * time.Second)
+	for !cond() {
+		if time.Now().After(deadline) {
+			t.Fatalf("condition not met in time")
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
+
+func TestBufferedSink_FailClosedNotReadyWhileDown(t *testing.T) {
+	w := &fakeWriter{fail: true}
+	s := audit.NewBufferedSink("siem", w, audit.FailClosed, 4)
+	s.RetryMin, s.RetryMax = time.Millisecond, 5*time.Millisecond
+	m := audit.NewMulti(nil, []*audit.BufferedSink{s})
+	defer m.Close()
+
+	if err := m.Append(context.Background(), audit.Entry{RequestID: "r1"}); err != nil {
+		t.Fatalf("Append: %v", err)
+	}
+	waitFor(t, func() bool { return m.Ready() != nil })
+	if !errors.Is(m.Ready(), audit.ErrSinkUnavailable) {
+		t.Fatalf("expected ErrSinkUnavailable, got %v", m.Ready())
+	}
+
+	// Ziel wieder da: der gepufferte Eintrag wird nachgeliefert
+	w.setFail(false)
+	waitFor(t, func() bool { return m.Ready() == nil && w.count() == 1 })
+}
+
+func TestBufferedSink_CloseDuringBackoffRetriesOnce(t *testing.T) {
+	w := &fakeWriter{fail: true}
+	s := audit.NewBufferedSink("siem", w, audit.FailClosed, 4)
+	s.RetryMin, s.RetryMax = time.Hour, time.Hour
+	m := audit.NewMulti(nil, []*audit.BufferedSink{s})
+
+	if err := m.Append(context.Background(), audit.Entry{RequestID: "r1"}); err != nil {
+		t.Fatalf("Append: %v", err)
+	}
+	waitFor(t, func() bool { return m.Ready() != nil })
+
+	// Ziel ist wieder da, die Schleife steckt aber im Backoff: Close darf den Eintrag nicht verwerfen
+	w.setFail(false)
+	if err := m.Close(); err != nil {
+		t.Fatalf("Close: %v", err)
+	}
+	if w.count() != 1 {
+		t.Fatalf("expected entry written on shutdown, got %d", w.count())
+	}
+}
+
+func TestBufferedSink_FailOpenDropsWhenFull(t *testing.T) {
+	w := &fakeWriter{block: make(chan struct{})}
+	s := audit.NewBufferedSink("stdout", w, audit.FailOpen, 1)
+	m := audit.NewMulti(nil, []*audit.BufferedSink{s})
+
+	for i := 0; i < 5; i++ {
+		if err := m.Append(context.Background(), audit.Entry{Seq: int64(i)}); err != nil {
+			t.Fatalf("fail-open Append must not fail: %v", err)
+		}
+	}
+	if s.Dropped() == 0 {
+		t.Fatalf("expected dropped entries")
+	}
+	if err := m.Ready(); err != nil {
+		t.Fatalf("fail-open sink must not block readiness: %v", err)
+	}
+	close(w.block)
+	if err := m.Close(); err != nil {
+		t.Fatalf("Close: %v", err)
+	}
+	if got := int64(w.count()) + s.Dropped(); got != 5 {
+		t.Fatalf("expected written+dropped=5, got %d", got)
+	}
+}
+
+func TestMulti_ForwardsChainedEntryToSinks(t *testing.T) {
+	db := openDB(t)
+	w := &fakeWriter{}
+	s := audit.NewBufferedSink("file", w, audit.FailOpen, 8)
+	m := audit.NewMulti(audit.NewSQLiteLog(db), []*audit.BufferedSink{s})
+
+	for i := 0; i < 2; i++ {
+		if err := m.Append(context.Background(), audit.Entry{Time: "2026-01-01T00:00:00Z", Subject: "bearer:ci"}); err != nil {
+			t.Fatalf("Append: %v", err)
+		}
+	}
+	if err := m.Close(); err != nil {
+		t.Fatalf("Close: %v", err)
+	}
+	if len(w.written) != 2 || w.written[1].Seq != 2 || w.written[1].PrevHash != w.written[0].Hash {
+		t.Fatalf("expected chained entries in sink, got %+v", w.written)
+	}
+}
+
+func TestFileWriter_Rotates(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "audit", "audit.jsonl")
+	fw, err := audit.NewFileWriter(path, 300, 2)
+	if err != nil {
+		t.Fatalf("NewFileWriter: %v", err)
+	}
+	for i := 1; i <= 10; i++ {
+		if err := fw.WriteEntry(audit.Entry{Seq: int64(i), Subject: "bearer:ci"}); err != nil {
+			t.Fatalf("WriteEntry: %v", err)
+		}
+	}
+	if err := fw.Close(); err != nil {
+		t.Fatalf("Close: %v", err)
+	}
+
+	for _, p := range []string{path, path + ".1", path + ".2"} {
+		st, err := os.Stat(p)
+		if err != nil {
+			t.Fatalf("expected %s: %v", p, err)
+		}
+		if st.Size() > 300 {
+			t.Fatalf("%s exceeds max size: %d", p, st.Size())
+		}
+	}
+	if _, err := os.Stat(path + ".3"); !os.IsNotExist(err) {
+		t.Fatalf("expected at most 2 rotated files")
+	}
+
+	// neueste Datei endet mit dem letzten Eintrag, jede Zeile ist gültiges JSON
+	b, _ := os.ReadFile(path)
+	lines := strings.Split(strings.TrimSpace(string(b)), "\n")
+	var last audit.Entry
+	if err := json.Unmarshal([]byte(lines[len(lines)-1]), &last); err != nil || last.Seq != 10 {
+		t.Fatalf("unexpected last line %q: %v", lines[len(lines)-1], err)
+	}
+}
+
+func TestSyslogWriter_TCPOctetCounting(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Listen: %v", err)
+	}
+	defer ln.Close()
+
+	msgs := make(chan string, 2)
+	go func() {
+		conn, err := ln.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		r := bufio.NewReader(conn)
+		for {
+			lenStr, err := r.ReadString(' ')
+			if err != nil {
+				return
+			}
+			n, _ := strconv.Atoi(strings.TrimSpace(lenStr))
+			buf := make([]byte, n)
+			if _, err := io.ReadFull(r, buf); err != nil {
+				return
+			}
+			msgs <- string(buf)
+		}
+	}()
+
+	w, err := audit.NewSyslogWriter("tcp", ln.Addr().String(), "glass", "", "")
+	if err != nil {
+		t.Fatalf("NewSyslogWriter: %v", err)
+	}
+	defer w.Close()
+	e := audit.Entry{Time: "2026-03-01T12:00:00.123456789Z", Subject: "bearer:ci", Decision: audit.DecisionDeny, Status: 403}
+	if err := w.WriteEntry(e); err != nil {
+		t.Fatalf("WriteEntry: %v", err)
+	}
+
+	select {
+	case msg := <-msgs:
+		// facility 13 (log audit) * 8 + severity 4 (warning) für deny
+		if !strings.HasPrefix(msg, "<108>1 2026-03-01T12:00:00.123456Z ") {
+			t.Fatalf("unexpected header: %q", msg)
+		}
+		fields := strings.SplitN(msg, " ", 8)
+		if len(fields) != 8 || fields[3] != "glass" || fields[5] != "audit" || fields[6] != "-" {
+			t.Fatalf("unexpected syslog fields: %q", msg)
+		}
+		var got audit.Entry
+		if err := json.Unmarshal([]byte(fields[7]), &got); err != nil || got.Subject != "bearer:ci" || got.Status != 403 {
+			t.Fatalf("unexpected MSG %q: %v", fields[7], err)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatalf("no syslog message received")
+	}
+}
+
+func TestLoadSinksFromFile_Validates(t *testing.T) {
+	dir := t.TempDir()
+	write := func(content string) string {
+		p := filepath.Join(dir, "sinks.yaml")
+		if err := os.WriteFile(p, []byte(content), 0o600); err != nil {
+			t.Fatalf("WriteFile: %v", err)
+		}
+		return p
+	}
+
+	sinks, err := audit.LoadSinksFromFile(write(`
+sinks:
+  - name: local
+    type: file
+    path: /var/log/glass/audit.jsonl
+  - name: siem
+    type: syslog
+    network: tls
+    address: siem.example:6514
+    failureMode: closed
+`))
+	if err != nil {
+		t.Fatalf("LoadSinksFromFile: %v", err)
+	}
+	if sinks[0].FailureMode != audit.FailOpen || sinks[0].MaxSizeMB != 100 || sinks[0].MaxFiles != 5 || sinks[0].BufferSize != 1024 {
+		t.Fatalf("defaults not applied: %+v", sinks[0])
+	}
+	if sinks[1].AppName != "glass" || sinks[1].FailureMode != audit.FailClosed {
+		t.Fatalf("unexpected syslog sink: %+v", sinks[1])
+	}
+
+	for _, bad := range []string{
+		"sinks:\n  - name: x\n    type: kafka\n",
+		"sinks:\n  - name: x\n    type: syslog\n    network: udp\n",
+		"sinks:\n  - name: x\n    type: stdout\n    failureMode: maybe\n",
+		"sinks:\n  - name: x\n    type: stdout\n  - name: x\n    type: stdout\n",
+	} {
+		if _, err := audit.LoadSinksFromFile(write(bad)); err == nil {
+			t.Fatalf("expected error for %q", bad)
+		}
+	}
+}
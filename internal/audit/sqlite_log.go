@@ -0,0 +1,59 @@
+package audit
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"sync"
+)
+
+// SQLiteLog schreibt Einträge hash-verkettet in die Tabelle audit_log (append-only per Trigger)
+type SQLiteLog struct {
+	db *sql.DB
+
+	mu       sync.Mutex
+	loaded   bool
+	lastSeq  int64
+	lastHash string
+}
+
+func NewSQLiteLog(db *sql.DB) *SQLiteLog {
+	return &SQLiteLog{db: db}
+}
+
+func (l *SQLiteLog) Append(ctx context.Context, e Entry) error {
+	_, err := l.append(ctx, e)
+	return err
+}
+
+// append liefert den geschriebenen Eintrag inkl. Seq/Hash (Multi reicht ihn so an die Sinks weiter)
+func (l *SQLiteLog) append(ctx context.Context, e Entry) (Entry, error) {
+	// Verkettung verlangt strikt serielles Schreiben
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if !l.loaded {
+		err := l.db.QueryRowContext(ctx, `SELECT seq, hash FROM audit_log ORDER BY seq DESC LIMIT 1`).Scan(&l.lastSeq, &l.lastHash)
+		if err != nil && !errors.Is(err, sql.ErrNoRows) {
+			return Entry{}, err
+		}
+		l.loaded = true
+	}
+
+	e.Seq = l.lastSeq + 1
+	e.PrevHash = l.lastHash
+	e.Hash = ComputeHash(e)
+
+	_, err := l.db.ExecContext(ctx, `
+INSERT INTO audit_log(seq, time, request_id, subject, method, path, action, key, decision, reason, status, source_ip, prev_hash, hash)
+VALUES(?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+		e.Seq, e.Time, e.RequestID, e.Subject, e.Method, e.Path, e.Action, e.Key, e.Decision, e.Reason, e.Status, e.SourceIP, e.PrevHash, e.Hash,
+	)
+	if err != nil {
+		// Tail neu laden: evtl. hat ein anderer Prozess geschrieben
+		l.loaded = false
+		return Entry{}, err
+	}
+	l.lastSeq, l.lastHash = e.Seq, e.Hash
+	return e, nil
+}
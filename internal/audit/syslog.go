@@ -0,0 +1,133 @@
+package audit
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/json"
+	"fmt"
+	"net"
+	"os"
+	"strconv"
+	"time"
+)
+
+const (
+	// RFC 5424: facility 13 = "log audit"
+	syslogFacilityAudit = 13
+	syslogSevWarning    = 4
+	syslogSevInfo       = 6
+
+	syslogTimeout = 5 * time.Second
+)
+
+// SyslogWriter sendet Einträge als RFC-5424-Nachricht (MSG = Eintrag als JSON).
+// udp: eine Nachricht pro Datagramm; tcp/tls: Octet-Counting ("LEN SP MSG", RFC 6587/5425).
+// Die Verbindung wird beim ersten Schreiben bzw. nach einem Fehler neu aufgebaut.
+type SyslogWriter struct {
+	network string
+	addr    string
+	tlsConf *tls.Config
+
+	hostname string
+	appName  string
+	procID   string
+
+	conn net.Conn
+}
+
+func NewSyslogWriter(network, addr, appName, caFile, serverName string) (*SyslogWriter, error) {
+	w := &SyslogWriter{
+		network:  network,
+		addr:     addr,
+		hostname: "-",
+		appName:  appName,
+		procID:   strconv.Itoa(os.Getpid()),
+	}
+	if h, err := os.Hostname(); err == nil && h != "" {
+		w.hostname = h
+	}
+	if network == "tls" {
+		w.tlsConf = &tls.Config{MinVersion: tls.VersionTLS12, ServerName: serverName}
+		if caFile != "" {
+			pem, err := os.ReadFile(caFile)
+			if err != nil {
+				return nil, err
+			}
+			pool := x509.NewCertPool()
+			if !pool.AppendCertsFromPEM(pem) {
+				return nil, fmt.Errorf("caFile %s: no certificates found", caFile)
+			}
+			w.tlsConf.RootCAs = pool
+		}
+	}
+	return w, nil
+}
+
+func (w *SyslogWriter) WriteEntry(e Entry) error {
+	msg, err := w.format(e)
+	if err != nil {
+		return err
+	}
+	if w.network != "udp" {
+		msg = append([]byte(strconv.Itoa(len(msg))+" "), msg...)
+	}
+
+	if w.conn == nil {
+		if err := w.dial(); err != nil {
+			return err
+		}
+	}
+	_ = w.conn.SetWriteDeadline(time.Now().Add(syslogTimeout))
+	if _, err := w.conn.Write(msg); err != nil {
+		_ = w.conn.Close()
+		w.conn = nil
+		return err
+	}
+	return nil
+}
+
+func (w *SyslogWriter) Close() error {
+	if w.conn == nil {
+		return nil
+	}
+	err := w.conn.Close()
+	w.conn = nil
+	return err
+}
+
+func (w *SyslogWriter) dial() error {
+	d := &net.Dialer{Timeout: syslogTimeout}
+	var (
+		conn net.Conn
+		err  error
+	)
+	if w.network == "tls" {
+		conn, err = tls.DialWithDialer(d, "tcp", w.addr, w.tlsConf)
+	} else {
+		conn, err = d.Dial(w.network, w.addr)
+	}
+	if err != nil {
+		return err
+	}
+	w.conn = conn
+	return nil
+}
+
+// format: <PRI>1 TIMESTAMP HOSTNAME APP-NAME PROCID MSGID STRUCTURED-DATA MSG
+func (w *SyslogWriter) format(e Entry) ([]byte, error) {
+	sev := syslogSevInfo
+	if e.Decision == DecisionDeny {
+		sev = syslogSevWarning
+	}
+	ts := "-"
+	if t, err := time.Parse(time.RFC3339Nano, e.Time); err == nil {
+		// RFC 5424 erlaubt höchstens 6 Nachkommastellen
+		ts = t.UTC().Format("2006-01-02T15:04:05.000000Z07:00")
+	}
+	body, err := json.Marshal(e)
+	if err != nil {
+		return nil, err
+	}
+	head := fmt.Sprintf("<%d>1 %s %s %s %s audit - ", syslogFacilityAudit*8+sev, ts, w.hostname, w.appName, w.procID)
+	return append([]byte(head), body...), nil
+}
@@ -0,0 +1,53 @@
+package audit
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+)
+
+var ErrTampered = errors.New("audit log tampered")
+
+type VerifyResult struct {
+	Entries int64
+	// Head: letzter Eintrag – extern festhalten, um auch abgeschnittene Enden zu erkennen
+	HeadSeq  int64
+	HeadHash string
+}
+
+// Verify prüft die komplette Kette: lückenlose seq ab 1, prev_hash = hash des Vorgängers, hash neu berechnet.
+// Erkennt veränderte, eingefügte und gelöschte Einträge (außer am Ende – dafür HeadSeq/HeadHash vergleichen).
+func Verify(ctx context.Context, db *sql.DB) (VerifyResult, error) {
+	rows, err := db.QueryContext(ctx, `
+SELECT seq, time, request_id, subject, method, path, action, key, decision, reason, status, source_ip, prev_hash, hash
+FROM audit_log
+ORDER BY seq`)
+	if err != nil {
+		return VerifyResult{}, err
+	}
+	defer rows.Close()
+
+	var res VerifyResult
+	for rows.Next() {
+		var e Entry
+		if err := rows.Scan(&e.Seq, &e.Time, &e.RequestID, &e.Subject, &e.Method, &e.Path, &e.Action, &e.Key,
+			&e.Decision, &e.Reason, &e.Status, &e.SourceIP, &e.PrevHash, &e.Hash); err != nil {
+			return res, err
+		}
+
+		if e.Seq != res.HeadSeq+1 {
+			return res, fmt.Errorf("%w: expected seq %d, found %d (entries deleted)", ErrTampered, res.HeadSeq+1, e.Seq)
+		}
+		if e.PrevHash != res.HeadHash {
+			return res, fmt.Errorf("%w: seq %d: prev_hash does not match previous entry", ErrTampered, e.Seq)
+		}
+		if ComputeHash(e) != e.Hash {
+			return res, fmt.Errorf("%w: seq %d: hash mismatch (entry modified)", ErrTampered, e.Seq)
+		}
+
+		res.Entries++
+		res.HeadSeq, res.HeadHash = e.Seq, e.Hash
+	}
+	return res, rows.Err()
+}
@@ -0,0 +1,120 @@
+package audit
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+)
+
+// StreamWriter schreibt JSONL auf einen Stream (z.B. stdout); der Stream wird nicht geschlossen
+type StreamWriter struct {
+	w io.Writer
+}
+
+func NewStreamWriter(w io.Writer) *StreamWriter {
+	return &StreamWriter{w: w}
+}
+
+func (s *StreamWriter) WriteEntry(e Entry) error {
+	b, err := jsonLine(e)
+	if err != nil {
+		return err
+	}
+	_, err = s.w.Write(b)
+	return err
+}
+
+func (s *StreamWriter) Close() error { return nil }
+
+// FileWriter schreibt JSONL in path und rotiert nach Größe: path -> path.1 -> … -> path.maxFiles (ältere fallen weg)
+type FileWriter struct {
+	path     string
+	maxSize  int64
+	maxFiles int
+
+	f    *os.File
+	size int64
+}
+
+func NewFileWriter(path string, maxSize int64, maxFiles int) (*FileWriter, error) {
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return nil, err
+	}
+	fw := &FileWriter{path: path, maxSize: maxSize, maxFiles: maxFiles}
+	if err := fw.open(); err != nil {
+		return nil, err
+	}
+	return fw, nil
+}
+
+func (fw *FileWriter) WriteEntry(e Entry) error {
+	b, err := jsonLine(e)
+	if err != nil {
+		return err
+	}
+	if fw.f == nil {
+		// letzte Rotation ist beim Öffnen gescheitert
+		if err := fw.open(); err != nil {
+			return err
+		}
+	}
+	if fw.size > 0 && fw.size+int64(len(b)) > fw.maxSize {
+		if err := fw.rotate(); err != nil {
+			return err
+		}
+	}
+	n, err := fw.f.Write(b)
+	fw.size += int64(n)
+	return err
+}
+
+func (fw *FileWriter) Close() error {
+	if fw.f == nil {
+		return nil
+	}
+	err := fw.f.Close()
+	fw.f = nil
+	return err
+}
+
+func (fw *FileWriter) open() error {
+	f, err := os.OpenFile(fw.path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o600)
+	if err != nil {
+		return err
+	}
+	st, err := f.Stat()
+	if err != nil {
+		_ = f.Close()
+		return err
+	}
+	fw.f, fw.size = f, st.Size()
+	return nil
+}
+
+func (fw *FileWriter) rotate() error {
+	if err := fw.Close(); err != nil {
+		return err
+	}
+	for i := fw.maxFiles - 1; i >= 1; i-- {
+		err := os.Rename(fmt.Sprintf("%s.%d", fw.path, i), fmt.Sprintf("%s.%d", fw.path, i+1))
+		if err != nil && !errors.Is(err, fs.ErrNotExist) {
+			return err
+		}
+	}
+	if err := os.Rename(fw.path, fw.path+".1"); err != nil {
+		return err
+	}
+	return fw.open()
+}
+
+func jsonLine(e Entry) ([]byte, error) {
+	b, err := json.Marshal(e)
+	if err != nil {
+		return nil, err
+	}
+	return append(b, '\n'), nil
+}
@@ -5,6 +5,7 @@ import (
 	"sync"
 
 	"github.com/timgst1/glass/internal/authn"
+	"github.com/timgst1/glass/internal/metrics"
 	"github.com/timgst1/glass/internal/policy"
 )
 
@@ -25,7 +26,14 @@ func NewRuntimeAuthorizer(src PolicySource) *RuntimeAuthorizer {
 	return &RuntimeAuthorizer{src: src}
 }
 
+// Evaluate zählt jede Entscheidung (auch reine Filter) in glass_authz_decisions_total
 func (a *RuntimeAuthorizer) Evaluate(subject authn.Subject, action, key string) Decision {
+	dec := a.evaluate(subject, action, key)
+	metrics.ObserveAuthz(action, dec.Allowed, dec.Reason)
+	return dec
+}
+
+func (a *RuntimeAuthorizer) evaluate(subject authn.Subject, action, key string) Decision {
 	doc, ok := a.src.Current()
 	if !ok || doc == nil {
 		return Deny("no policy available")
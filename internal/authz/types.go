@@ -3,9 +3,12 @@ package authz
 import "github.com/timgst1/glass/internal/authn"
 
 const (
-	ActionRead  = "read"
-	ActionWrite = "write"
-	ActionList  = "list"
+	ActionRead   = "read"
+	ActionWrite  = "write"
+	ActionList   = "list"
+	ActionDelete = "delete"
+	// ActionAdmin schützt irreversible Admin-Operationen (z.B. destroy) und ist nie implizit enthalten
+	ActionAdmin = "admin"
 )
 
 type Decision struct {
@@ -1,14 +1,25 @@
 package envelope
 
 import (
+	"context"
 	"crypto/aes"
 	"crypto/cipher"
 	"crypto/rand"
 	"encoding/base64"
+	"errors"
 	"fmt"
 	"io"
+
+	"github.com/timgst1/glass/internal/metrics"
+	"github.com/timgst1/glass/internal/tracing"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
 )
 
+// ErrUnknownKEK: der Datensatz wurde mit einem KEK verschlüsselt, der nicht (mehr) im Keyring ist
+var ErrUnknownKEK = errors.New("unknown kek_id")
+
 type Envelope struct {
 	kr *Keyring
 }
@@ -17,6 +28,19 @@ func New(kr *Keyring) *Envelope {
 	return &Envelope{kr: kr}
 }
 
+// CheckActiveKEK prüft, ob der aktive KEK im Keyring liegt (Readiness: ohne ihn scheitert jedes Schreiben)
+func (e *Envelope) CheckActiveKEK() error {
+	if e == nil || e.kr == nil {
+		return fmt.Errorf("envelope is nil")
+	}
+	id := e.kr.ActiveID()
+	kek, ok := e.kr.Get(id)
+	if !ok || len(kek) != 32 {
+		return fmt.Errorf("active kek %q not in keyring", id)
+	}
+	return nil
+}
+
 // Stored in DB:
 // - value = base64(ciphertext)
 // - value_nonce = base64(nonce)
@@ -32,7 +56,34 @@ type EncryptedValue struct {
 	WrapNonce  string
 }
 
-func (e *Envelope) Encrypt(key string, version int64, plaintext []byte) (EncryptedValue, error) {
+// Encrypt/Decrypt öffnen je einen Span; glass.key nur mit TRACING_INCLUDE_KEYS
+func (e *Envelope) Encrypt(ctx context.Context, key string, version int64, plaintext []byte) (EncryptedValue, error) {
+	_, span := tracing.Start(ctx, "envelope.Encrypt", trace.WithAttributes(tracing.KeyAttrs(key)...))
+	defer span.End()
+
+	ev, err := e.encrypt(key, version, plaintext)
+	if err != nil {
+		span.SetStatus(codes.Error, err.Error())
+		return EncryptedValue{}, err
+	}
+	span.SetAttributes(attribute.String("glass.kek_id", ev.KekID))
+	return ev, nil
+}
+
+func (e *Envelope) Decrypt(ctx context.Context, key string, version int64, ev EncryptedValue) ([]byte, error) {
+	_, span := tracing.Start(ctx, "envelope.Decrypt", trace.WithAttributes(tracing.KeyAttrs(key)...))
+	defer span.End()
+	span.SetAttributes(attribute.String("glass.kek_id", ev.KekID))
+
+	pt, err := e.decrypt(key, version, ev)
+	if err != nil {
+		span.SetStatus(codes.Error, err.Error())
+		return nil, err
+	}
+	return pt, nil
+}
+
+func (e *Envelope) encrypt(key string, version int64, plaintext []byte) (EncryptedValue, error) {
 	if e == nil || e.kr == nil {
 		return EncryptedValue{}, fmt.Errorf("envelope is nil")
 	}
@@ -61,6 +112,7 @@ func (e *Envelope) Encrypt(key string, version int64, plaintext []byte) (Encrypt
 		return EncryptedValue{}, err
 	}
 
+	metrics.CryptoOps.WithLabelValues("encrypt", kekID).Inc()
 	return EncryptedValue{
 		Enc:        1,
 		KekID:      kekID,
@@ -71,14 +123,14 @@ func (e *Envelope) Encrypt(key string, version int64, plaintext []byte) (Encrypt
 	}, nil
 }
 
-func (e *Envelope) Decrypt(key string, version int64, ev EncryptedValue) ([]byte, error) {
+func (e *Envelope) decrypt(key string, version int64, ev EncryptedValue) ([]byte, error) {
 	if e == nil || e.kr == nil {
 		return nil, fmt.Errorf("envelope is nil")
 	}
 
 	kek, ok := e.kr.Get(ev.KekID)
 	if !ok {
-		return nil, fmt.Errorf("unknown kek_id %q", ev.KekID)
+		return nil, fmt.Errorf("%w %q", ErrUnknownKEK, ev.KekID)
 	}
 
 	wrapped, err := base64.StdEncoding.DecodeString(ev.WrappedDEK)
@@ -111,6 +163,7 @@ func (e *Envelope) Decrypt(key string, version int64, ev EncryptedValue) ([]byte
 	if err != nil {
 		return nil, fmt.Errorf("decrypt value: %w", err)
 	}
+	metrics.CryptoOps.WithLabelValues("decrypt", ev.KekID).Inc()
 	return pt, nil
 }
 
@@ -133,7 +186,7 @@ func (e *Envelope) RewrapDEK(key string, version int64, ev EncryptedValue, newKe
 
 	oldKEK, ok := e.kr.Get(ev.KekID)
 	if !ok {
-		return EncryptedValue{}, fmt.Errorf("unkown kek_id %q", ev.KekID)
+		return EncryptedValue{}, fmt.Errorf("%w %q", ErrUnknownKEK, ev.KekID)
 	}
 	newKEK, ok := e.kr.Get(newKekID)
 	if !ok {
@@ -164,6 +217,7 @@ func (e *Envelope) RewrapDEK(key string, version int64, ev EncryptedValue, newKe
 		return EncryptedValue{}, fmt.Errorf("wrap dek: %w", err)
 	}
 
+	metrics.CryptoOps.WithLabelValues("rewrap", newKekID).Inc()
 	ev.KekID = newKekID
 	ev.WrappedDEK = base64.StdEncoding.EncodeToString(newWrapped)
 	ev.WrapNonce = base64.StdEncoding.EncodeToString(newWrapNonce)
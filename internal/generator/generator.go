@@ -0,0 +1,114 @@
+package generator
+
+import (
+	"crypto/rand"
+	"fmt"
+	"strings"
+)
+
+const (
+	TypePassword = "password"
+	TypeBytes    = "bytes"
+	TypeUUID     = "uuid"
+	TypeEd25519  = "ed25519"
+	TypeECDSA    = "ecdsa"
+	TypeRSA      = "rsa"
+)
+
+// Spec beschreibt, wie ein Wert erzeugt wird. Je nach Type sind nur bestimmte Felder relevant.
+type Spec struct {
+	Type string `json:"type" yaml:"type"`
+
+	// password: Länge in Zeichen; bytes: Länge in Bytes
+	Length int `json:"length,omitempty" yaml:"length,omitempty"`
+
+	// password: Zeichenklassen (nil = Default) oder eigener Zeichensatz
+	Lowercase *bool  `json:"lowercase,omitempty" yaml:"lowercase,omitempty"`
+	Uppercase *bool  `json:"uppercase,omitempty" yaml:"uppercase,omitempty"`
+	Digits    *bool  `json:"digits,omitempty" yaml:"digits,omitempty"`
+	Symbols   *bool  `json:"symbols,omitempty" yaml:"symbols,omitempty"`
+	Charset   string `json:"charset,omitempty" yaml:"charset,omitempty"`
+	// password: Mindestanzahl je Klasse
+	MinLowercase int `json:"min_lowercase,omitempty" yaml:"min_lowercase,omitempty"`
+	MinUppercase int `json:"min_uppercase,omitempty" yaml:"min_uppercase,omitempty"`
+	MinDigits    int `json:"min_digits,omitempty" yaml:"min_digits,omitempty"`
+	MinSymbols   int `json:"min_symbols,omitempty" yaml:"min_symbols,omitempty"`
+
+	// bytes: hex | base64 (Default base64)
+	Encoding string `json:"encoding,omitempty" yaml:"encoding,omitempty"`
+
+	// ecdsa: P-256 | P-384 | P-521 (Default P-256)
+	Curve string `json:"curve,omitempty" yaml:"curve,omitempty"`
+	// rsa: 2048 | 3072 | 4096 (Default 3072)
+	Bits int `json:"bits,omitempty" yaml:"bits,omitempty"`
+}
+
+// Result ist der erzeugte Wert
+type Result struct {
+	// Value wird als Secret gespeichert (bei Schlüsselpaaren: privater Schlüssel, PKCS#8 PEM)
+	Value       string
+	ContentType string
+	// PublicKey (PKIX PEM) bei Schlüsselpaaren – nicht geheim, darf immer zurückgegeben werden
+	PublicKey string
+}
+
+// Validate prüft die Spec, ohne etwas zu erzeugen (z.B. beim Laden von Rotation-Regeln)
+func (s Spec) Validate() error {
+	switch strings.ToLower(s.Type) {
+	case TypePassword:
+		_, err := s.passwordClasses()
+		return err
+	case TypeBytes:
+		return s.validateBytes()
+	case TypeUUID:
+		return nil
+	case TypeEd25519:
+		return nil
+	case TypeECDSA:
+		_, err := curveFor(s.Curve)
+		return err
+	case TypeRSA:
+		_, err := rsaBits(s.Bits)
+		return err
+	case "":
+		return fmt.Errorf("generator type is empty")
+	default:
+		return fmt.Errorf("unknown generator type %q (use password|bytes|uuid|ed25519|ecdsa|rsa)", s.Type)
+	}
+}
+
+// Generate erzeugt einen neuen Wert nach Spec (kryptographisch zufällig)
+func Generate(s Spec) (Result, error) {
+	if err := s.Validate(); err != nil {
+		return Result{}, err
+	}
+
+	switch strings.ToLower(s.Type) {
+	case TypePassword:
+		v, err := s.password()
+		return Result{Value: v}, err
+	case TypeBytes:
+		v, err := s.bytes()
+		return Result{Value: v}, err
+	case TypeUUID:
+		v, err := uuidV4()
+		return Result{Value: v}, err
+	case TypeEd25519:
+		return ed25519Pair()
+	case TypeECDSA:
+		return ecdsaPair(s.Curve)
+	case TypeRSA:
+		return rsaPair(s.Bits)
+	}
+	return Result{}, fmt.Errorf("unknown generator type %q", s.Type)
+}
+
+func uuidV4() (string, error) {
+	var b [16]byte
+	if _, err := rand.Read(b[:]); err != nil {
+		return "", err
+	}
+	b[6] = (b[6] & 0x0f) | 0x40 // Version 4
+	b[8] = (b[8] & 0x3f) | 0x80 // Variante RFC 4122
+	return fmt.Sprintf("%x-%x-%x-%x-%x", b[0:4], b[4:6], b[6:8], b[8:10], b[10:16]), nil
+}
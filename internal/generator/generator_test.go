@@ -0,0 +1,107 @@
+package generator_test
+
+import (
+	"crypto/x509"
+	"encoding/hex"
+	"encoding/pem"
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/timgst1/glass/internal/generator"
+)
+
+func boolp(b bool) *bool { return &b }
+
+func TestGenerate_PasswordRules(t *testing.T) {
+	spec := generator.Spec{
+		Type:       "password",
+		Length:     24,
+		Symbols:    boolp(true),
+		MinDigits:  4,
+		MinSymbols: 3,
+	}
+	for i := 0; i < 20; i++ {
+		res, err := generator.Generate(spec)
+		if err != nil {
+			t.Fatalf("Generate: %v", err)
+		}
+		if len(res.Value) != 24 {
+			t.Fatalf("expected length 24, got %d", len(res.Value))
+		}
+		if n := len(regexp.MustCompile(`[0-9]`).FindAllString(res.Value, -1)); n < 4 {
+			t.Fatalf("expected at least 4 digits in %q", res.Value)
+		}
+		if n := len(regexp.MustCompile(`[^a-zA-Z0-9]`).FindAllString(res.Value, -1)); n < 3 {
+			t.Fatalf("expected at least 3 symbols in %q", res.Value)
+		}
+	}
+
+	res, err := generator.Generate(generator.Spec{Type: "password", Length: 16, Charset: "ab"})
+	if err != nil {
+		t.Fatalf("Generate charset: %v", err)
+	}
+	if strings.Trim(res.Value, "ab") != "" {
+		t.Fatalf("expected only charset characters, got %q", res.Value)
+	}
+}
+
+func TestGenerate_BytesAndUUID(t *testing.T) {
+	res, err := generator.Generate(generator.Spec{Type: "bytes", Length: 16, Encoding: "hex"})
+	if err != nil {
+		t.Fatalf("Generate bytes: %v", err)
+	}
+	if b, err := hex.DecodeString(res.Value); err != nil || len(b) != 16 {
+		t.Fatalf("expected 16 hex-encoded bytes, got %q", res.Value)
+	}
+
+	res, err = generator.Generate(generator.Spec{Type: "uuid"})
+	if err != nil {
+		t.Fatalf("Generate uuid: %v", err)
+	}
+	if !regexp.MustCompile(`^[0-9a-f]{8}-[0-9a-f]{4}-4[0-9a-f]{3}-[89ab][0-9a-f]{3}-[0-9a-f]{12}$`).MatchString(res.Value) {
+		t.Fatalf("invalid uuid v4: %q", res.Value)
+	}
+}
+
+func TestGenerate_KeyPairs(t *testing.T) {
+	for _, spec := range []generator.Spec{
+		{Type: "ed25519"},
+		{Type: "ecdsa", Curve: "P-384"},
+		{Type: "rsa", Bits: 2048},
+	} {
+		res, err := generator.Generate(spec)
+		if err != nil {
+			t.Fatalf("%s: Generate: %v", spec.Type, err)
+		}
+		blk, _ := pem.Decode([]byte(res.Value))
+		if blk == nil || blk.Type != "PRIVATE KEY" {
+			t.Fatalf("%s: expected PKCS#8 PEM private key", spec.Type)
+		}
+		if _, err := x509.ParsePKCS8PrivateKey(blk.Bytes); err != nil {
+			t.Fatalf("%s: parse private key: %v", spec.Type, err)
+		}
+		pub, _ := pem.Decode([]byte(res.PublicKey))
+		if pub == nil || pub.Type != "PUBLIC KEY" {
+			t.Fatalf("%s: expected PKIX PEM public key", spec.Type)
+		}
+	}
+}
+
+func TestSpec_ValidateRejectsBadSpecs(t *testing.T) {
+	for _, spec := range []generator.Spec{
+		{},
+		{Type: "nope"},
+		{Type: "password", Length: 4, MinDigits: 5},
+		{Type: "password", Digits: boolp(false), MinDigits: 1},
+		{Type: "password", Charset: "abc", MinDigits: 1},
+		{Type: "password", Charset: "aab"},
+		{Type: "bytes", Encoding: "base32"},
+		{Type: "ecdsa", Curve: "P-192"},
+		{Type: "rsa", Bits: 1024},
+	} {
+		if err := spec.Validate(); err == nil {
+			t.Fatalf("expected error for %+v", spec)
+		}
+	}
+}
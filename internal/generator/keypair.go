@@ -0,0 +1,87 @@
+package generator
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+	"fmt"
+	"strings"
+)
+
+const pemContentType = "application/x-pem-file"
+
+func curveFor(name string) (elliptic.Curve, error) {
+	switch strings.ToUpper(name) {
+	case "", "P-256", "P256":
+		return elliptic.P256(), nil
+	case "P-384", "P384":
+		return elliptic.P384(), nil
+	case "P-521", "P521":
+		return elliptic.P521(), nil
+	}
+	return nil, fmt.Errorf("unknown ecdsa curve %q (use P-256|P-384|P-521)", name)
+}
+
+func rsaBits(bits int) (int, error) {
+	switch bits {
+	case 0:
+		return 3072, nil
+	case 2048, 3072, 4096:
+		return bits, nil
+	}
+	return 0, fmt.Errorf("unsupported rsa bits %d (use 2048|3072|4096)", bits)
+}
+
+func ed25519Pair() (Result, error) {
+	pub, priv, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		return Result{}, err
+	}
+	return encodePair(priv, pub)
+}
+
+func ecdsaPair(curve string) (Result, error) {
+	c, err := curveFor(curve)
+	if err != nil {
+		return Result{}, err
+	}
+	priv, err := ecdsa.GenerateKey(c, rand.Reader)
+	if err != nil {
+		return Result{}, err
+	}
+	return encodePair(priv, &priv.PublicKey)
+}
+
+func rsaPair(bits int) (Result, error) {
+	n, err := rsaBits(bits)
+	if err != nil {
+		return Result{}, err
+	}
+	priv, err := rsa.GenerateKey(rand.Reader, n)
+	if err != nil {
+		return Result{}, err
+	}
+	return encodePair(priv, &priv.PublicKey)
+}
+
+// encodePair: privater Schlüssel als PKCS#8, öffentlicher als PKIX – beides PEM
+func encodePair(priv crypto.PrivateKey, pub crypto.PublicKey) (Result, error) {
+	privDER, err := x509.MarshalPKCS8PrivateKey(priv)
+	if err != nil {
+		return Result{}, fmt.Errorf("marshal private key: %w", err)
+	}
+	pubDER, err := x509.MarshalPKIXPublicKey(pub)
+	if err != nil {
+		return Result{}, fmt.Errorf("marshal public key: %w", err)
+	}
+	return Result{
+		Value:       string(pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: privDER})),
+		ContentType: pemContentType,
+		PublicKey:   string(pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: pubDER})),
+	}, nil
+}
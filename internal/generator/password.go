@@ -0,0 +1,184 @@
+package generator
+
+import (
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"math/big"
+)
+
+const (
+	lowerChars  = "abcdefghijklmnopqrstuvwxyz"
+	upperChars  = "ABCDEFGHIJKLMNOPQRSTUVWXYZ"
+	digitChars  = "0123456789"
+	symbolChars = "!#$%&()*+,-./:;<=>?@[]^_{|}~"
+
+	defaultPasswordLength = 32
+	maxPasswordLength     = 1024
+	defaultBytesLength    = 32
+	maxBytesLength        = 4096
+)
+
+type charClass struct {
+	chars string
+	min   int
+}
+
+func enabled(p *bool, def bool) bool {
+	if p == nil {
+		return def
+	}
+	return *p
+}
+
+func (s Spec) passwordLength() int {
+	if s.Length == 0 {
+		return defaultPasswordLength
+	}
+	return s.Length
+}
+
+// passwordClasses liefert die aktiven Zeichenklassen; Default: Klein-, Großbuchstaben und Ziffern
+func (s Spec) passwordClasses() ([]charClass, error) {
+	n := s.passwordLength()
+	if n < 1 || n > maxPasswordLength {
+		return nil, fmt.Errorf("password length must be between 1 and %d", maxPasswordLength)
+	}
+
+	if s.Charset != "" {
+		if s.MinLowercase+s.MinUppercase+s.MinDigits+s.MinSymbols > 0 {
+			return nil, fmt.Errorf("min_* rules cannot be combined with charset")
+		}
+		// doppelte Zeichen würden bei der Auswahl häufiger gezogen -> ablehnen statt still verzerren
+		var seen [128]bool
+		for i := 0; i < len(s.Charset); i++ {
+			c := s.Charset[i]
+			if c < 0x21 || c > 0x7e {
+				return nil, fmt.Errorf("charset may only contain printable ASCII characters")
+			}
+			if seen[c] {
+				return nil, fmt.Errorf("charset contains %q more than once", c)
+			}
+			seen[c] = true
+		}
+		return []charClass{{chars: s.Charset}}, nil
+	}
+
+	var classes []charClass
+	add := func(on bool, chars string, min int, name string) error {
+		if min < 0 {
+			return fmt.Errorf("min_%s must not be negative", name)
+		}
+		if !on && min > 0 {
+			return fmt.Errorf("min_%s requires %s to be enabled", name, name)
+		}
+		if on {
+			classes = append(classes, charClass{chars: chars, min: min})
+		}
+		return nil
+	}
+	if err := add(enabled(s.Lowercase, true), lowerChars, s.MinLowercase, "lowercase"); err != nil {
+		return nil, err
+	}
+	if err := add(enabled(s.Uppercase, true), upperChars, s.MinUppercase, "uppercase"); err != nil {
+		return nil, err
+	}
+	if err := add(enabled(s.Digits, true), digitChars, s.MinDigits, "digits"); err != nil {
+		return nil, err
+	}
+	if err := add(enabled(s.Symbols, false), symbolChars, s.MinSymbols, "symbols"); err != nil {
+		return nil, err
+	}
+
+	if len(classes) == 0 {
+		return nil, fmt.Errorf("password needs at least one character class")
+	}
+	total := 0
+	for _, c := range classes {
+		total += c.min
+	}
+	if total > n {
+		return nil, fmt.Errorf("sum of min_* (%d) exceeds length %d", total, n)
+	}
+	return classes, nil
+}
+
+func (s Spec) password() (string, error) {
+	classes, err := s.passwordClasses()
+	if err != nil {
+		return "", err
+	}
+
+	all := ""
+	out := make([]byte, 0, s.passwordLength())
+	for _, c := range classes {
+		all += c.chars
+		for i := 0; i < c.min; i++ {
+			ch, err := randChar(c.chars)
+			if err != nil {
+				return "", err
+			}
+			out = append(out, ch)
+		}
+	}
+	for len(out) < s.passwordLength() {
+		ch, err := randChar(all)
+		if err != nil {
+			return "", err
+		}
+		out = append(out, ch)
+	}
+
+	// Pflichtzeichen stehen vorne -> mischen (Fisher-Yates)
+	for i := len(out) - 1; i > 0; i-- {
+		j, err := randInt(i + 1)
+		if err != nil {
+			return "", err
+		}
+		out[i], out[j] = out[j], out[i]
+	}
+	return string(out), nil
+}
+
+func randChar(chars string) (byte, error) {
+	i, err := randInt(len(chars))
+	if err != nil {
+		return 0, err
+	}
+	return chars[i], nil
+}
+
+func randInt(n int) (int, error) {
+	v, err := rand.Int(rand.Reader, big.NewInt(int64(n)))
+	if err != nil {
+		return 0, err
+	}
+	return int(v.Int64()), nil
+}
+
+func (s Spec) validateBytes() error {
+	if s.Length < 0 || s.Length > maxBytesLength {
+		return fmt.Errorf("bytes length must be between 1 and %d", maxBytesLength)
+	}
+	switch s.Encoding {
+	case "", "base64", "hex":
+		return nil
+	}
+	return fmt.Errorf("unknown bytes encoding %q (use hex|base64)", s.Encoding)
+}
+
+func (s Spec) bytes() (string, error) {
+	n := s.Length
+	if n == 0 {
+		n = defaultBytesLength
+	}
+	b := make([]byte, n)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	if s.Encoding == "hex" {
+		return hex.EncodeToString(b), nil
+	}
+	return base64.StdEncoding.EncodeToString(b), nil
+}
@@ -0,0 +1,80 @@
+package health
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"path/filepath"
+
+	"github.com/timgst1/glass/internal/audit"
+	"github.com/timgst1/glass/internal/crypto/envelope"
+	"github.com/timgst1/glass/internal/policy"
+)
+
+// SQLite: Ping plus triviale Query (Ping allein prüft nur den Pool, nicht die Datei)
+func SQLite(db *sql.DB) Check {
+	return Check{Name: "sqlite", Run: func(ctx context.Context) (Status, string, error) {
+		if err := db.PingContext(ctx); err != nil {
+			return StatusFail, "database unavailable", err
+		}
+		var n int
+		if err := db.QueryRowContext(ctx, `SELECT COUNT(*) FROM sqlite_master`).Scan(&n); err != nil {
+			return StatusFail, "database unavailable", err
+		}
+		return StatusOK, "", nil
+	}}
+}
+
+// PolicySource ist der Ausschnitt von policy.Manager, den die Prüfung braucht
+type PolicySource interface {
+	Current() (*policy.Document, bool)
+	Status() policy.ReloadStatus
+}
+
+// Policy: ohne Dokument fail; schlug der letzte Reload fehl, läuft das alte Dokument weiter (degraded)
+func Policy(src PolicySource) Check {
+	return Check{Name: "policy", Run: func(ctx context.Context) (Status, string, error) {
+		if doc, ok := src.Current(); !ok || doc == nil {
+			return StatusFail, "no policy loaded", nil
+		}
+		if st := src.Status(); st.LastError != "" {
+			return StatusDegraded, "last reload failed, serving last known good", errors.New(st.LastError)
+		}
+		return StatusOK, "", nil
+	}}
+}
+
+func Keyring(env *envelope.Envelope) Check {
+	return Check{Name: "keyring", Run: func(ctx context.Context) (Status, string, error) {
+		if err := env.CheckActiveKEK(); err != nil {
+			return StatusFail, "active kek missing", err
+		}
+		return StatusOK, "", nil
+	}}
+}
+
+// DiskSpace prüft den freien Platz auf dem Volume, auf dem path liegt
+func DiskSpace(path string, minFree uint64) Check {
+	dir := filepath.Dir(path)
+	return Check{Name: "disk", Run: func(ctx context.Context) (Status, string, error) {
+		free, err := freeBytes(dir)
+		if err != nil {
+			return StatusDegraded, "free space unknown", err
+		}
+		if free < minFree {
+			return StatusDegraded, "low disk space", fmt.Errorf("%d MiB free on %s (minimum %d MiB)", free>>20, dir, minFree>>20)
+		}
+		return StatusOK, "", nil
+	}}
+}
+
+// AuditSinks: ein hängender fail-closed Sink weist ohnehin jeden Request ab
+func AuditSinks(c audit.Checker) Check {
+	return Check{Name: "audit", Run: func(ctx context.Context) (Status, string, error) {
+		if err := c.Ready(); err != nil {
+			return StatusDegraded, "audit sink unavailable", err
+		}
+		return StatusOK, "", nil
+	}}
+}
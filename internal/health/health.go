@@ -0,0 +1,90 @@
+package health
+
+import (
+	"context"
+	"log/slog"
+	"sync"
+	"time"
+)
+
+// Status einer Prüfung: fail macht /readyz immer unready, degraded nur mit READINESS_STRICT=true
+type Status string
+
+const (
+	StatusOK       Status = "ok"
+	StatusDegraded Status = "degraded"
+	StatusFail     Status = "fail"
+)
+
+// Result geht ungeschützt über /readyz: Message ist ein fester Kurztext, die eigentliche Ursache steht nur im Log
+type Result struct {
+	Name    string `json:"name"`
+	Status  Status `json:"status"`
+	Message string `json:"message,omitempty"`
+}
+
+// Check liefert Status, festen Kurztext und (nur fürs Log) die Ursache
+type Check struct {
+	Name string
+	Run  func(ctx context.Context) (Status, string, error)
+}
+
+type Report struct {
+	Status Status   `json:"status"`
+	Strict bool     `json:"strict"`
+	Ready  bool     `json:"ready"`
+	Checks []Result `json:"checks"`
+}
+
+type Checker struct {
+	strict  bool
+	checks  []Check
+	timeout time.Duration
+
+	mu   sync.Mutex
+	last map[string]Status
+	log  *slog.Logger
+}
+
+func NewChecker(strict bool, checks ...Check) *Checker {
+	return &Checker{strict: strict, checks: checks, timeout: 2 * time.Second, last: map[string]Status{}, log: slog.Default()}
+}
+
+// Run führt alle Prüfungen nacheinander aus (gemeinsames Timeout) und fasst sie zusammen
+func (c *Checker) Run(ctx context.Context) Report {
+	ctx, cancel := context.WithTimeout(ctx, c.timeout)
+	defer cancel()
+
+	rep := Report{Status: StatusOK, Strict: c.strict, Checks: make([]Result, 0, len(c.checks))}
+	for _, ch := range c.checks {
+		st, msg, err := ch.Run(ctx)
+		c.logChange(ch.Name, st, err)
+		rep.Checks = append(rep.Checks, Result{Name: ch.Name, Status: st, Message: msg})
+		if worse(st, rep.Status) {
+			rep.Status = st
+		}
+	}
+	rep.Ready = rep.Status == StatusOK || (rep.Status == StatusDegraded && !c.strict)
+	return rep
+}
+
+// logChange loggt nur Statuswechsel, sonst schreibt jede Probe (alle paar Sekunden) dieselbe Zeile
+func (c *Checker) logChange(name string, st Status, err error) {
+	c.mu.Lock()
+	prev, seen := c.last[name]
+	c.last[name] = st
+	c.mu.Unlock()
+	if prev == st && (seen || st == StatusOK) {
+		return
+	}
+	if st == StatusOK {
+		c.log.Info("readiness check recovered", "check", name)
+		return
+	}
+	c.log.Warn("readiness check not ok", "check", name, "status", string(st), "err", err)
+}
+
+func worse(a, b Status) bool {
+	rank := map[Status]int{StatusOK: 0, StatusDegraded: 1, StatusFail: 2}
+	return rank[a] > rank[b]
+}
@@ -0,0 +1,60 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"github.com/timgst1/glass/internal/service"
+)
+
+type destroyReq struct {
+	Key      string  `json:"key"`
+	Versions []int64 `json:"versions"`
+	All      bool    `json:"all"`
+}
+
+func (h SecretHandler) DestroySecret(w http.ResponseWriter, r *http.Request) {
+	var in destroyReq
+	if err := json.NewDecoder(r.Body).Decode(&in); err != nil {
+		http.Error(w, "invalid json body", http.StatusBadRequest)
+		return
+	}
+
+	in.Key = normalizeKey(in.Key)
+	if in.Key == "" {
+		http.Error(w, "missing field: key", http.StatusBadRequest)
+		return
+	}
+	// Explizit: entweder Versionen oder all=true, damit nicht versehentlich alles vernichtet wird
+	if in.All == (len(in.Versions) > 0) {
+		http.Error(w, "exactly one of versions or all=true is required", http.StatusBadRequest)
+		return
+	}
+	for _, v := range in.Versions {
+		if v < 1 {
+			http.Error(w, "invalid field: versions", http.StatusBadRequest)
+			return
+		}
+	}
+
+	n, err := h.Secrets.DestroySecretVersions(r.Context(), in.Key, in.Versions)
+	if err != nil {
+		if errors.Is(err, service.ErrNotFound) {
+			http.Error(w, "not found", http.StatusNotFound)
+			return
+		}
+		if errors.Is(err, service.ErrForbidden) {
+			http.Error(w, "forbidden", http.StatusForbidden)
+			return
+		}
+		writeInternalError(w, r, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(map[string]any{
+		"key":       in.Key,
+		"destroyed": n,
+	})
+}
@@ -0,0 +1,122 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"github.com/timgst1/glass/internal/service"
+	"github.com/timgst1/glass/internal/webhook"
+)
+
+type WebhookHandler struct {
+	Webhooks webhook.Registry
+}
+
+type webhookReq struct {
+	ID        string   `json:"id"`
+	URL       string   `json:"url"`
+	KeyPrefix string   `json:"key_prefix"`
+	Events    []string `json:"events"`
+	// Secret optional: leer = wird generiert und einmalig in der Antwort geliefert
+	Secret string `json:"secret"`
+}
+
+// webhookJSON enthält nie das HMAC-Secret (Ausnahme: Antwort auf POST)
+type webhookJSON struct {
+	ID        string   `json:"id"`
+	URL       string   `json:"url"`
+	KeyPrefix string   `json:"key_prefix"`
+	Events    []string `json:"events"`
+	Source    string   `json:"source"`
+	CreatedAt string   `json:"created_at,omitempty"`
+	CreatedBy string   `json:"created_by,omitempty"`
+	Secret    string   `json:"secret,omitempty"`
+}
+
+func toWebhookJSON(s webhook.Subscription) webhookJSON {
+	events := s.Events
+	if events == nil {
+		events = []string{}
+	}
+	return webhookJSON{
+		ID:        s.ID,
+		URL:       s.URL,
+		KeyPrefix: s.KeyPrefix,
+		Events:    events,
+		Source:    s.Source,
+		CreatedAt: s.CreatedAt,
+		CreatedBy: s.CreatedBy,
+	}
+}
+
+func (h WebhookHandler) ListWebhooks(w http.ResponseWriter, r *http.Request) {
+	subs, err := h.Webhooks.List(r.Context())
+	if err != nil {
+		writeWebhookError(w, r, err)
+		return
+	}
+	out := make([]webhookJSON, 0, len(subs))
+	for _, s := range subs {
+		out = append(out, toWebhookJSON(s))
+	}
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(map[string]any{"webhooks": out})
+}
+
+func (h WebhookHandler) CreateWebhook(w http.ResponseWriter, r *http.Request) {
+	var in webhookReq
+	if err := json.NewDecoder(r.Body).Decode(&in); err != nil {
+		http.Error(w, "invalid json body", http.StatusBadRequest)
+		return
+	}
+
+	sub, err := h.Webhooks.Create(r.Context(), webhook.Subscription{
+		ID:        in.ID,
+		URL:       in.URL,
+		KeyPrefix: normalizePrefix(in.KeyPrefix),
+		Events:    in.Events,
+		Secret:    in.Secret,
+	})
+	if err != nil {
+		writeWebhookError(w, r, err)
+		return
+	}
+
+	out := toWebhookJSON(sub)
+	out.Secret = sub.Secret
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
+	w.WriteHeader(http.StatusCreated)
+	_ = json.NewEncoder(w).Encode(out)
+}
+
+func (h WebhookHandler) DeleteWebhook(w http.ResponseWriter, r *http.Request) {
+	id := r.URL.Query().Get("id")
+	if id == "" {
+		http.Error(w, "missing query parameter: id", http.StatusBadRequest)
+		return
+	}
+	if err := h.Webhooks.Delete(r.Context(), id); err != nil {
+		writeWebhookError(w, r, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func writeWebhookError(w http.ResponseWriter, r *http.Request, err error) {
+	switch {
+	case errors.Is(err, service.ErrNotFound):
+		http.Error(w, "not found", http.StatusNotFound)
+	case errors.Is(err, service.ErrForbidden):
+		http.Error(w, "forbidden", http.StatusForbidden)
+	case errors.Is(err, service.ErrInvalid), errors.Is(err, service.ErrConflict):
+		status := http.StatusBadRequest
+		if errors.Is(err, service.ErrConflict) {
+			status = http.StatusConflict
+		}
+		http.Error(w, err.Error(), status)
+	default:
+		writeInternalError(w, r, err)
+	}
+}
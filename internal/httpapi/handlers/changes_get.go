@@ -0,0 +1,81 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/timgst1/glass/internal/service"
+)
+
+const defaultChangesLimit = 100
+
+// ListChanges liefert den Change-Feed ab since. Ohne since gibt es nur die aktuelle Revision
+// (Startpunkt für einen inkrementellen Sync nach einem vollen GET /v1/secrets).
+func (h SecretHandler) ListChanges(w http.ResponseWriter, r *http.Request) {
+	prefix := normalizePrefix(r.URL.Query().Get("prefix"))
+
+	since := int64(-1)
+	if ss := r.URL.Query().Get("since"); ss != "" {
+		n, err := strconv.ParseInt(ss, 10, 64)
+		if err != nil || n < 0 {
+			http.Error(w, "invalid query parameter: since", http.StatusBadRequest)
+			return
+		}
+		since = n
+	}
+
+	limit := defaultChangesLimit
+	if ls := r.URL.Query().Get("limit"); ls != "" {
+		n, err := strconv.Atoi(ls)
+		if err != nil || n < 1 || n > maxListLimit {
+			http.Error(w, "invalid query parameter: limit", http.StatusBadRequest)
+			return
+		}
+		limit = n
+	}
+
+	feed, err := h.Secrets.ListChanges(r.Context(), prefix, since, limit)
+	if err != nil {
+		if errors.Is(err, service.ErrForbidden) {
+			http.Error(w, "forbidden", http.StatusForbidden)
+			return
+		}
+		writeInternalError(w, r, err)
+		return
+	}
+
+	changes := make([]changeJSON, 0, len(feed.Changes))
+	for _, c := range feed.Changes {
+		changes = append(changes, toChangeJSON(c))
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(map[string]any{
+		"changes":  changes,
+		"revision": feed.Revision,
+		"more":     feed.More,
+	})
+}
+
+// changeJSON ist die JSON-Form eines Change-Events (/v1/changes und /v1/watch)
+type changeJSON struct {
+	Revision  int64  `json:"revision"`
+	Key       string `json:"key"`
+	Type      string `json:"type"`
+	Version   int64  `json:"version,omitempty"`
+	CreatedAt string `json:"created_at"`
+	CreatedBy string `json:"created_by"`
+}
+
+func toChangeJSON(c service.Change) changeJSON {
+	return changeJSON{
+		Revision:  c.Revision,
+		Key:       c.Key,
+		Type:      string(c.Type),
+		Version:   c.Version,
+		CreatedAt: c.CreatedAt,
+		CreatedBy: c.CreatedBy,
+	}
+}
@@ -0,0 +1,18 @@
+package handlers
+
+import (
+	"log/slog"
+	"net/http"
+
+	"github.com/timgst1/glass/internal/logging"
+)
+
+// writeInternalError loggt die Ursache (mit request_id) und gibt dem Client nur die Request-ID zur Zuordnung
+func writeInternalError(w http.ResponseWriter, r *http.Request, err error) {
+	slog.ErrorContext(r.Context(), "request failed", "method", r.Method, "path", r.URL.Path, "err", err)
+	msg := "internal error"
+	if id := logging.RequestID(r.Context()); id != "" {
+		msg += " (request_id: " + id + ")"
+	}
+	http.Error(w, msg, http.StatusInternalServerError)
+}
@@ -0,0 +1,23 @@
+package handlers
+
+import (
+	"strconv"
+	"strings"
+)
+
+// etag formatiert eine Secret-Version als (starkes) ETag, z.B. "3"
+func etag(version int64) string {
+	return strconv.Quote(strconv.FormatInt(version, 10))
+}
+
+// parseETagVersion liest eine Version aus einem If-Match-Wert ("3", W/"3" oder 3)
+func parseETagVersion(s string) (int64, bool) {
+	s = strings.TrimSpace(s)
+	s = strings.TrimPrefix(s, "W/")
+	s = strings.Trim(s, `"`)
+	v, err := strconv.ParseInt(s, 10, 64)
+	if err != nil || v < 1 {
+		return 0, false
+	}
+	return v, true
+}
@@ -0,0 +1,55 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"github.com/timgst1/glass/internal/service"
+)
+
+// ListKeys liefert nur Keys und "Ordner" (ohne Werte) – braucht daher nur list auf den Prefix.
+func (h SecretHandler) ListKeys(w http.ResponseWriter, r *http.Request) {
+	prefix := normalizePrefix(r.URL.Query().Get("prefix"))
+	delimiter := r.URL.Query().Get("delimiter")
+
+	listing, err := h.Secrets.ListKeys(r.Context(), prefix, delimiter)
+	if err != nil {
+		if errors.Is(err, service.ErrForbidden) {
+			http.Error(w, "forbidden", http.StatusForbidden)
+			return
+		}
+		writeInternalError(w, r, err)
+		return
+	}
+
+	type outKey struct {
+		Key         string `json:"key"`
+		Version     int64  `json:"version"`
+		CreatedAt   string `json:"created_at"`
+		CreatedBy   string `json:"created_by"`
+		ExpiresAt   string `json:"expires_at,omitempty"`
+		Binary      bool   `json:"binary,omitempty"`
+		ContentType string `json:"content_type,omitempty"`
+	}
+	keys := make([]outKey, 0, len(listing.Keys))
+	for _, m := range listing.Keys {
+		keys = append(keys, outKey{
+			Key:         m.Key,
+			Version:     m.Version,
+			CreatedAt:   m.CreatedAt,
+			CreatedBy:   m.CreatedBy,
+			ExpiresAt:   m.ExpiresAt,
+			Binary:      m.Binary,
+			ContentType: m.ContentType,
+		})
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(map[string]any{
+		"prefix":    prefix,
+		"delimiter": delimiter,
+		"keys":      keys,
+		"prefixes":  listing.Prefixes,
+	})
+}
@@ -0,0 +1,74 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"github.com/timgst1/glass/internal/service"
+)
+
+func (h SecretHandler) DeleteSecret(w http.ResponseWriter, r *http.Request) {
+	key := normalizeKey(r.URL.Query().Get("key"))
+	if key == "" {
+		http.Error(w, "missing query parameter: key", http.StatusBadRequest)
+		return
+	}
+
+	ver, err := h.Secrets.DeleteSecret(r.Context(), key)
+	if err != nil {
+		if errors.Is(err, service.ErrNotFound) {
+			http.Error(w, "not found", http.StatusNotFound)
+			return
+		}
+		if errors.Is(err, service.ErrForbidden) {
+			http.Error(w, "forbidden", http.StatusForbidden)
+			return
+		}
+		writeInternalError(w, r, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(map[string]any{
+		"key":     key,
+		"version": ver,
+		"deleted": true,
+	})
+}
+
+func (h SecretHandler) UndeleteSecret(w http.ResponseWriter, r *http.Request) {
+	key := normalizeKey(r.URL.Query().Get("key"))
+	if key == "" {
+		http.Error(w, "missing query parameter: key", http.StatusBadRequest)
+		return
+	}
+
+	ver, err := h.Secrets.UndeleteSecret(r.Context(), key)
+	if err != nil {
+		if errors.Is(err, service.ErrNotFound) {
+			http.Error(w, "not found", http.StatusNotFound)
+			return
+		}
+		if errors.Is(err, service.ErrForbidden) {
+			http.Error(w, "forbidden", http.StatusForbidden)
+			return
+		}
+		if errors.Is(err, service.ErrConflict) {
+			http.Error(w, "secret is not deleted", http.StatusConflict)
+			return
+		}
+		if errors.Is(err, service.ErrDestroyed) {
+			http.Error(w, "previous version destroyed", http.StatusGone)
+			return
+		}
+		writeInternalError(w, r, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(map[string]any{
+		"key":     key,
+		"version": ver,
+	})
+}
@@ -0,0 +1,107 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/timgst1/glass/internal/generator"
+	"github.com/timgst1/glass/internal/service"
+)
+
+type generateReq struct {
+	Key       string         `json:"key"`
+	Generator generator.Spec `json:"generator"`
+	// wie bei PUT /v1/secret
+	ExpectedVersion *int64 `json:"expected_version,omitempty"`
+	TTL             string `json:"ttl,omitempty"`
+	ExpiresAt       string `json:"expires_at,omitempty"`
+}
+
+// GenerateSecret erzeugt den Wert serverseitig und schreibt ihn über den normalen PutSecret-Pfad
+// (verschlüsselt, versioniert). Mit ?return=false verlässt der Klartext den Server nie.
+func (h SecretHandler) GenerateSecret(w http.ResponseWriter, r *http.Request) {
+	var in generateReq
+	if err := json.NewDecoder(r.Body).Decode(&in); err != nil {
+		http.Error(w, "invalid json body", http.StatusBadRequest)
+		return
+	}
+
+	in.Key = normalizeKey(in.Key)
+	if in.Key == "" {
+		http.Error(w, "missing field: key", http.StatusBadRequest)
+		return
+	}
+
+	returnValue := true
+	switch r.URL.Query().Get("return") {
+	case "", "true", "1":
+	case "false", "0":
+		returnValue = false
+	default:
+		http.Error(w, "invalid query parameter: return", http.StatusBadRequest)
+		return
+	}
+
+	if err := in.Generator.Validate(); err != nil {
+		http.Error(w, "invalid field: generator: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	expected, err := expectedVersionFromRequest(r, in.ExpectedVersion)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	res, err := generator.Generate(in.Generator)
+	if err != nil {
+		writeInternalError(w, r, err)
+		return
+	}
+
+	value, opts, err := valueAndOptions(putReq{
+		Key:         in.Key,
+		Value:       res.Value,
+		ContentType: res.ContentType,
+		TTL:         in.TTL,
+		ExpiresAt:   in.ExpiresAt,
+	}, expected, time.Now())
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	ver, err := h.Secrets.PutSecret(r.Context(), in.Key, value, opts...)
+	if err != nil {
+		if errors.Is(err, service.ErrForbidden) {
+			http.Error(w, "forbidden", http.StatusForbidden)
+			return
+		}
+		var vc *service.VersionConflictError
+		if errors.As(err, &vc) {
+			writeVersionConflict(w, vc)
+			return
+		}
+		writeInternalError(w, r, err)
+		return
+	}
+
+	out := map[string]any{
+		"key":     in.Key,
+		"version": ver,
+	}
+	if returnValue {
+		out["value"] = res.Value
+	}
+	if res.PublicKey != "" {
+		// öffentlicher Schlüssel ist nicht geheim
+		out["public_key"] = res.PublicKey
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
+	w.Header().Set("ETag", etag(ver))
+	_ = json.NewEncoder(w).Encode(out)
+}
@@ -4,12 +4,19 @@ import (
 	"encoding/json"
 	"errors"
 	"net/http"
+	"strconv"
 
+	"github.com/timgst1/glass/internal/crypto/envelope"
+	"github.com/timgst1/glass/internal/rotation"
 	"github.com/timgst1/glass/internal/service"
 )
 
 type SecretHandler struct {
 	Secrets service.SecretService
+	// Rotation ist optional (nil = keine Rotationsregeln)
+	Rotation *rotation.Rules
+	// WatchOpts für GET /v1/watch
+	WatchOpts WatchOptions
 }
 
 func (h SecretHandler) GetSecret(w http.ResponseWriter, r *http.Request) {
@@ -19,7 +26,33 @@ func (h SecretHandler) GetSecret(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	val, err := h.Secrets.GetSecret(r.Context(), key)
+	// version 0 = neueste Version
+	var version int64
+	if vs := r.URL.Query().Get("version"); vs != "" {
+		v, err := strconv.ParseInt(vs, 10, 64)
+		if err != nil || v < 1 {
+			http.Error(w, "invalid query parameter: version", http.StatusBadRequest)
+			return
+		}
+		version = v
+	}
+
+	enc, err := valueEncodingFromRequest(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	var resolve bool
+	if rs := r.URL.Query().Get("resolve"); rs != "" {
+		resolve, err = strconv.ParseBool(rs)
+		if err != nil {
+			http.Error(w, "invalid query parameter: resolve", http.StatusBadRequest)
+			return
+		}
+	}
+
+	it, err := h.Secrets.GetSecretVersion(r.Context(), key, version)
 	if err != nil {
 		if errors.Is(err, service.ErrNotFound) {
 			http.Error(w, "not found", http.StatusNotFound)
@@ -29,10 +62,63 @@ func (h SecretHandler) GetSecret(w http.ResponseWriter, r *http.Request) {
 			http.Error(w, "forbidden", http.StatusForbidden)
 			return
 		}
-		http.Error(w, "internal error", http.StatusInternalServerError)
+		if errors.Is(err, service.ErrDestroyed) {
+			http.Error(w, "secret version destroyed", http.StatusGone)
+			return
+		}
+		if errors.Is(err, envelope.ErrUnknownKEK) {
+			http.Error(w, "cannot decrypt secret: unknown kek_id (check KEK_DIR)", http.StatusInternalServerError)
+			return
+		}
+		writeInternalError(w, r, err)
 		return
 	}
 
+	var resolved []service.ResolvedRef
+	if resolve {
+		if it.Binary {
+			http.Error(w, "resolve is not supported for binary secrets", http.StatusBadRequest)
+			return
+		}
+		it.Value, resolved, err = service.ResolveReferences(r.Context(), h.Secrets, key, it.Value)
+		if err != nil {
+			writeResolveError(w, r, err)
+			return
+		}
+	}
+
 	w.Header().Set("Content-Type", "application/json")
-	_ = json.NewEncoder(w).Encode(map[string]string{"value": val})
+	// aufgelöste Werte hängen auch von den referenzierten Keys ab -> ETag wäre irreführend
+	if !resolve {
+		w.Header().Set("ETag", etag(it.Version))
+	}
+	val, usedEnc := encodeValue(it.Value, it.Binary, enc)
+	out := map[string]any{"value": val}
+	if usedEnc != "" {
+		out["encoding"] = usedEnc
+	}
+	if it.ContentType != "" {
+		out["content_type"] = it.ContentType
+	}
+	if resolve {
+		refs := make([]map[string]any, 0, len(resolved))
+		for _, ref := range resolved {
+			refs = append(refs, map[string]any{"key": ref.Key, "version": ref.Version})
+		}
+		out["resolved"] = refs
+	}
+	_ = json.NewEncoder(w).Encode(out)
+}
+
+func writeResolveError(w http.ResponseWriter, r *http.Request, err error) {
+	switch {
+	case errors.Is(err, service.ErrUnresolvable):
+		http.Error(w, err.Error(), http.StatusUnprocessableEntity)
+	case errors.Is(err, service.ErrForbidden):
+		http.Error(w, "forbidden: referenced secret", http.StatusForbidden)
+	case errors.Is(err, envelope.ErrUnknownKEK):
+		http.Error(w, "cannot decrypt referenced secret: unknown kek_id (check KEK_DIR)", http.StatusInternalServerError)
+	default:
+		writeInternalError(w, r, err)
+	}
 }
@@ -4,6 +4,7 @@ import (
 	"encoding/json"
 	"errors"
 	"net/http"
+	"time"
 
 	"github.com/timgst1/glass/internal/service"
 )
@@ -25,15 +26,34 @@ func (h SecretHandler) GetSecretMeta(w http.ResponseWriter, r *http.Request) {
 			http.Error(w, "forbidden", http.StatusForbidden)
 			return
 		}
-		http.Error(w, "internal error", http.StatusInternalServerError)
+		writeInternalError(w, r, err)
 		return
 	}
 
-	w.Header().Set("Content-Type", "application/json")
-	_ = json.NewEncoder(w).Encode(map[string]any{
+	out := map[string]any{
 		"key":        meta.Key,
 		"version":    meta.Version,
 		"created_at": meta.CreatedAt,
 		"created_by": meta.CreatedBy,
-	})
+	}
+	if meta.Destroyed {
+		out["destroyed"] = true
+	}
+	if meta.ExpiresAt != "" {
+		out["expires_at"] = meta.ExpiresAt
+	}
+	if meta.Binary {
+		out["binary"] = true
+	}
+	if meta.ContentType != "" {
+		out["content_type"] = meta.ContentType
+	}
+	if next, ok := h.Rotation.NextRotation(meta.Key, meta.CreatedAt); ok {
+		out["next_rotation_at"] = next.UTC().Format(time.RFC3339)
+	}
+	addMetadata(out, meta.Metadata)
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("ETag", etag(meta.Version))
+	_ = json.NewEncoder(w).Encode(out)
 }
@@ -0,0 +1,79 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"github.com/timgst1/glass/internal/service"
+)
+
+// patchMetaReq folgt JSON Merge Patch (RFC 7386): null entfernt ein Label/eine Annotation,
+// fehlende Felder bleiben unverändert.
+type patchMetaReq struct {
+	Labels      map[string]*string `json:"labels,omitempty"`
+	Annotations map[string]*string `json:"annotations,omitempty"`
+	Description *string            `json:"description,omitempty"`
+	Owner       *string            `json:"owner,omitempty"`
+}
+
+func (h SecretHandler) PatchSecretMeta(w http.ResponseWriter, r *http.Request) {
+	key := normalizeKey(r.URL.Query().Get("key"))
+	if key == "" {
+		http.Error(w, "missing query parameter: key", http.StatusBadRequest)
+		return
+	}
+
+	var in patchMetaReq
+	if err := json.NewDecoder(r.Body).Decode(&in); err != nil {
+		http.Error(w, "invalid json body", http.StatusBadRequest)
+		return
+	}
+
+	md, err := h.Secrets.UpdateSecretMetadata(r.Context(), key, service.MetadataPatch{
+		Labels:      in.Labels,
+		Annotations: in.Annotations,
+		Description: in.Description,
+		Owner:       in.Owner,
+	})
+	if err != nil {
+		if errors.Is(err, service.ErrNotFound) {
+			http.Error(w, "not found", http.StatusNotFound)
+			return
+		}
+		if errors.Is(err, service.ErrForbidden) {
+			http.Error(w, "forbidden", http.StatusForbidden)
+			return
+		}
+		if errors.Is(err, service.ErrInvalid) {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		writeInternalError(w, r, err)
+		return
+	}
+
+	out := map[string]any{"key": key}
+	addMetadata(out, md)
+	out["updated_at"] = md.UpdatedAt
+	out["updated_by"] = md.UpdatedBy
+
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(out)
+}
+
+// addMetadata ergänzt nur gesetzte Key-Metadaten, damit Antworten ohne Labels unverändert bleiben
+func addMetadata(out map[string]any, md service.KeyMetadata) {
+	if len(md.Labels) > 0 {
+		out["labels"] = md.Labels
+	}
+	if len(md.Annotations) > 0 {
+		out["annotations"] = md.Annotations
+	}
+	if md.Description != "" {
+		out["description"] = md.Description
+	}
+	if md.Owner != "" {
+		out["owner"] = md.Owner
+	}
+}
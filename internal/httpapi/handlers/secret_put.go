@@ -1,9 +1,12 @@
 package handlers
 
 import (
+	"encoding/base64"
 	"encoding/json"
 	"errors"
 	"net/http"
+	"strings"
+	"time"
 
 	"github.com/timgst1/glass/internal/service"
 )
@@ -11,6 +14,14 @@ import (
 type putReq struct {
 	Key   string `json:"key"`
 	Value string `json:"value"`
+	// ValueBase64: Binärwert (z.B. Keystore), alternativ zu value
+	ValueBase64 *string `json:"value_base64,omitempty"`
+	ContentType string  `json:"content_type,omitempty"`
+	// ExpectedVersion: optimistic concurrency, 0 = nur anlegen wenn nicht vorhanden
+	ExpectedVersion *int64 `json:"expected_version,omitempty"`
+	// Ablauf: entweder relative TTL (Go-Duration, z.B. "15m") oder absoluter Zeitpunkt (RFC3339)
+	TTL       string `json:"ttl,omitempty"`
+	ExpiresAt string `json:"expires_at,omitempty"`
 }
 
 func (h SecretHandler) PutSecret(w http.ResponseWriter, r *http.Request) {
@@ -26,19 +37,142 @@ func (h SecretHandler) PutSecret(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	ver, err := h.Secrets.PutSecret(r.Context(), in.Key, in.Value)
+	expected, err := expectedVersionFromRequest(r, in.ExpectedVersion)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	value, opts, err := valueAndOptions(in, expected, time.Now())
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	ver, err := h.Secrets.PutSecret(r.Context(), in.Key, value, opts...)
 	if err != nil {
 		if errors.Is(err, service.ErrForbidden) {
 			http.Error(w, "forbidden", http.StatusForbidden)
 			return
 		}
-		http.Error(w, "internal error", http.StatusInternalServerError)
+		var vc *service.VersionConflictError
+		if errors.As(err, &vc) {
+			writeVersionConflict(w, vc)
+			return
+		}
+		writeInternalError(w, r, err)
 		return
 	}
 
 	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("ETag", etag(ver))
 	_ = json.NewEncoder(w).Encode(map[string]any{
 		"key":     in.Key,
 		"version": ver,
 	})
 }
+
+// valueAndOptions wertet die Body-Felder eines putReq aus (value/value_base64, content_type, ttl/expires_at)
+func valueAndOptions(in putReq, expected *int64, now time.Time) (string, []service.PutOption, error) {
+	expiresAt, err := expiresAtFromRequest(in, now)
+	if err != nil {
+		return "", nil, err
+	}
+
+	value := in.Value
+	var opts []service.PutOption
+	if in.ValueBase64 != nil {
+		if in.Value != "" {
+			return "", nil, errors.New("value and value_base64 are mutually exclusive")
+		}
+		b, err := base64.StdEncoding.DecodeString(*in.ValueBase64)
+		if err != nil {
+			return "", nil, errors.New("invalid field: value_base64")
+		}
+		value = string(b)
+		opts = append(opts, service.WithBinary())
+	}
+	if in.ContentType != "" {
+		opts = append(opts, service.WithContentType(in.ContentType))
+	}
+	if expected != nil {
+		opts = append(opts, service.WithExpectedVersion(*expected))
+	}
+	if !expiresAt.IsZero() {
+		opts = append(opts, service.WithExpiresAt(expiresAt))
+	}
+	return value, opts, nil
+}
+
+// expectedVersionFromRequest kombiniert expected_version aus dem Body mit If-Match / If-None-Match.
+// If-None-Match: * entspricht expected_version=0 (nur anlegen).
+func expectedVersionFromRequest(r *http.Request, fromBody *int64) (*int64, error) {
+	var fromHeader *int64
+
+	if im := strings.TrimSpace(r.Header.Get("If-Match")); im != "" {
+		v, ok := parseETagVersion(im)
+		if !ok {
+			return nil, errors.New("invalid header: If-Match (use the ETag of a version)")
+		}
+		fromHeader = &v
+	}
+	if inm := strings.TrimSpace(r.Header.Get("If-None-Match")); inm != "" {
+		if inm != "*" {
+			return nil, errors.New("invalid header: If-None-Match (only * is supported)")
+		}
+		if fromHeader != nil {
+			return nil, errors.New("If-Match and If-None-Match are mutually exclusive")
+		}
+		zero := int64(0)
+		fromHeader = &zero
+	}
+
+	if fromBody != nil && *fromBody < 0 {
+		return nil, errors.New("invalid field: expected_version")
+	}
+	if fromBody != nil && fromHeader != nil && *fromBody != *fromHeader {
+		return nil, errors.New("expected_version and If-Match/If-None-Match disagree")
+	}
+	if fromBody != nil {
+		return fromBody, nil
+	}
+	return fromHeader, nil
+}
+
+func expiresAtFromRequest(in putReq, now time.Time) (time.Time, error) {
+	if in.TTL != "" && in.ExpiresAt != "" {
+		return time.Time{}, errors.New("ttl and expires_at are mutually exclusive")
+	}
+	if in.TTL != "" {
+		d, err := time.ParseDuration(in.TTL)
+		if err != nil || d <= 0 {
+			return time.Time{}, errors.New("invalid field: ttl (use a positive duration like 15m)")
+		}
+		return now.Add(d), nil
+	}
+	if in.ExpiresAt != "" {
+		t, err := time.Parse(time.RFC3339, in.ExpiresAt)
+		if err != nil {
+			return time.Time{}, errors.New("invalid field: expires_at (use RFC3339)")
+		}
+		if !t.After(now) {
+			return time.Time{}, errors.New("invalid field: expires_at must be in the future")
+		}
+		return t, nil
+	}
+	return time.Time{}, nil
+}
+
+func writeVersionConflict(w http.ResponseWriter, vc *service.VersionConflictError) {
+	w.Header().Set("Content-Type", "application/json")
+	if vc.Current > 0 {
+		w.Header().Set("ETag", etag(vc.Current))
+	}
+	w.WriteHeader(http.StatusConflict)
+	_ = json.NewEncoder(w).Encode(map[string]any{
+		"error":            "version conflict",
+		"key":              vc.Key,
+		"expected_version": vc.Expected,
+		"current_version":  vc.Current,
+	})
+}
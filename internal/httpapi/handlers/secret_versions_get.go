@@ -0,0 +1,95 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/timgst1/glass/internal/service"
+)
+
+const (
+	defaultVersionsLimit = 50
+	maxVersionsLimit     = 1000
+)
+
+func (h SecretHandler) ListSecretVersions(w http.ResponseWriter, r *http.Request) {
+	key := normalizeKey(r.URL.Query().Get("key"))
+	if key == "" {
+		http.Error(w, "missing query parameter: key", http.StatusBadRequest)
+		return
+	}
+
+	limit := defaultVersionsLimit
+	if ls := r.URL.Query().Get("limit"); ls != "" {
+		n, err := strconv.Atoi(ls)
+		if err != nil || n < 1 || n > maxVersionsLimit {
+			http.Error(w, "invalid query parameter: limit", http.StatusBadRequest)
+			return
+		}
+		limit = n
+	}
+
+	var before int64
+	if bs := r.URL.Query().Get("before"); bs != "" {
+		n, err := strconv.ParseInt(bs, 10, 64)
+		if err != nil || n < 1 {
+			http.Error(w, "invalid query parameter: before", http.StatusBadRequest)
+			return
+		}
+		before = n
+	}
+
+	// Eine Version mehr laden, um zu wissen, ob es eine weitere Seite gibt
+	metas, err := h.Secrets.ListSecretVersions(r.Context(), key, limit+1, before)
+	if err != nil {
+		if errors.Is(err, service.ErrNotFound) {
+			http.Error(w, "not found", http.StatusNotFound)
+			return
+		}
+		if errors.Is(err, service.ErrForbidden) {
+			http.Error(w, "forbidden", http.StatusForbidden)
+			return
+		}
+		writeInternalError(w, r, err)
+		return
+	}
+
+	type outVersion struct {
+		Version   int64  `json:"version"`
+		CreatedAt string `json:"created_at"`
+		CreatedBy string `json:"created_by"`
+		Deleted   bool   `json:"deleted,omitempty"`
+		Destroyed bool   `json:"destroyed,omitempty"`
+		ExpiresAt string `json:"expires_at,omitempty"`
+	}
+
+	hasMore := len(metas) > limit
+	if hasMore {
+		metas = metas[:limit]
+	}
+
+	out := make([]outVersion, 0, len(metas))
+	for _, m := range metas {
+		out = append(out, outVersion{
+			Version:   m.Version,
+			CreatedAt: m.CreatedAt,
+			CreatedBy: m.CreatedBy,
+			Deleted:   m.Deleted,
+			Destroyed: m.Destroyed,
+			ExpiresAt: m.ExpiresAt,
+		})
+	}
+
+	resp := map[string]any{
+		"key":      key,
+		"versions": out,
+	}
+	if hasMore && len(out) > 0 {
+		resp["next_before"] = out[len(out)-1].Version
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(resp)
+}
@@ -0,0 +1,86 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/timgst1/glass/internal/service"
+)
+
+const maxBatchPutItems = 100
+
+type batchPutReq struct {
+	Items []putReq `json:"items"`
+}
+
+// BatchPutSecrets schreibt alle Items atomar: entweder bekommen alle Keys eine neue Version oder keiner.
+func (h SecretHandler) BatchPutSecrets(w http.ResponseWriter, r *http.Request) {
+	var in batchPutReq
+	if err := json.NewDecoder(r.Body).Decode(&in); err != nil {
+		http.Error(w, "invalid json body", http.StatusBadRequest)
+		return
+	}
+	if len(in.Items) == 0 {
+		http.Error(w, "missing field: items", http.StatusBadRequest)
+		return
+	}
+	if len(in.Items) > maxBatchPutItems {
+		http.Error(w, fmt.Sprintf("too many items (max %d)", maxBatchPutItems), http.StatusBadRequest)
+		return
+	}
+
+	now := time.Now()
+	writes := make([]service.SecretWrite, 0, len(in.Items))
+	for i, it := range in.Items {
+		it.Key = normalizeKey(it.Key)
+		if it.Key == "" {
+			http.Error(w, fmt.Sprintf("items[%d]: missing field: key", i), http.StatusBadRequest)
+			return
+		}
+		if it.ExpectedVersion != nil && *it.ExpectedVersion < 0 {
+			http.Error(w, fmt.Sprintf("items[%d]: invalid field: expected_version", i), http.StatusBadRequest)
+			return
+		}
+
+		value, opts, err := valueAndOptions(it, it.ExpectedVersion, now)
+		if err != nil {
+			http.Error(w, fmt.Sprintf("items[%d]: %v", i, err), http.StatusBadRequest)
+			return
+		}
+		writes = append(writes, service.SecretWrite{Key: it.Key, Value: value, Opts: opts})
+	}
+
+	vers, err := h.Secrets.PutSecrets(r.Context(), writes)
+	if err != nil {
+		if errors.Is(err, service.ErrForbidden) {
+			http.Error(w, "forbidden", http.StatusForbidden)
+			return
+		}
+		if errors.Is(err, service.ErrInvalid) {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		var vc *service.VersionConflictError
+		if errors.As(err, &vc) {
+			writeVersionConflict(w, vc)
+			return
+		}
+		writeInternalError(w, r, err)
+		return
+	}
+
+	type outItem struct {
+		Key     string `json:"key"`
+		Version int64  `json:"version"`
+	}
+	out := make([]outItem, 0, len(writes))
+	for i, wr := range writes {
+		out = append(out, outItem{Key: wr.Key, Version: vers[i]})
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(map[string]any{"items": out})
+}
@@ -1,14 +1,20 @@
 package handlers
 
 import (
+	"encoding/base64"
 	"encoding/json"
 	"errors"
 	"net/http"
+	"strconv"
 	"strings"
 
+	"github.com/timgst1/glass/internal/crypto/envelope"
+	"github.com/timgst1/glass/internal/labels"
 	"github.com/timgst1/glass/internal/service"
 )
 
+const maxListLimit = 1000
+
 func (h SecretHandler) ListSecrets(w http.ResponseWriter, r *http.Request) {
 	prefix := normalizePrefix(r.URL.Query().Get("prefix"))
 	if prefix == "" {
@@ -43,50 +49,116 @@ func (h SecretHandler) ListSecrets(w http.ResponseWriter, r *http.Request) {
 		flatten = false
 	}
 
-	items, err := h.Secrets.ListSecrets(r.Context(), prefix)
+	enc, err := valueEncodingFromRequest(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	// Pagination (nur format=list): ohne limit wird wie bisher alles geliefert
+	limit := 0
+	if ls := r.URL.Query().Get("limit"); ls != "" {
+		n, err := strconv.Atoi(ls)
+		if err != nil || n < 1 || n > maxListLimit {
+			http.Error(w, "invalid query parameter: limit", http.StatusBadRequest)
+			return
+		}
+		limit = n
+	}
+	cursor := r.URL.Query().Get("cursor")
+	if (limit > 0 || cursor != "") && format != "list" {
+		http.Error(w, "limit/cursor require format=list", http.StatusBadRequest)
+		return
+	}
+	if cursor != "" && limit == 0 {
+		http.Error(w, "missing query parameter: limit", http.StatusBadRequest)
+		return
+	}
+	after, err := decodeListCursor(cursor, prefix)
+	if err != nil {
+		http.Error(w, "invalid query parameter: cursor", http.StatusBadRequest)
+		return
+	}
+
+	var opts []service.ListOption
+	if limit > 0 {
+		// Einen Eintrag mehr laden, um zu wissen, ob es eine weitere Seite gibt
+		opts = append(opts, service.WithPage(after, limit+1))
+	}
+	if raw := r.URL.Query().Get("labelSelector"); raw != "" {
+		sel, err := labels.Parse(raw)
+		if err != nil {
+			http.Error(w, "invalid query parameter: labelSelector: "+err.Error(), http.StatusBadRequest)
+			return
+		}
+		opts = append(opts, service.WithLabelSelector(sel))
+	}
+
+	items, err := h.Secrets.ListSecrets(r.Context(), prefix, opts...)
 	if err != nil {
 		if errors.Is(err, service.ErrForbidden) {
 			http.Error(w, "forbidden", http.StatusForbidden)
 			return
 		}
-		http.Error(w, "internal error", http.StatusInternalServerError)
+		if errors.Is(err, envelope.ErrUnknownKEK) {
+			http.Error(w, "cannot decrypt secret: unknown kek_id (check KEK_DIR)", http.StatusInternalServerError)
+			return
+		}
+		writeInternalError(w, r, err)
 		return
 	}
 
+	nextCursor := ""
+	if limit > 0 && len(items) > limit {
+		items = items[:limit]
+		nextCursor = encodeListCursor(items[limit-1].Key)
+	}
+
 	w.Header().Set("Content-Type", "application/json")
 
 	if format == "list" {
 		if !withMeta {
 			type outItem struct {
-				Key   string `json:"key"`
-				Value string `json:"value"`
+				Key      string `json:"key"`
+				Value    string `json:"value"`
+				Encoding string `json:"encoding,omitempty"`
 			}
 			out := make([]outItem, 0, len(items))
 			for _, it := range items {
-				out = append(out, outItem{Key: it.Key, Value: it.Value})
+				val, usedEnc := encodeValue(it.Value, it.Binary, enc)
+				out = append(out, outItem{Key: it.Key, Value: val, Encoding: usedEnc})
 			}
-			_ = json.NewEncoder(w).Encode(map[string]any{"items": out})
+			writeListPage(w, out, nextCursor)
 			return
 		}
 
 		type outItemMeta struct {
-			Key       string `json:"key"`
-			Value     string `json:"value"`
-			Version   int64  `json:"version"`
-			CreatedAt string `json:"created_at"`
-			CreatedBy string `json:"created_by"`
+			Key         string `json:"key"`
+			Value       string `json:"value"`
+			Encoding    string `json:"encoding,omitempty"`
+			ContentType string `json:"content_type,omitempty"`
+			Version     int64  `json:"version"`
+			CreatedAt   string `json:"created_at"`
+			CreatedBy   string `json:"created_by"`
+			ExpiresAt   string `json:"expires_at,omitempty"`
+			keyMetadataJSON
 		}
 		out := make([]outItemMeta, 0, len(items))
 		for _, it := range items {
+			val, usedEnc := encodeValue(it.Value, it.Binary, enc)
 			out = append(out, outItemMeta{
-				Key:       it.Key,
-				Value:     it.Value,
-				Version:   it.Version,
-				CreatedAt: it.CreatedAt,
-				CreatedBy: it.CreatedBy,
+				Key:             it.Key,
+				Value:           val,
+				Encoding:        usedEnc,
+				ContentType:     it.ContentType,
+				Version:         it.Version,
+				CreatedAt:       it.CreatedAt,
+				CreatedBy:       it.CreatedBy,
+				ExpiresAt:       it.ExpiresAt,
+				keyMetadataJSON: toKeyMetadataJSON(it.Metadata),
 			})
 		}
-		_ = json.NewEncoder(w).Encode(map[string]any{"items": out})
+		writeListPage(w, out, nextCursor)
 		return
 	}
 
@@ -115,17 +187,22 @@ func (h SecretHandler) ListSecrets(w http.ResponseWriter, r *http.Request) {
 			if k == "" {
 				continue
 			}
-			data[k] = it.Value
+			// Binärwerte sind immer base64; mit encoding=base64 sind es alle Werte (ESO: decodingStrategy Base64)
+			data[k], _ = encodeValue(it.Value, it.Binary, enc)
 		}
 		_ = json.NewEncoder(w).Encode(map[string]any{"data": data})
 		return
 	}
 
 	type metaVal struct {
-		Value     string `json:"value"`
-		Version   int64  `json:"version"`
-		CreatedAt string `json:"created_at"`
-		CreatedBy string `json:"created_by"`
+		Value       string `json:"value"`
+		Encoding    string `json:"encoding,omitempty"`
+		ContentType string `json:"content_type,omitempty"`
+		Version     int64  `json:"version"`
+		CreatedAt   string `json:"created_at"`
+		CreatedBy   string `json:"created_by"`
+		ExpiresAt   string `json:"expires_at,omitempty"`
+		keyMetadataJSON
 	}
 
 	data := make(map[string]metaVal, len(items))
@@ -134,12 +211,63 @@ func (h SecretHandler) ListSecrets(w http.ResponseWriter, r *http.Request) {
 		if k == "" {
 			continue
 		}
+		val, usedEnc := encodeValue(it.Value, it.Binary, enc)
 		data[k] = metaVal{
-			Value:     it.Value,
-			Version:   it.Version,
-			CreatedAt: it.CreatedAt,
-			CreatedBy: it.CreatedBy,
+			Value:           val,
+			Encoding:        usedEnc,
+			ContentType:     it.ContentType,
+			Version:         it.Version,
+			CreatedAt:       it.CreatedAt,
+			CreatedBy:       it.CreatedBy,
+			ExpiresAt:       it.ExpiresAt,
+			keyMetadataJSON: toKeyMetadataJSON(it.Metadata),
 		}
 	}
 	_ = json.NewEncoder(w).Encode(map[string]any{"data": data})
 }
+
+func writeListPage(w http.ResponseWriter, items any, nextCursor string) {
+	out := map[string]any{"items": items}
+	if nextCursor != "" {
+		out["next_cursor"] = nextCursor
+	}
+	_ = json.NewEncoder(w).Encode(out)
+}
+
+// Der Cursor ist für Clients opak: base64url des letzten gelieferten Keys
+func encodeListCursor(lastKey string) string {
+	return base64.RawURLEncoding.EncodeToString([]byte(lastKey))
+}
+
+func decodeListCursor(cursor, prefix string) (string, error) {
+	if cursor == "" {
+		return "", nil
+	}
+	b, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil {
+		return "", err
+	}
+	k := string(b)
+	// Cursor aus einer anderen Liste (anderer prefix) ablehnen
+	if !strings.HasPrefix(k, prefix) {
+		return "", errors.New("cursor does not match prefix")
+	}
+	return k, nil
+}
+
+// keyMetadataJSON wird in withMeta-Ausgaben eingebettet
+type keyMetadataJSON struct {
+	Labels      map[string]string `json:"labels,omitempty"`
+	Annotations map[string]string `json:"annotations,omitempty"`
+	Description string            `json:"description,omitempty"`
+	Owner       string            `json:"owner,omitempty"`
+}
+
+func toKeyMetadataJSON(md service.KeyMetadata) keyMetadataJSON {
+	return keyMetadataJSON{
+		Labels:      md.Labels,
+		Annotations: md.Annotations,
+		Description: md.Description,
+		Owner:       md.Owner,
+	}
+}
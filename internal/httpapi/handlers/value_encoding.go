@@ -0,0 +1,28 @@
+package handlers
+
+import (
+	"encoding/base64"
+	"errors"
+	"net/http"
+	"strings"
+)
+
+const encodingBase64 = "base64"
+
+// valueEncodingFromRequest liest ?encoding= ("" = automatisch, "base64" = alle Werte base64)
+func valueEncodingFromRequest(r *http.Request) (string, error) {
+	enc := strings.ToLower(strings.TrimSpace(r.URL.Query().Get("encoding")))
+	if enc != "" && enc != encodingBase64 {
+		return "", errors.New("invalid query parameter: encoding (use base64)")
+	}
+	return enc, nil
+}
+
+// encodeValue liefert den Wert für JSON-Antworten und die verwendete Kodierung.
+// Binärwerte sind kein gültiges UTF-8 und werden deshalb immer base64-kodiert.
+func encodeValue(v string, binary bool, enc string) (string, string) {
+	if binary || enc == encodingBase64 {
+		return base64.StdEncoding.EncodeToString([]byte(v)), encodingBase64
+	}
+	return v, ""
+}
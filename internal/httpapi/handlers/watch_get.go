@@ -0,0 +1,150 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/timgst1/glass/internal/service"
+)
+
+const (
+	defaultWatchPollInterval = time.Second
+	defaultWatchHeartbeat    = 15 * time.Second
+	// watchWriteTimeout gilt pro Schreibvorgang (der globale WriteTimeout ist für Streams aufgehoben)
+	watchWriteTimeout = 10 * time.Second
+)
+
+// WatchOptions steuert GET /v1/watch (Zero-Werte = Defaults)
+type WatchOptions struct {
+	PollInterval time.Duration
+	Heartbeat    time.Duration
+	// Done wird beim Server-Shutdown geschlossen und beendet offene Streams
+	Done <-chan struct{}
+}
+
+// Watch streamt Change-Events als Server-Sent Events. Fortsetzen über Last-Event-ID (oder ?since=),
+// ohne beides beginnt der Stream bei der aktuellen Revision. Jede Abfrage läuft durch ListChanges,
+// d.h. list auf den Prefix und read pro Event werden bei jedem Event neu geprüft.
+func (h SecretHandler) Watch(w http.ResponseWriter, r *http.Request) {
+	prefix := normalizePrefix(r.URL.Query().Get("prefix"))
+
+	since := int64(-1)
+	resume := r.Header.Get("Last-Event-ID")
+	if resume == "" {
+		resume = r.URL.Query().Get("since")
+	}
+	if resume != "" {
+		n, err := strconv.ParseInt(strings.TrimSpace(resume), 10, 64)
+		if err != nil || n < 0 {
+			http.Error(w, "invalid Last-Event-ID/since", http.StatusBadRequest)
+			return
+		}
+		since = n
+	}
+
+	poll := h.WatchOpts.PollInterval
+	if poll <= 0 {
+		poll = defaultWatchPollInterval
+	}
+	heartbeat := h.WatchOpts.Heartbeat
+	if heartbeat <= 0 {
+		heartbeat = defaultWatchHeartbeat
+	}
+
+	// erste Abfrage vor den Headern: fehlende Berechtigung wird noch als normales 403 gemeldet
+	ctx := r.Context()
+	feed, err := h.Secrets.ListChanges(ctx, prefix, since, maxListLimit)
+	if err != nil {
+		if errors.Is(err, service.ErrForbidden) {
+			http.Error(w, "forbidden", http.StatusForbidden)
+			return
+		}
+		writeInternalError(w, r, err)
+		return
+	}
+
+	rc := http.NewResponseController(w)
+	// Streams laufen länger als der WriteTimeout des Servers -> Deadline für diese Verbindung aufheben
+	if err := rc.SetWriteDeadline(time.Time{}); err != nil && !errors.Is(err, http.ErrNotSupported) {
+		writeInternalError(w, r, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-store")
+	w.Header().Set("X-Accel-Buffering", "no")
+	w.WriteHeader(http.StatusOK)
+
+	// write schreibt einen Block und flusht; Fehler = Client weg oder hängt
+	write := func(block string) bool {
+		_ = rc.SetWriteDeadline(time.Now().Add(watchWriteTimeout))
+		if _, err := fmt.Fprint(w, block); err != nil {
+			return false
+		}
+		return rc.Flush() == nil
+	}
+
+	if !write(fmt.Sprintf("retry: %d\n\n", poll.Milliseconds()*3)) {
+		return
+	}
+
+	pollT := time.NewTicker(poll)
+	defer pollT.Stop()
+	hbT := time.NewTicker(heartbeat)
+	defer hbT.Stop()
+
+	for {
+		var b strings.Builder
+		for _, c := range feed.Changes {
+			data, _ := json.Marshal(toChangeJSON(c))
+			fmt.Fprintf(&b, "id: %d\nevent: %s\ndata: %s\n\n", c.Revision, c.Type, data)
+		}
+		if b.Len() > 0 && !write(b.String()) {
+			return
+		}
+		since = feed.Revision
+
+		// volle Seite: sofort weiterlesen
+		if !feed.More {
+			if !h.waitForPoll(r, pollT, hbT, write) {
+				return
+			}
+		}
+
+		feed, err = h.Secrets.ListChanges(ctx, prefix, since, maxListLimit)
+		if err != nil {
+			if ctx.Err() != nil {
+				return
+			}
+			msg := "internal error"
+			if errors.Is(err, service.ErrForbidden) {
+				msg = "forbidden"
+			}
+			_ = write("event: error\ndata: " + msg + "\n\n")
+			return
+		}
+	}
+}
+
+// waitForPoll wartet auf den nächsten Poll und schickt zwischendurch Heartbeats; false = Stream beenden
+func (h SecretHandler) waitForPoll(r *http.Request, pollT, hbT *time.Ticker, write func(string) bool) bool {
+	for {
+		select {
+		case <-r.Context().Done():
+			return false
+		case <-h.WatchOpts.Done:
+			return false
+		case <-hbT.C:
+			if !write(": heartbeat\n\n") {
+				return false
+			}
+		case <-pollT.C:
+			return true
+		}
+	}
+}
@@ -0,0 +1,41 @@
+package middleware
+
+import (
+	"log/slog"
+	"net/http"
+	"time"
+
+	"github.com/timgst1/glass/internal/logging"
+)
+
+// AccessLog schreibt pro Request eine Zeile (Methode, Route-Pattern, Status, Dauer, Subject; request_id kommt
+// über den Context-Handler dazu). Query-Strings und Bodies werden nie geloggt – sie enthalten Keys bzw. Werte.
+// Probes (/healthz, /readyz) nur auf Debug.
+func AccessLog(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		sw := &statusWriter{ResponseWriter: w, status: http.StatusOK}
+		start := time.Now()
+
+		next.ServeHTTP(sw, r)
+
+		route := routePattern(r)
+		level := slog.LevelInfo
+		switch {
+		case sw.status >= 500:
+			level = slog.LevelError
+		case route == "/healthz" || route == "/readyz":
+			level = slog.LevelDebug
+		}
+
+		attrs := []slog.Attr{
+			slog.String("method", r.Method),
+			slog.String("route", route),
+			slog.Int("status", sw.status),
+			slog.Float64("duration_ms", float64(time.Since(start).Microseconds())/1000),
+		}
+		if info, ok := logging.InfoFromContext(r.Context()); ok && info.Subject() != "" {
+			attrs = append(attrs, slog.String("subject", info.Subject()))
+		}
+		slog.LogAttrs(r.Context(), level, "http request", attrs...)
+	})
+}
@@ -0,0 +1,99 @@
+package middleware
+
+import (
+	"context"
+	"log/slog"
+	"net"
+	"net/http"
+	"time"
+
+	"github.com/timgst1/glass/internal/audit"
+	"github.com/timgst1/glass/internal/authn"
+	"github.com/timgst1/glass/internal/logging"
+)
+
+// Audit schreibt nach jedem (authentifizierten) Request einen Audit-Eintrag; muss nach RequireAuth laufen.
+// l == nil = Audit aus. Implementiert l audit.Checker (fail-closed Sinks) und ist nicht bereit,
+// wird der Request nicht ausgeführt, sondern mit 503 beantwortet (und trotzdem protokolliert).
+func Audit(l audit.Logger) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		if l == nil {
+			return next
+		}
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			// X-Request-ID setzt die RequestID-Middleware am Root-Router
+			reqID := logging.RequestID(r.Context())
+			if reqID == "" {
+				reqID = newRequestID()
+			}
+
+			ctx, rec := audit.WithRecord(r.Context())
+			sw := &statusWriter{ResponseWriter: w, status: http.StatusOK}
+			start := time.Now()
+
+			if err := ready(l); err != nil {
+				slog.WarnContext(r.Context(), "rejecting request, audit sink unavailable", "err", err)
+				http.Error(sw, "audit sink unavailable", http.StatusServiceUnavailable)
+			} else {
+				next.ServeHTTP(sw, r.WithContext(ctx))
+			}
+
+			e := audit.Entry{
+				Time:      start.UTC().Format(time.RFC3339Nano),
+				RequestID: reqID,
+				Subject:   "unknown",
+				Method:    r.Method,
+				Path:      r.URL.Path,
+				Status:    sw.status,
+				SourceIP:  sourceIP(r),
+			}
+			if sub, ok := authn.SubjectFromContext(r.Context()); ok {
+				e.Subject = sub.Kind + ":" + sub.Name
+			}
+			rec.Apply(&e)
+
+			// Context des Requests kann schon abgebrochen sein (Client weg) – Eintrag trotzdem schreiben
+			if err := l.Append(context.WithoutCancel(r.Context()), e); err != nil {
+				slog.ErrorContext(r.Context(), "audit append failed", "err", err)
+			}
+		})
+	}
+}
+
+func ready(l audit.Logger) error {
+	c, ok := l.(audit.Checker)
+	if !ok {
+		return nil
+	}
+	return c.Ready()
+}
+
+// statusWriter merkt sich den Status; Unwrap lässt http.ResponseController (Flush, Deadlines) durch
+type statusWriter struct {
+	http.ResponseWriter
+	status      int
+	wroteHeader bool
+}
+
+func (w *statusWriter) WriteHeader(code int) {
+	if !w.wroteHeader {
+		w.status = code
+		w.wroteHeader = true
+	}
+	w.ResponseWriter.WriteHeader(code)
+}
+
+func (w *statusWriter) Write(b []byte) (int, error) {
+	w.wroteHeader = true
+	return w.ResponseWriter.Write(b)
+}
+
+func (w *statusWriter) Unwrap() http.ResponseWriter { return w.ResponseWriter }
+
+func sourceIP(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
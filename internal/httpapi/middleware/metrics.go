@@ -0,0 +1,43 @@
+package middleware
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/timgst1/glass/internal/metrics"
+)
+
+// Metrics zählt Requests und misst die Latenz pro Route-Pattern (nicht pro URL, sonst wächst die Kardinalität
+// mit jedem Query-Parameter). Reihenfolge am Root-Router: siehe httpapi.NewRouter.
+func Metrics(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		sw := &statusWriter{ResponseWriter: w, status: http.StatusOK}
+		start := time.Now()
+
+		next.ServeHTTP(sw, r)
+
+		labels := []string{routePattern(r), methodLabel(r.Method), strconv.Itoa(sw.status)}
+		metrics.HTTPRequests.WithLabelValues(labels...).Inc()
+		metrics.HTTPDuration.WithLabelValues(labels...).Observe(time.Since(start).Seconds())
+	})
+}
+
+// routePattern liefert das chi-Pattern (z.B. "/v1/secret"); erst nach dem Routing gesetzt
+func routePattern(r *http.Request) string {
+	if rc := chi.RouteContext(r.Context()); rc != nil && rc.RoutePattern() != "" {
+		return rc.RoutePattern()
+	}
+	return "unmatched"
+}
+
+// methodLabel fasst unbekannte Methoden zusammen (beliebige Methoden-Strings kommen vom Client)
+func methodLabel(m string) string {
+	switch m {
+	case http.MethodGet, http.MethodHead, http.MethodPost, http.MethodPut, http.MethodPatch,
+		http.MethodDelete, http.MethodOptions:
+		return m
+	}
+	return "OTHER"
+}
@@ -4,6 +4,11 @@ import (
 	"net/http"
 
 	"github.com/timgst1/glass/internal/authn"
+	"github.com/timgst1/glass/internal/logging"
+	"github.com/timgst1/glass/internal/metrics"
+	"github.com/timgst1/glass/internal/tracing"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
 )
 
 func RequireAuth(a authn.Authenticator) func(http.Handler) http.Handler {
@@ -13,12 +18,19 @@ func RequireAuth(a authn.Authenticator) func(http.Handler) http.Handler {
 				http.Error(w, "authenticator not configured", http.StatusInternalServerError)
 				return
 			}
+			_, span := tracing.Start(r.Context(), "authn.Authenticate")
 			sub, err := a.Authenticate(r)
 			if err != nil {
+				span.SetStatus(codes.Error, "unauthorized")
+				span.End()
+				metrics.AuthnFailures.Inc()
 				w.Header().Set("WWW-Authenticate", "Bearer")
 				http.Error(w, "unauthorized", http.StatusUnauthorized)
 				return
 			}
+			span.SetAttributes(attribute.String("glass.subject", sub.Kind+":"+sub.Name))
+			span.End()
+			logging.SetSubject(r.Context(), sub.Kind+":"+sub.Name)
 
 			r = r.WithContext(authn.WithSubject(r.Context(), sub))
 			next.ServeHTTP(w, r)
@@ -4,33 +4,81 @@ import (
 	"net/http"
 
 	"github.com/go-chi/chi/v5"
+	"github.com/timgst1/glass/internal/audit"
 	"github.com/timgst1/glass/internal/authn"
+	"github.com/timgst1/glass/internal/health"
 	"github.com/timgst1/glass/internal/httpapi/handlers"
 	"github.com/timgst1/glass/internal/httpapi/middleware"
+	"github.com/timgst1/glass/internal/rotation"
 	"github.com/timgst1/glass/internal/service"
+	"github.com/timgst1/glass/internal/webhook"
 )
 
 type Deps struct {
 	SecretService service.SecretService
 	Authenticator authn.Authenticator
+	// Rotation (optional) liefert next_rotation_at in /v1/secret/meta
+	Rotation *rotation.Rules
+	// Watch steuert Poll-Intervall/Heartbeat von /v1/watch (Zero-Werte = Defaults)
+	Watch handlers.WatchOptions
+	// Webhooks (optional, nur sqlite): Admin-API unter /v1/admin/webhooks
+	Webhooks webhook.Registry
+	// Audit (optional) bekommt einen Eintrag pro authentifiziertem /v1-Request
+	Audit audit.Logger
+	// Readiness (optional) prüft /readyz; nil = immer ready
+	Readiness *health.Checker
+	// Metrics (optional) wird unter /metrics ohne Auth ausgeliefert; nil = eigener Listener oder aus
+	Metrics http.Handler
 }
 
 func NewRouter(deps Deps) http.Handler {
 	r := chi.NewRouter()
+	// Reihenfolge: RequestID → Trace → Metrics → AccessLog. RequestID muss vorne stehen, weil
+	// AccessLog und Audit die ID aus dem Context lesen.
+	r.Use(middleware.RequestID)
+	r.Use(middleware.Trace)
+	r.Use(middleware.Metrics)
+	r.Use(middleware.AccessLog)
 
 	r.Get("/healthz", func(w http.ResponseWriter, r *http.Request) { w.WriteHeader(200); w.Write([]byte("ok")) })
-	r.Get("/readyz", func(w http.ResponseWriter, r *http.Request) { w.WriteHeader(200); w.Write([]byte("ready")) })
+	if deps.Readiness != nil {
+		r.Get("/readyz", handlers.Readiness(deps.Readiness))
+	} else {
+		r.Get("/readyz", func(w http.ResponseWriter, r *http.Request) { w.WriteHeader(200); w.Write([]byte("ready")) })
+	}
+	if deps.Metrics != nil {
+		r.Handle("/metrics", deps.Metrics)
+	}
 
-	sh := handlers.SecretHandler{Secrets: deps.SecretService}
+	sh := handlers.SecretHandler{Secrets: deps.SecretService, Rotation: deps.Rotation, WatchOpts: deps.Watch}
 
 	r.Route("/v1", func(r chi.Router) {
 		r.Use(middleware.RequireAuth(deps.Authenticator))
+		r.Use(middleware.Audit(deps.Audit))
 
 		r.Get("/secret", sh.GetSecret)
 		r.Put("/secret", sh.PutSecret)
+		r.Post("/secret/generate", sh.GenerateSecret)
+		r.Delete("/secret", sh.DeleteSecret)
+		r.Post("/secret/undelete", sh.UndeleteSecret)
 
 		r.Get("/secret/meta", sh.GetSecretMeta)
+		r.Patch("/secret/meta", sh.PatchSecretMeta)
+		r.Get("/secret/versions", sh.ListSecretVersions)
 		r.Get("/secrets", sh.ListSecrets)
+		r.Post("/secrets:batchPut", sh.BatchPutSecrets)
+		r.Get("/keys", sh.ListKeys)
+		r.Get("/changes", sh.ListChanges)
+		r.Get("/watch", sh.Watch)
+
+		r.Post("/admin/destroy", sh.DestroySecret)
+
+		if deps.Webhooks != nil {
+			wh := handlers.WebhookHandler{Webhooks: deps.Webhooks}
+			r.Get("/admin/webhooks", wh.ListWebhooks)
+			r.Post("/admin/webhooks", wh.CreateWebhook)
+			r.Delete("/admin/webhooks", wh.DeleteWebhook)
+		}
 	})
 
 	return r
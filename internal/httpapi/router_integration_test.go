@@ -1,20 +1,44 @@
 package httpapi_test
 
 import (
+	"bufio"
 	"bytes"
+	"context"
+	"database/sql"
+	"encoding/base64"
 	"encoding/json"
+	"errors"
+	"io"
+	"log/slog"
 	"net/http"
 	"net/http/httptest"
 	"os"
 	"path/filepath"
+	"strconv"
+	"strings"
 	"testing"
+	"time"
 
+	"github.com/timgst1/glass/internal/audit"
 	"github.com/timgst1/glass/internal/authn"
 	"github.com/timgst1/glass/internal/authz"
+	"github.com/timgst1/glass/internal/crypto/envelope"
+	"github.com/timgst1/glass/internal/generator"
+	"github.com/timgst1/glass/internal/health"
 	"github.com/timgst1/glass/internal/httpapi"
+	"github.com/timgst1/glass/internal/httpapi/handlers"
+	"github.com/timgst1/glass/internal/logging"
+	"github.com/timgst1/glass/internal/metrics"
 	"github.com/timgst1/glass/internal/policy"
+	"github.com/timgst1/glass/internal/rotation"
 	"github.com/timgst1/glass/internal/service"
 	"github.com/timgst1/glass/internal/storage/sqlite"
+	"github.com/timgst1/glass/internal/tracing"
+	"github.com/timgst1/glass/internal/webhook"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/propagation"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
 )
 
 type staticPolicySource struct{ doc *policy.Document }
@@ -85,6 +109,34 @@ func docAllowDemoReadWrite() *policy.Document {
 	}
 }
 
+// read/write/list auf team-a/, delete nur für team-a/db
+func docAllowTeamADeleteDBOnly() *policy.Document {
+	var s policy.Subject
+	s.Name = "eso"
+	s.Match.Kind = "bearer"
+	s.Match.Name = "webhook"
+
+	r := policy.Role{
+		Name: "team-a-deleter",
+		Permissions: []policy.Permission{
+			{Action: "read", KeyPrefix: "team-a/"},
+			{Action: "write", KeyPrefix: "team-a/"},
+			{Action: "list", KeyPrefix: "team-a/"},
+			{Action: "delete", KeyExact: "team-a/db"},
+		},
+	}
+
+	return &policy.Document{
+		APIVersion: "glass.secretstore/v1alpha1",
+		Kind:       "Policy",
+		Subjects:   []policy.Subject{s},
+		Roles:      []policy.Role{r},
+		Bindings: []policy.Binding{
+			{Subject: "eso", Roles: []string{"team-a-deleter"}},
+		},
+	}
+}
+
 func docDenyDemo() *policy.Document {
 	var s policy.Subject
 	s.Name = "eso"
@@ -178,6 +230,38 @@ func newSQLiteService(t *testing.T) service.SecretService {
 	return service.NewSQLiteSecretService(db, nil)
 }
 
+func openTestDB(t *testing.T) *sql.DB {
+	t.Helper()
+
+	db, err := sqlite.Open(filepath.Join(t.TempDir(), "test.sqlite"))
+	if err != nil {
+		t.Fatalf("sqlite.Open: %v", err)
+	}
+	t.Cleanup(func() { _ = db.Close() })
+
+	if err := sqlite.Migrate(db); err != nil {
+		t.Fatalf("sqlite.Migrate: %v", err)
+	}
+	return db
+}
+
+// newTestEnvelope legt einen Keyring mit genau einem KEK (kekID, alle Bytes = fill) an
+func newTestEnvelope(t *testing.T, kekID string, fill byte) *envelope.Envelope {
+	t.Helper()
+
+	dir := t.TempDir()
+	raw := bytes.Repeat([]byte{fill}, 32)
+	if err := os.WriteFile(filepath.Join(dir, kekID), []byte(base64.StdEncoding.EncodeToString(raw)), 0o600); err != nil {
+		t.Fatalf("write kek: %v", err)
+	}
+
+	kr, err := envelope.LoadKeyring(dir, kekID)
+	if err != nil {
+		t.Fatalf("LoadKeyring: %v", err)
+	}
+	return envelope.New(kr)
+}
+
 func newTestServerWithService(t *testing.T, doc *policy.Document, base service.SecretService) (*httptest.Server, string) {
 	t.Helper()
 
@@ -613,3 +697,1542 @@ func TestV1SecretsList_OKWithLeadingSlashInPrefix(t *testing.T) {
 		t.Fatalf("expected %d, got %d", http.StatusOK, resp.StatusCode)
 	}
 }
+
+func TestV1Secret_VersionParamAndVersionsList(t *testing.T) {
+	srv, token := newTestServerWithService(t, docAllowDemoReadWrite(), newSQLiteService(t))
+	defer srv.Close()
+
+	for _, v := range []string{"v1", "v2", "v3"} {
+		body := bytes.NewBufferString(`{"key":"demo","value":"` + v + `"}`)
+		req, _ := http.NewRequest(http.MethodPut, srv.URL+"/v1/secret", body)
+		req.Header.Set("Authorization", "Bearer "+token)
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatalf("Do PUT: %v", err)
+		}
+		resp.Body.Close()
+		if resp.StatusCode != http.StatusOK {
+			t.Fatalf("expected %d, got %d", http.StatusOK, resp.StatusCode)
+		}
+	}
+
+	// Alte Version lesen
+	req, _ := http.NewRequest(http.MethodGet, srv.URL+"/v1/secret?key=demo&version=1", nil)
+	req.Header.Set("Authorization", "Bearer "+token)
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("Do GET: %v", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("expected %d, got %d", http.StatusOK, resp.StatusCode)
+	}
+	var out map[string]string
+	if err := json.NewDecoder(resp.Body).Decode(&out); err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	if out["value"] != "v1" {
+		t.Fatalf("expected value=v1, got %q", out["value"])
+	}
+
+	// Versionsliste, paginiert
+	req, _ = http.NewRequest(http.MethodGet, srv.URL+"/v1/secret/versions?key=demo&limit=2", nil)
+	req.Header.Set("Authorization", "Bearer "+token)
+	vresp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("Do GET versions: %v", err)
+	}
+	defer vresp.Body.Close()
+	if vresp.StatusCode != http.StatusOK {
+		t.Fatalf("expected %d, got %d", http.StatusOK, vresp.StatusCode)
+	}
+
+	var vout struct {
+		Key      string `json:"key"`
+		Versions []struct {
+			Version   int64  `json:"version"`
+			CreatedAt string `json:"created_at"`
+			CreatedBy string `json:"created_by"`
+		} `json:"versions"`
+		NextBefore int64 `json:"next_before"`
+	}
+	if err := json.NewDecoder(vresp.Body).Decode(&vout); err != nil {
+		t.Fatalf("decode versions: %v", err)
+	}
+	if len(vout.Versions) != 2 || vout.Versions[0].Version != 3 || vout.Versions[1].Version != 2 {
+		t.Fatalf("unexpected versions: %+v", vout.Versions)
+	}
+	if vout.NextBefore != 2 {
+		t.Fatalf("expected next_before=2, got %d", vout.NextBefore)
+	}
+}
+
+func TestV1SecretVersions_ForbiddenWhenPolicyDenies(t *testing.T) {
+	srv, token := newTestServer(t, docDenyDemo())
+	defer srv.Close()
+
+	req, _ := http.NewRequest(http.MethodGet, srv.URL+"/v1/secret/versions?key=demo", nil)
+	req.Header.Set("Authorization", "Bearer "+token)
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("Do: %v", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusForbidden {
+		t.Fatalf("expected %d, got %d", http.StatusForbidden, resp.StatusCode)
+	}
+}
+
+func doReq(t *testing.T, method, url, token string, body string) *http.Response {
+	t.Helper()
+	var rd *bytes.Buffer
+	if body != "" {
+		rd = bytes.NewBufferString(body)
+	} else {
+		rd = &bytes.Buffer{}
+	}
+	req, _ := http.NewRequest(method, url, rd)
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("Do %s %s: %v", method, url, err)
+	}
+	return resp
+}
+
+func TestV1SecretDelete_TombstoneAndUndelete(t *testing.T) {
+	seed := map[string]string{
+		"team-a/db":  "dbpass",
+		"team-a/api": "apipass",
+	}
+	srv, token := newTestServerWithService(t, docAllowTeamADeleteDBOnly(), service.NewMemorySecretService(seed))
+	defer srv.Close()
+
+	// write erlaubt, delete aber nicht -> 403
+	resp := doReq(t, http.MethodDelete, srv.URL+"/v1/secret?key=team-a/api", token, "")
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusForbidden {
+		t.Fatalf("expected %d, got %d", http.StatusForbidden, resp.StatusCode)
+	}
+
+	resp = doReq(t, http.MethodDelete, srv.URL+"/v1/secret?key=team-a/db", token, "")
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("expected %d, got %d", http.StatusOK, resp.StatusCode)
+	}
+
+	resp = doReq(t, http.MethodGet, srv.URL+"/v1/secret?key=team-a/db", token, "")
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusNotFound {
+		t.Fatalf("expected %d after delete, got %d", http.StatusNotFound, resp.StatusCode)
+	}
+
+	resp = doReq(t, http.MethodGet, srv.URL+"/v1/secrets?prefix=team-a/", token, "")
+	var list struct {
+		Data map[string]string `json:"data"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&list); err != nil {
+		t.Fatalf("decode list: %v", err)
+	}
+	resp.Body.Close()
+	if _, exists := list.Data["db"]; exists {
+		t.Fatalf("expected deleted key to be omitted from list")
+	}
+
+	resp = doReq(t, http.MethodPost, srv.URL+"/v1/secret/undelete?key=team-a/db", token, "")
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("expected %d on undelete, got %d", http.StatusOK, resp.StatusCode)
+	}
+
+	resp = doReq(t, http.MethodGet, srv.URL+"/v1/secret?key=team-a/db", token, "")
+	defer resp.Body.Close()
+	var out map[string]string
+	if err := json.NewDecoder(resp.Body).Decode(&out); err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	if out["value"] != "dbpass" {
+		t.Fatalf("expected restored value dbpass, got %q", out["value"])
+	}
+
+	resp = doReq(t, http.MethodPost, srv.URL+"/v1/secret/undelete?key=team-a/db", token, "")
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusConflict {
+		t.Fatalf("expected %d when undeleting live key, got %d", http.StatusConflict, resp.StatusCode)
+	}
+}
+
+func TestV1AdminDestroy_RequiresAdminAction(t *testing.T) {
+	base := service.NewMemorySecretService(map[string]string{"team-a/db": "dbpass"})
+	srv, token := newTestServerWithService(t, docAllowTeamADeleteDBOnly(), base)
+	defer srv.Close()
+
+	// delete/write reichen nicht für destroy
+	resp := doReq(t, http.MethodPost, srv.URL+"/v1/admin/destroy", token, `{"key":"team-a/db","all":true}`)
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusForbidden {
+		t.Fatalf("expected %d, got %d", http.StatusForbidden, resp.StatusCode)
+	}
+
+	doc := docAllowTeamADeleteDBOnly()
+	doc.Roles[0].Permissions = append(doc.Roles[0].Permissions, policy.Permission{Action: "admin", KeyPrefix: "team-a/"})
+	adminSrv, token := newTestServerWithService(t, doc, base)
+	defer adminSrv.Close()
+
+	resp = doReq(t, http.MethodPost, adminSrv.URL+"/v1/admin/destroy", token, `{"key":"team-a/db"}`)
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusBadRequest {
+		t.Fatalf("expected %d without versions/all, got %d", http.StatusBadRequest, resp.StatusCode)
+	}
+
+	resp = doReq(t, http.MethodPost, adminSrv.URL+"/v1/admin/destroy", token, `{"key":"team-a/db","all":true}`)
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("expected %d, got %d", http.StatusOK, resp.StatusCode)
+	}
+
+	resp = doReq(t, http.MethodGet, adminSrv.URL+"/v1/secret?key=team-a/db", token, "")
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusGone {
+		t.Fatalf("expected %d after destroy, got %d", http.StatusGone, resp.StatusCode)
+	}
+}
+
+func TestV1SecretPut_IfMatchAndConflict(t *testing.T) {
+	srv, token := newTestServerWithService(t, docAllowDemoReadWrite(), newSQLiteService(t))
+	defer srv.Close()
+
+	// If-None-Match: * => nur anlegen
+	req, _ := http.NewRequest(http.MethodPut, srv.URL+"/v1/secret", bytes.NewBufferString(`{"key":"demo","value":"a"}`))
+	req.Header.Set("Authorization", "Bearer "+token)
+	req.Header.Set("If-None-Match", "*")
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("Do PUT: %v", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("expected %d, got %d", http.StatusOK, resp.StatusCode)
+	}
+
+	// ETag aus GET für den nächsten PUT verwenden
+	resp = doReq(t, http.MethodGet, srv.URL+"/v1/secret?key=demo", token, "")
+	resp.Body.Close()
+	tag := resp.Header.Get("ETag")
+	if tag != `"1"` {
+		t.Fatalf("expected ETag \"1\", got %q", tag)
+	}
+
+	req, _ = http.NewRequest(http.MethodPut, srv.URL+"/v1/secret", bytes.NewBufferString(`{"key":"demo","value":"b"}`))
+	req.Header.Set("Authorization", "Bearer "+token)
+	req.Header.Set("If-Match", tag)
+	resp, err = http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("Do PUT: %v", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("expected %d, got %d", http.StatusOK, resp.StatusCode)
+	}
+	if resp.Header.Get("ETag") != `"2"` {
+		t.Fatalf("expected ETag \"2\" on PUT, got %q", resp.Header.Get("ETag"))
+	}
+
+	// Veraltete Version im Body => 409 mit aktueller Version
+	resp = doReq(t, http.MethodPut, srv.URL+"/v1/secret", token, `{"key":"demo","value":"c","expected_version":1}`)
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusConflict {
+		t.Fatalf("expected %d, got %d", http.StatusConflict, resp.StatusCode)
+	}
+	var conflict struct {
+		CurrentVersion  int64 `json:"current_version"`
+		ExpectedVersion int64 `json:"expected_version"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&conflict); err != nil {
+		t.Fatalf("decode conflict: %v", err)
+	}
+	if conflict.CurrentVersion != 2 || conflict.ExpectedVersion != 1 {
+		t.Fatalf("unexpected conflict body: %+v", conflict)
+	}
+
+	resp = doReq(t, http.MethodGet, srv.URL+"/v1/secret/meta?key=demo", token, "")
+	resp.Body.Close()
+	if resp.Header.Get("ETag") != `"2"` {
+		t.Fatalf("expected meta ETag \"2\", got %q", resp.Header.Get("ETag"))
+	}
+}
+
+func TestV1SecretPut_TTLShownInMeta(t *testing.T) {
+	srv, token := newTestServerWithService(t, docAllowDemoReadWrite(), newSQLiteService(t))
+	defer srv.Close()
+
+	resp := doReq(t, http.MethodPut, srv.URL+"/v1/secret", token, `{"key":"demo","value":"x","ttl":"nope"}`)
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusBadRequest {
+		t.Fatalf("expected %d for invalid ttl, got %d", http.StatusBadRequest, resp.StatusCode)
+	}
+
+	resp = doReq(t, http.MethodPut, srv.URL+"/v1/secret", token, `{"key":"demo","value":"x","ttl":"1h"}`)
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("expected %d, got %d", http.StatusOK, resp.StatusCode)
+	}
+
+	resp = doReq(t, http.MethodGet, srv.URL+"/v1/secret/meta?key=demo", token, "")
+	defer resp.Body.Close()
+	var out struct {
+		ExpiresAt string `json:"expires_at"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&out); err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	if out.ExpiresAt == "" {
+		t.Fatalf("expected expires_at in meta")
+	}
+}
+
+func TestV1SecretMeta_PatchLabelsAndLabelSelector(t *testing.T) {
+	seed := map[string]string{
+		"team-a/db":  "dbpass",
+		"team-a/api": "apipass",
+	}
+	srv, token := newTestServerWithService(t, docAllowTeamADeleteDBOnly(), service.NewMemorySecretService(seed))
+	defer srv.Close()
+
+	resp := doReq(t, http.MethodPatch, srv.URL+"/v1/secret/meta?key=team-a/db", token,
+		`{"labels":{"team":"payments","env":"prod"},"description":"primary db","owner":"alice"}`)
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("expected %d, got %d", http.StatusOK, resp.StatusCode)
+	}
+
+	resp = doReq(t, http.MethodPatch, srv.URL+"/v1/secret/meta?key=team-a/api", token, `{"labels":{"env":"not valid"}}`)
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusBadRequest {
+		t.Fatalf("expected %d for invalid label, got %d", http.StatusBadRequest, resp.StatusCode)
+	}
+
+	resp = doReq(t, http.MethodGet, srv.URL+"/v1/secret/meta?key=team-a/db", token, "")
+	var meta struct {
+		Labels      map[string]string `json:"labels"`
+		Description string            `json:"description"`
+		Owner       string            `json:"owner"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&meta); err != nil {
+		t.Fatalf("decode meta: %v", err)
+	}
+	resp.Body.Close()
+	if meta.Labels["team"] != "payments" || meta.Description != "primary db" || meta.Owner != "alice" {
+		t.Fatalf("unexpected meta: %+v", meta)
+	}
+
+	resp = doReq(t, http.MethodGet, srv.URL+"/v1/secrets?prefix=team-a/&withMeta=true&labelSelector=env+in+(prod,staging)", token, "")
+	var list struct {
+		Data map[string]struct {
+			Value  string            `json:"value"`
+			Labels map[string]string `json:"labels"`
+		} `json:"data"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&list); err != nil {
+		t.Fatalf("decode list: %v", err)
+	}
+	resp.Body.Close()
+	if len(list.Data) != 1 || list.Data["db"].Value != "dbpass" || list.Data["db"].Labels["env"] != "prod" {
+		t.Fatalf("expected only db with labels, got %+v", list.Data)
+	}
+
+	resp = doReq(t, http.MethodGet, srv.URL+"/v1/secrets?prefix=team-a/&labelSelector=env+in+(prod", token, "")
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusBadRequest {
+		t.Fatalf("expected %d for invalid selector, got %d", http.StatusBadRequest, resp.StatusCode)
+	}
+}
+
+func TestV1SecretMeta_PatchForbiddenWithoutWrite(t *testing.T) {
+	seed := map[string]string{"team-a/db": "dbpass"}
+	srv, token := newTestServerWithService(t, docAllowTeamAListReadDBOnly(), service.NewMemorySecretService(seed))
+	defer srv.Close()
+
+	resp := doReq(t, http.MethodPatch, srv.URL+"/v1/secret/meta?key=team-a/db", token, `{"labels":{"team":"payments"}}`)
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusForbidden {
+		t.Fatalf("expected %d, got %d", http.StatusForbidden, resp.StatusCode)
+	}
+}
+
+func TestV1SecretPut_BinaryValueBase64(t *testing.T) {
+	srv, token := newTestServerWithService(t, docAllowTeamADeleteDBOnly(), newSQLiteService(t))
+	defer srv.Close()
+
+	resp := doReq(t, http.MethodPut, srv.URL+"/v1/secret", token, `{"key":"team-a/ks","value_base64":"not base64!"}`)
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusBadRequest {
+		t.Fatalf("expected %d for invalid base64, got %d", http.StatusBadRequest, resp.StatusCode)
+	}
+
+	resp = doReq(t, http.MethodPut, srv.URL+"/v1/secret", token,
+		`{"key":"team-a/ks","value_base64":"AP/+EIA=","content_type":"application/x-pkcs12"}`)
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("expected %d, got %d", http.StatusOK, resp.StatusCode)
+	}
+	resp = doReq(t, http.MethodPut, srv.URL+"/v1/secret", token, `{"key":"team-a/user","value":"admin"}`)
+	resp.Body.Close()
+
+	// Binärwert kommt immer base64 zurück
+	resp = doReq(t, http.MethodGet, srv.URL+"/v1/secret?key=team-a/ks", token, "")
+	var got map[string]string
+	if err := json.NewDecoder(resp.Body).Decode(&got); err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	resp.Body.Close()
+	if got["value"] != "AP/+EIA=" || got["encoding"] != "base64" || got["content_type"] != "application/x-pkcs12" {
+		t.Fatalf("unexpected response: %v", got)
+	}
+
+	// Textwert nur mit encoding=base64 kodiert
+	resp = doReq(t, http.MethodGet, srv.URL+"/v1/secret?key=team-a/user&encoding=base64", token, "")
+	got = nil
+	if err := json.NewDecoder(resp.Body).Decode(&got); err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	resp.Body.Close()
+	if got["value"] != "YWRtaW4=" || got["encoding"] != "base64" {
+		t.Fatalf("unexpected response: %v", got)
+	}
+
+	resp = doReq(t, http.MethodGet, srv.URL+"/v1/secrets?prefix=team-a/", token, "")
+	var list struct {
+		Data map[string]string `json:"data"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&list); err != nil {
+		t.Fatalf("decode list: %v", err)
+	}
+	resp.Body.Close()
+	if list.Data["ks"] != "AP/+EIA=" || list.Data["user"] != "admin" {
+		t.Fatalf("unexpected list: %v", list.Data)
+	}
+
+	resp = doReq(t, http.MethodGet, srv.URL+"/v1/secret?key=team-a/user&encoding=hex", token, "")
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusBadRequest {
+		t.Fatalf("expected %d for unknown encoding, got %d", http.StatusBadRequest, resp.StatusCode)
+	}
+}
+
+func TestV1SecretsBatchPut_AtomicAndAuthorizedUpFront(t *testing.T) {
+	srv, token := newTestServerWithService(t, docAllowTeamADeleteDBOnly(), newSQLiteService(t))
+	defer srv.Close()
+
+	// team-b/ ist nicht schreibbar -> nichts wird geschrieben
+	resp := doReq(t, http.MethodPost, srv.URL+"/v1/secrets:batchPut", token,
+		`{"items":[{"key":"team-a/user","value":"app"},{"key":"team-b/user","value":"x"}]}`)
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusForbidden {
+		t.Fatalf("expected %d, got %d", http.StatusForbidden, resp.StatusCode)
+	}
+	resp = doReq(t, http.MethodGet, srv.URL+"/v1/secret?key=team-a/user", token, "")
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusNotFound {
+		t.Fatalf("expected team-a/user not written, got %d", resp.StatusCode)
+	}
+
+	resp = doReq(t, http.MethodPost, srv.URL+"/v1/secrets:batchPut", token,
+		`{"items":[{"key":"team-a/user","value":"app"},{"key":"team-a/password","value":"pw","expected_version":0}]}`)
+	if resp.StatusCode != http.StatusOK {
+		resp.Body.Close()
+		t.Fatalf("expected %d, got %d", http.StatusOK, resp.StatusCode)
+	}
+	var out struct {
+		Items []struct {
+			Key     string `json:"key"`
+			Version int64  `json:"version"`
+		} `json:"items"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&out); err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	resp.Body.Close()
+	if len(out.Items) != 2 || out.Items[0].Key != "team-a/user" || out.Items[1].Version != 1 {
+		t.Fatalf("unexpected response: %+v", out)
+	}
+
+	// Konflikt beim zweiten Key -> erster bleibt unverändert
+	resp = doReq(t, http.MethodPost, srv.URL+"/v1/secrets:batchPut", token,
+		`{"items":[{"key":"team-a/user","value":"app2"},{"key":"team-a/password","value":"pw2","expected_version":0}]}`)
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusConflict {
+		t.Fatalf("expected %d, got %d", http.StatusConflict, resp.StatusCode)
+	}
+	resp = doReq(t, http.MethodGet, srv.URL+"/v1/secret?key=team-a/user", token, "")
+	var got map[string]string
+	if err := json.NewDecoder(resp.Body).Decode(&got); err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	resp.Body.Close()
+	if got["value"] != "app" {
+		t.Fatalf("expected unchanged value app, got %q", got["value"])
+	}
+}
+
+func TestV1SecretsList_DecryptsEnvelopeEncryptedValues(t *testing.T) {
+	db := openTestDB(t)
+	base := service.NewSQLiteSecretService(db, newTestEnvelope(t, "default", 0x11))
+	srv, token := newTestServerWithService(t, docAllowTeamADeleteDBOnly(), base)
+	defer srv.Close()
+
+	resp := doReq(t, http.MethodPost, srv.URL+"/v1/secrets:batchPut", token,
+		`{"items":[{"key":"team-a/db","value":"dbpass"},{"key":"team-a/api","value":"apipass"}]}`)
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("expected %d, got %d", http.StatusOK, resp.StatusCode)
+	}
+
+	for _, format := range []string{"map", "list"} {
+		resp = doReq(t, http.MethodGet, srv.URL+"/v1/secrets?prefix=team-a/&withMeta=true&format="+format, token, "")
+		body, _ := io.ReadAll(resp.Body)
+		resp.Body.Close()
+		if resp.StatusCode != http.StatusOK {
+			t.Fatalf("%s: expected %d, got %d (%s)", format, http.StatusOK, resp.StatusCode, body)
+		}
+		if !strings.Contains(string(body), `"dbpass"`) || !strings.Contains(string(body), `"apipass"`) {
+			t.Fatalf("%s: expected plaintext values, got %s", format, body)
+		}
+	}
+}
+
+func TestV1SecretsList_FailsClearlyOnUnknownKEK(t *testing.T) {
+	db := openTestDB(t)
+	writer := service.NewSQLiteSecretService(db, newTestEnvelope(t, "old", 0x11))
+	if _, err := writer.PutSecret(t.Context(), "team-a/db", "dbpass"); err != nil {
+		t.Fatalf("PutSecret: %v", err)
+	}
+
+	// Server kennt nur einen anderen KEK
+	reader := service.NewSQLiteSecretService(db, newTestEnvelope(t, "new", 0x22))
+	srv, token := newTestServerWithService(t, docAllowTeamADeleteDBOnly(), reader)
+	defer srv.Close()
+
+	resp := doReq(t, http.MethodGet, srv.URL+"/v1/secrets?prefix=team-a/", token, "")
+	body, _ := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusInternalServerError {
+		t.Fatalf("expected %d, got %d", http.StatusInternalServerError, resp.StatusCode)
+	}
+	if !strings.Contains(string(body), "unknown kek_id") {
+		t.Fatalf("expected unknown kek_id error, got %q", body)
+	}
+	if strings.Contains(string(body), "dbpass") {
+		t.Fatalf("leaked value in error body")
+	}
+}
+
+// list auf team-a/, read nur auf wenige, verstreute Keys
+func docAllowTeamAListReadSparse(readable ...string) *policy.Document {
+	var s policy.Subject
+	s.Name = "eso"
+	s.Match.Kind = "bearer"
+	s.Match.Name = "webhook"
+
+	perms := []policy.Permission{{Action: "list", KeyPrefix: "team-a/"}}
+	for _, k := range readable {
+		perms = append(perms, policy.Permission{Action: "read", KeyExact: k})
+	}
+
+	return &policy.Document{
+		APIVersion: "glass.secretstore/v1alpha1",
+		Kind:       "Policy",
+		Subjects:   []policy.Subject{s},
+		Roles:      []policy.Role{{Name: "sparse", Permissions: perms}},
+		Bindings: []policy.Binding{
+			{Subject: "eso", Roles: []string{"sparse"}},
+		},
+	}
+}
+
+func TestV1SecretsList_CursorPaginationFillsPages(t *testing.T) {
+	seed := map[string]string{}
+	for _, k := range []string{"k01", "k02", "k03", "k04", "k05", "k06", "k07", "k08", "k09", "k10"} {
+		seed["team-a/"+k] = "v-" + k
+	}
+	doc := docAllowTeamAListReadSparse("team-a/k02", "team-a/k05", "team-a/k09")
+	srv, token := newTestServerWithService(t, doc, service.NewMemorySecretService(seed))
+	defer srv.Close()
+
+	type page struct {
+		Items []struct {
+			Key string `json:"key"`
+		} `json:"items"`
+		NextCursor string `json:"next_cursor"`
+	}
+	get := func(url string) page {
+		t.Helper()
+		resp := doReq(t, http.MethodGet, url, token, "")
+		defer resp.Body.Close()
+		if resp.StatusCode != http.StatusOK {
+			t.Fatalf("expected %d, got %d", http.StatusOK, resp.StatusCode)
+		}
+		var p page
+		if err := json.NewDecoder(resp.Body).Decode(&p); err != nil {
+			t.Fatalf("decode: %v", err)
+		}
+		return p
+	}
+
+	p1 := get(srv.URL + "/v1/secrets?prefix=team-a/&format=list&limit=2")
+	if len(p1.Items) != 2 || p1.Items[0].Key != "team-a/k02" || p1.Items[1].Key != "team-a/k05" || p1.NextCursor == "" {
+		t.Fatalf("unexpected first page: %+v", p1)
+	}
+
+	p2 := get(srv.URL + "/v1/secrets?prefix=team-a/&format=list&limit=2&cursor=" + p1.NextCursor)
+	if len(p2.Items) != 1 || p2.Items[0].Key != "team-a/k09" || p2.NextCursor != "" {
+		t.Fatalf("unexpected second page: %+v", p2)
+	}
+
+	for _, q := range []string{
+		"prefix=team-a/&limit=2",                                     // format=map
+		"prefix=team-a/&format=list&limit=0",                         // limit ungültig
+		"prefix=team-a/&format=list&limit=2&cursor=!!",               // kein base64url
+		"prefix=team-b/&format=list&limit=2&cursor=" + p1.NextCursor, // anderer prefix
+	} {
+		resp := doReq(t, http.MethodGet, srv.URL+"/v1/secrets?"+q, token, "")
+		resp.Body.Close()
+		if resp.StatusCode != http.StatusBadRequest {
+			t.Fatalf("%s: expected %d, got %d", q, http.StatusBadRequest, resp.StatusCode)
+		}
+	}
+}
+
+func TestV1Keys_DelimiterListingNeedsOnlyList(t *testing.T) {
+	seed := map[string]string{
+		"team-a/db/user":     "u",
+		"team-a/db/password": "p",
+		"team-a/api/token":   "t",
+		"team-a/top":         "x",
+		"team-b/other":       "y",
+	}
+	// ohne read-Keys: nur list auf team-a/
+	srv, token := newTestServerWithService(t, docAllowTeamAListReadSparse(), service.NewMemorySecretService(seed))
+	defer srv.Close()
+
+	// Werte dürfen nicht gelesen werden
+	resp := doReq(t, http.MethodGet, srv.URL+"/v1/secret?key=team-a/top", token, "")
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusForbidden {
+		t.Fatalf("expected %d for read, got %d", http.StatusForbidden, resp.StatusCode)
+	}
+
+	resp = doReq(t, http.MethodGet, srv.URL+"/v1/keys?prefix=team-a/&delimiter=/", token, "")
+	body, _ := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("expected %d, got %d", http.StatusOK, resp.StatusCode)
+	}
+	if strings.Contains(string(body), `"value"`) {
+		t.Fatalf("key listing must not contain values: %s", body)
+	}
+
+	var out struct {
+		Keys []struct {
+			Key     string `json:"key"`
+			Version int64  `json:"version"`
+		} `json:"keys"`
+		Prefixes []string `json:"prefixes"`
+	}
+	if err := json.Unmarshal(body, &out); err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	if len(out.Keys) != 1 || out.Keys[0].Key != "team-a/top" || out.Keys[0].Version != 1 {
+		t.Fatalf("unexpected keys: %+v", out.Keys)
+	}
+	if len(out.Prefixes) != 2 || out.Prefixes[0] != "team-a/api/" || out.Prefixes[1] != "team-a/db/" {
+		t.Fatalf("unexpected prefixes: %v", out.Prefixes)
+	}
+
+	// ohne Delimiter: alle Keys flach
+	resp = doReq(t, http.MethodGet, srv.URL+"/v1/keys?prefix=team-a/db/", token, "")
+	out.Keys, out.Prefixes = nil, nil
+	if err := json.NewDecoder(resp.Body).Decode(&out); err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	resp.Body.Close()
+	if len(out.Keys) != 2 || len(out.Prefixes) != 0 {
+		t.Fatalf("unexpected flat listing: %+v", out)
+	}
+
+	resp = doReq(t, http.MethodGet, srv.URL+"/v1/keys?prefix=team-b/&delimiter=/", token, "")
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusForbidden {
+		t.Fatalf("expected %d for team-b/, got %d", http.StatusForbidden, resp.StatusCode)
+	}
+}
+
+func TestV1SecretGenerate_PasswordAndReturnFalse(t *testing.T) {
+	srv, token := newTestServerWithService(t, docAllowTeamADeleteDBOnly(), newSQLiteService(t))
+	defer srv.Close()
+
+	resp := doReq(t, http.MethodPost, srv.URL+"/v1/secret/generate", token,
+		`{"key":"team-a/db","generator":{"type":"password","length":20,"symbols":true,"min_symbols":2}}`)
+	if resp.StatusCode != http.StatusOK {
+		resp.Body.Close()
+		t.Fatalf("expected %d, got %d", http.StatusOK, resp.StatusCode)
+	}
+	var gen struct {
+		Version int64  `json:"version"`
+		Value   string `json:"value"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&gen); err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	resp.Body.Close()
+	if gen.Version != 1 || len(gen.Value) != 20 {
+		t.Fatalf("unexpected response: %+v", gen)
+	}
+
+	resp = doReq(t, http.MethodGet, srv.URL+"/v1/secret?key=team-a/db", token, "")
+	var got map[string]string
+	if err := json.NewDecoder(resp.Body).Decode(&got); err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	resp.Body.Close()
+	if got["value"] != gen.Value {
+		t.Fatalf("expected stored value to equal generated value")
+	}
+
+	// return=false: Klartext verlässt den Server nicht, public key schon
+	resp = doReq(t, http.MethodPost, srv.URL+"/v1/secret/generate?return=false", token,
+		`{"key":"team-a/signing","generator":{"type":"ed25519"}}`)
+	body, _ := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("expected %d, got %d", http.StatusOK, resp.StatusCode)
+	}
+	if strings.Contains(string(body), `"value"`) || strings.Contains(string(body), "PRIVATE KEY") {
+		t.Fatalf("expected no private value in response, got %s", body)
+	}
+	if !strings.Contains(string(body), "PUBLIC KEY") {
+		t.Fatalf("expected public key in response, got %s", body)
+	}
+
+	resp = doReq(t, http.MethodPost, srv.URL+"/v1/secret/generate", token, `{"key":"team-a/x","generator":{"type":"rsa","bits":512}}`)
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusBadRequest {
+		t.Fatalf("expected %d for invalid spec, got %d", http.StatusBadRequest, resp.StatusCode)
+	}
+
+	resp = doReq(t, http.MethodPost, srv.URL+"/v1/secret/generate", token, `{"key":"team-b/x","generator":{"type":"uuid"}}`)
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusForbidden {
+		t.Fatalf("expected %d without write, got %d", http.StatusForbidden, resp.StatusCode)
+	}
+}
+
+func TestV1SecretMeta_ShowsNextRotation(t *testing.T) {
+	tokPath := writeTempTokenFile(t, "secret-token\n")
+	bearer, err := authn.NewBearerFromFile(tokPath)
+	if err != nil {
+		t.Fatalf("NewBearerFromFile: %v", err)
+	}
+	az := authz.NewRuntimeAuthorizer(staticPolicySource{doc: docAllowDemo()})
+	rules := &rotation.Rules{Rules: []rotation.Rule{{
+		KeyPrefix: "demo",
+		Interval:  24 * time.Hour,
+		Generator: generator.Spec{Type: "uuid"},
+	}}}
+
+	h := httpapi.NewRouter(httpapi.Deps{
+		SecretService: service.NewSecuredSecretService(service.NewMemorySecretService(map[string]string{"demo": "hello"}), az),
+		Authenticator: bearer,
+		Rotation:      rules,
+	})
+	srv := httptest.NewServer(h)
+	defer srv.Close()
+
+	resp := doReq(t, http.MethodGet, srv.URL+"/v1/secret/meta?key=demo", "secret-token", "")
+	defer resp.Body.Close()
+	var out struct {
+		CreatedAt      string `json:"created_at"`
+		NextRotationAt string `json:"next_rotation_at"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&out); err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	created, err := time.Parse(time.RFC3339Nano, out.CreatedAt)
+	if err != nil {
+		t.Fatalf("parse created_at: %v", err)
+	}
+	next, err := time.Parse(time.RFC3339, out.NextRotationAt)
+	if err != nil {
+		t.Fatalf("parse next_rotation_at %q: %v", out.NextRotationAt, err)
+	}
+	if d := next.Sub(created); d < 24*time.Hour-time.Second || d > 24*time.Hour {
+		t.Fatalf("expected next rotation 24h after created_at, got %v", d)
+	}
+}
+
+func TestV1Secret_ResolveReferencesChecksReadPerReference(t *testing.T) {
+	seed := map[string]string{
+		"team-a/db/password": "s3cret",
+		"team-a/db/url":      "jdbc:postgresql://db/app?password=${secret:team-a/db/password}",
+		"team-a/other":       "hidden",
+		"team-a/leaky":       "x=${secret:team-a/other}",
+	}
+	doc := docAllowTeamAListReadSparse("team-a/db/password", "team-a/db/url", "team-a/leaky")
+	srv, token := newTestServerWithService(t, doc, service.NewMemorySecretService(seed))
+	defer srv.Close()
+
+	// ohne resolve bleibt die Referenz unverändert
+	resp := doReq(t, http.MethodGet, srv.URL+"/v1/secret?key=team-a/db/url", token, "")
+	var raw struct {
+		Value string `json:"value"`
+	}
+	_ = json.NewDecoder(resp.Body).Decode(&raw)
+	resp.Body.Close()
+	if raw.Value != seed["team-a/db/url"] {
+		t.Fatalf("expected unresolved value, got %q", raw.Value)
+	}
+
+	resp = doReq(t, http.MethodGet, srv.URL+"/v1/secret?key=team-a/db/url&resolve=true", token, "")
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("expected 200, got %d", resp.StatusCode)
+	}
+	if resp.Header.Get("ETag") != "" {
+		t.Fatalf("expected no ETag for resolved value")
+	}
+	var out struct {
+		Value    string `json:"value"`
+		Resolved []struct {
+			Key     string `json:"key"`
+			Version int64  `json:"version"`
+		} `json:"resolved"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&out); err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	resp.Body.Close()
+	if out.Value != "jdbc:postgresql://db/app?password=s3cret" {
+		t.Fatalf("unexpected resolved value %q", out.Value)
+	}
+	if len(out.Resolved) != 1 || out.Resolved[0].Key != "team-a/db/password" || out.Resolved[0].Version != 1 {
+		t.Fatalf("unexpected resolved refs: %+v", out.Resolved)
+	}
+
+	// Referenz auf einen nicht lesbaren Key -> 403
+	resp = doReq(t, http.MethodGet, srv.URL+"/v1/secret?key=team-a/leaky&resolve=true", token, "")
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusForbidden {
+		t.Fatalf("expected 403 for unreadable reference, got %d", resp.StatusCode)
+	}
+
+	resp = doReq(t, http.MethodGet, srv.URL+"/v1/secret?key=team-a/db/url&resolve=maybe", token, "")
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusBadRequest {
+		t.Fatalf("expected 400 for invalid resolve, got %d", resp.StatusCode)
+	}
+}
+
+func TestV1Changes_FilteredByReadAndPaged(t *testing.T) {
+	seed := map[string]string{}
+	base := service.NewMemorySecretService(seed)
+	doc := docAllowTeamAListReadSparse("team-a/k1", "team-a/k3")
+	srv, token := newTestServerWithService(t, doc, base)
+	defer srv.Close()
+
+	type feedResp struct {
+		Changes []struct {
+			Revision int64  `json:"revision"`
+			Key      string `json:"key"`
+			Type     string `json:"type"`
+			Version  int64  `json:"version"`
+		} `json:"changes"`
+		Revision int64 `json:"revision"`
+		More     bool  `json:"more"`
+	}
+	get := func(query string) feedResp {
+		t.Helper()
+		resp := doReq(t, http.MethodGet, srv.URL+"/v1/changes?"+query, token, "")
+		defer resp.Body.Close()
+		if resp.StatusCode != http.StatusOK {
+			t.Fatalf("GET /v1/changes?%s: expected 200, got %d", query, resp.StatusCode)
+		}
+		var out feedResp
+		if err := json.NewDecoder(resp.Body).Decode(&out); err != nil {
+			t.Fatalf("decode: %v", err)
+		}
+		return out
+	}
+
+	// Startpunkt ohne since
+	start := get("prefix=team-a/")
+	if start.Revision != 0 || len(start.Changes) != 0 {
+		t.Fatalf("unexpected start: %+v", start)
+	}
+
+	ctx := context.Background()
+	for _, k := range []string{"team-a/k1", "team-a/k2", "team-a/k3", "team-a/k1"} {
+		if _, err := base.PutSecret(ctx, k, "v"); err != nil {
+			t.Fatalf("PutSecret: %v", err)
+		}
+	}
+
+	// k2 ist nicht lesbar -> gefiltert, Seiten trotzdem voll
+	p1 := get("prefix=team-a/&since=0&limit=2")
+	if len(p1.Changes) != 2 || p1.Changes[0].Key != "team-a/k1" || p1.Changes[1].Key != "team-a/k3" || !p1.More {
+		t.Fatalf("unexpected first page: %+v", p1)
+	}
+	p2 := get("prefix=team-a/&since=" + strconv.FormatInt(p1.Revision, 10) + "&limit=2")
+	if len(p2.Changes) != 1 || p2.Changes[0].Key != "team-a/k1" || p2.Changes[0].Version != 2 || p2.More || p2.Revision != 4 {
+		t.Fatalf("unexpected second page: %+v", p2)
+	}
+
+	resp := doReq(t, http.MethodGet, srv.URL+"/v1/changes?prefix=team-b/&since=0", token, "")
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusForbidden {
+		t.Fatalf("expected 403 without list permission, got %d", resp.StatusCode)
+	}
+
+	resp = doReq(t, http.MethodGet, srv.URL+"/v1/changes?prefix=team-a/&since=-3", token, "")
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusBadRequest {
+		t.Fatalf("expected 400 for negative since, got %d", resp.StatusCode)
+	}
+}
+
+type sseEvent struct {
+	ID, Event, Data string
+	Comment         bool
+}
+
+// readSSE liest Events (und Kommentare wie Heartbeats) in einen Channel
+func readSSE(body io.Reader) <-chan sseEvent {
+	ch := make(chan sseEvent, 32)
+	go func() {
+		defer close(ch)
+		sc := bufio.NewScanner(body)
+		var ev sseEvent
+		for sc.Scan() {
+			line := sc.Text()
+			switch {
+			case line == "":
+				if ev != (sseEvent{}) {
+					ch <- ev
+				}
+				ev = sseEvent{}
+			case strings.HasPrefix(line, ":"):
+				ch <- sseEvent{Comment: true, Data: strings.TrimSpace(line[1:])}
+			case strings.HasPrefix(line, "id: "):
+				ev.ID = line[4:]
+			case strings.HasPrefix(line, "event: "):
+				ev.Event = line[7:]
+			case strings.HasPrefix(line, "data: "):
+				ev.Data = line[6:]
+			}
+		}
+	}()
+	return ch
+}
+
+func TestV1Watch_StreamsReadableChangesPastWriteTimeout(t *testing.T) {
+	tokPath := writeTempTokenFile(t, "secret-token\n")
+	bearer, err := authn.NewBearerFromFile(tokPath)
+	if err != nil {
+		t.Fatalf("NewBearerFromFile: %v", err)
+	}
+	base := service.NewMemorySecretService(nil)
+	az := authz.NewRuntimeAuthorizer(staticPolicySource{doc: docAllowTeamAListReadSparse("team-a/k1", "team-a/k3")})
+	h := httpapi.NewRouter(httpapi.Deps{
+		SecretService: service.NewSecuredSecretService(base, az),
+		Authenticator: bearer,
+		Watch:         handlers.WatchOptions{PollInterval: 10 * time.Millisecond, Heartbeat: 50 * time.Millisecond},
+	})
+	srv := httptest.NewUnstartedServer(h)
+	srv.Config.WriteTimeout = 200 * time.Millisecond
+	srv.Start()
+	defer srv.Close()
+
+	ctx := context.Background()
+	if _, err := base.PutSecret(ctx, "team-a/k1", "old"); err != nil {
+		t.Fatalf("PutSecret: %v", err)
+	}
+
+	// Fortsetzen nach Revision 1: das erste Event wird nicht erneut geliefert
+	req, _ := http.NewRequest(http.MethodGet, srv.URL+"/v1/watch?prefix=team-a/", nil)
+	req.Header.Set("Authorization", "Bearer secret-token")
+	req.Header.Set("Last-Event-ID", "1")
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("GET /v1/watch: %v", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK || resp.Header.Get("Content-Type") != "text/event-stream" {
+		t.Fatalf("expected 200 event-stream, got %d %q", resp.StatusCode, resp.Header.Get("Content-Type"))
+	}
+	events := readSSE(resp.Body)
+
+	// länger als WriteTimeout warten, dann schreiben
+	time.Sleep(300 * time.Millisecond)
+	for _, k := range []string{"team-a/k2", "team-a/k3", "team-a/k1"} {
+		if _, err := base.PutSecret(ctx, k, "new"); err != nil {
+			t.Fatalf("PutSecret: %v", err)
+		}
+	}
+
+	var got []sseEvent
+	heartbeats := 0
+	timeout := time.After(3 * time.Second)
+	for len(got) < 2 {
+		select {
+		case ev, ok := <-events:
+			if !ok {
+				t.Fatalf("stream closed early after %+v", got)
+			}
+			if ev.Comment {
+				heartbeats++
+				continue
+			}
+			got = append(got, ev)
+		case <-timeout:
+			t.Fatalf("timeout waiting for events, got %+v", got)
+		}
+	}
+	if heartbeats == 0 {
+		t.Fatalf("expected heartbeats while idle")
+	}
+
+	// k2 ist nicht lesbar -> kein Event
+	if got[0].ID != "3" || got[0].Event != "put" || !strings.Contains(got[0].Data, `"key":"team-a/k3"`) {
+		t.Fatalf("unexpected first event %+v", got[0])
+	}
+	var ch struct {
+		Key     string `json:"key"`
+		Version int64  `json:"version"`
+	}
+	if err := json.Unmarshal([]byte(got[1].Data), &ch); err != nil || ch.Key != "team-a/k1" || ch.Version != 2 || got[1].ID != "4" {
+		t.Fatalf("unexpected second event %+v", got[1])
+	}
+}
+
+func TestV1Watch_ForbiddenWithoutList(t *testing.T) {
+	srv, token := newTestServerWithService(t, docAllowTeamAReadNoList(), service.NewMemorySecretService(nil))
+	defer srv.Close()
+
+	resp := doReq(t, http.MethodGet, srv.URL+"/v1/watch?prefix=team-a/", token, "")
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusForbidden {
+		t.Fatalf("expected 403, got %d", resp.StatusCode)
+	}
+}
+
+func TestV1AdminWebhooks_RequireAdminOnPrefix(t *testing.T) {
+	db := openTestDB(t)
+	tokPath := writeTempTokenFile(t, "secret-token\n")
+	bearer, err := authn.NewBearerFromFile(tokPath)
+	if err != nil {
+		t.Fatalf("NewBearerFromFile: %v", err)
+	}
+	doc := docAllowTeamADeleteDBOnly()
+	doc.Roles[0].Permissions = append(doc.Roles[0].Permissions, policy.Permission{Action: "admin", KeyPrefix: "team-a/"})
+	az := authz.NewRuntimeAuthorizer(staticPolicySource{doc: doc})
+
+	fileSub := webhook.Subscription{ID: "ops", URL: "https://ops.example.com", KeyPrefix: "team-b/", Secret: "0123456789abcdef", Source: "file"}
+	h := httpapi.NewRouter(httpapi.Deps{
+		SecretService: service.NewSecuredSecretService(service.NewSQLiteSecretService(db, nil), az),
+		Authenticator: bearer,
+		Webhooks:      webhook.NewSecuredRegistry(webhook.NewStore(db, []webhook.Subscription{fileSub}, nil), az),
+	})
+	srv := httptest.NewServer(h)
+	defer srv.Close()
+
+	resp := doReq(t, http.MethodPost, srv.URL+"/v1/admin/webhooks", "secret-token", `{"url":"https://ci.example.com/hook","key_prefix":"team-b/"}`)
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusForbidden {
+		t.Fatalf("expected 403 for team-b/, got %d", resp.StatusCode)
+	}
+
+	resp = doReq(t, http.MethodPost, srv.URL+"/v1/admin/webhooks", "secret-token", `{"url":"https://ci.example.com/hook","key_prefix":"team-a/","events":["put"]}`)
+	if resp.StatusCode != http.StatusCreated {
+		t.Fatalf("expected 201, got %d", resp.StatusCode)
+	}
+	var created struct {
+		ID     string `json:"id"`
+		Secret string `json:"secret"`
+	}
+	_ = json.NewDecoder(resp.Body).Decode(&created)
+	resp.Body.Close()
+	if created.ID == "" || len(created.Secret) < 16 {
+		t.Fatalf("expected generated id and secret, got %+v", created)
+	}
+
+	resp = doReq(t, http.MethodPost, srv.URL+"/v1/admin/webhooks", "secret-token", `{"url":"not a url","key_prefix":"team-a/"}`)
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusBadRequest {
+		t.Fatalf("expected 400 for invalid url, got %d", resp.StatusCode)
+	}
+
+	// Liste: nur team-a/ sichtbar, ohne Secret
+	resp = doReq(t, http.MethodGet, srv.URL+"/v1/admin/webhooks", "secret-token", "")
+	body, _ := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if strings.Contains(string(body), created.Secret) || strings.Contains(string(body), `"ops"`) || !strings.Contains(string(body), created.ID) {
+		t.Fatalf("unexpected list: %s", body)
+	}
+
+	resp = doReq(t, http.MethodDelete, srv.URL+"/v1/admin/webhooks?id="+created.ID, "secret-token", "")
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusNoContent {
+		t.Fatalf("expected 204, got %d", resp.StatusCode)
+	}
+	resp = doReq(t, http.MethodDelete, srv.URL+"/v1/admin/webhooks?id="+created.ID, "secret-token", "")
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusNotFound {
+		t.Fatalf("expected 404 after delete, got %d", resp.StatusCode)
+	}
+}
+
+func TestV1Audit_RecordsDecisionsAndStatus(t *testing.T) {
+	db := openTestDB(t)
+	tokPath := writeTempTokenFile(t, "secret-token\n")
+	bearer, err := authn.NewBearerFromFile(tokPath)
+	if err != nil {
+		t.Fatalf("NewBearerFromFile: %v", err)
+	}
+	az := authz.NewRuntimeAuthorizer(staticPolicySource{doc: docAllowTeamAListReadDBOnly()})
+	base := service.NewSQLiteSecretService(db, nil)
+	if _, err := base.PutSecret(context.Background(), "team-a/db", "pw"); err != nil {
+		t.Fatalf("PutSecret: %v", err)
+	}
+
+	h := httpapi.NewRouter(httpapi.Deps{
+		SecretService: service.NewSecuredSecretService(base, az),
+		Authenticator: bearer,
+		Audit:         audit.NewSQLiteLog(db),
+	})
+	srv := httptest.NewServer(h)
+	defer srv.Close()
+
+	req, _ := http.NewRequest(http.MethodGet, srv.URL+"/v1/secret?key=team-a/db", nil)
+	req.Header.Set("Authorization", "Bearer secret-token")
+	req.Header.Set("X-Request-ID", "req-123")
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("GET: %v", err)
+	}
+	resp.Body.Close()
+	if resp.Header.Get("X-Request-ID") != "req-123" {
+		t.Fatalf("expected request id echoed")
+	}
+
+	resp = doReq(t, http.MethodGet, srv.URL+"/v1/secret?key=team-a/other", "secret-token", "")
+	resp.Body.Close()
+	resp = doReq(t, http.MethodPut, srv.URL+"/v1/secret", "secret-token", `{"key":"team-a/db","value":"x"}`)
+	resp.Body.Close()
+	// nicht authentifiziert -> kein Eintrag
+	resp = doReq(t, http.MethodGet, srv.URL+"/v1/secret?key=team-a/db", "wrong", "")
+	resp.Body.Close()
+
+	rows, err := db.Query(`SELECT request_id, subject, action, key, decision, status, source_ip FROM audit_log ORDER BY seq`)
+	if err != nil {
+		t.Fatalf("query: %v", err)
+	}
+	defer rows.Close()
+	type row struct {
+		reqID, subject, action, key, decision string
+		status                                int
+		ip                                    string
+	}
+	var got []row
+	for rows.Next() {
+		var r row
+		if err := rows.Scan(&r.reqID, &r.subject, &r.action, &r.key, &r.decision, &r.status, &r.ip); err != nil {
+			t.Fatalf("scan: %v", err)
+		}
+		got = append(got, r)
+	}
+	if len(got) != 3 {
+		t.Fatalf("expected 3 audit entries, got %+v", got)
+	}
+	if got[0].reqID != "req-123" || got[0].subject != "bearer:webhook" || got[0].action != "read" || got[0].decision != "allow" || got[0].status != 200 || got[0].ip != "127.0.0.1" {
+		t.Fatalf("unexpected first entry %+v", got[0])
+	}
+	if got[1].key != "team-a/other" || got[1].decision != "deny" || got[1].status != http.StatusForbidden {
+		t.Fatalf("unexpected second entry %+v", got[1])
+	}
+	if got[2].action != "write" || got[2].decision != "deny" {
+		t.Fatalf("unexpected third entry %+v", got[2])
+	}
+
+	if _, err := audit.Verify(context.Background(), db); err != nil {
+		t.Fatalf("Verify: %v", err)
+	}
+}
+
+// failingSinkWriter simuliert ein nicht erreichbares Audit-Ziel
+type failingSinkWriter struct{}
+
+func (failingSinkWriter) WriteEntry(audit.Entry) error { return errors.New("connection refused") }
+func (failingSinkWriter) Close() error                 { return nil }
+
+func TestV1Audit_FailClosedSinkRejectsRequests(t *testing.T) {
+	db := openTestDB(t)
+	tokPath := writeTempTokenFile(t, "secret-token\n")
+	bearer, err := authn.NewBearerFromFile(tokPath)
+	if err != nil {
+		t.Fatalf("NewBearerFromFile: %v", err)
+	}
+	az := authz.NewRuntimeAuthorizer(staticPolicySource{doc: docAllowTeamAListReadDBOnly()})
+	base := service.NewSQLiteSecretService(db, nil)
+	if _, err := base.PutSecret(context.Background(), "team-a/db", "pw"); err != nil {
+		t.Fatalf("PutSecret: %v", err)
+	}
+
+	sink := audit.NewBufferedSink("siem", failingSinkWriter{}, audit.FailClosed, 8)
+	m := audit.NewMulti(audit.NewSQLiteLog(db), []*audit.BufferedSink{sink})
+	defer m.Close()
+
+	h := httpapi.NewRouter(httpapi.Deps{
+		SecretService: service.NewSecuredSecretService(base, az),
+		Authenticator: bearer,
+		Audit:         m,
+	})
+	srv := httptest.NewServer(h)
+	defer srv.Close()
+
+	// erster Request geht durch, der Sink scheitert erst beim Schreiben
+	resp := doReq(t, http.MethodGet, srv.URL+"/v1/secret?key=team-a/db", "secret-token", "")
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("expected 200, got %d", resp.StatusCode)
+	}
+
+	deadline := time.Now().Add(2 * time.Second)
+	for m.Ready() == nil {
+		if time.Now().After(deadline) {
+			t.Fatalf("sink never reported unavailable")
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+
+	resp = doReq(t, http.MethodGet, srv.URL+"/v1/secret?key=team-a/db", "secret-token", "")
+	body, _ := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusServiceUnavailable || strings.Contains(string(body), "pw") {
+		t.Fatalf("expected 503 without value, got %d %q", resp.StatusCode, body)
+	}
+
+	// abgewiesener Request steht trotzdem im SQLite-Log
+	var status int
+	if err := db.QueryRow(`SELECT status FROM audit_log ORDER BY seq DESC LIMIT 1`).Scan(&status); err != nil {
+		t.Fatalf("query: %v", err)
+	}
+	if status != http.StatusServiceUnavailable {
+		t.Fatalf("expected 503 entry in audit_log, got %d", status)
+	}
+}
+
+func TestMetrics_CountsRoutesAuthnAndAuthz(t *testing.T) {
+	tokPath := writeTempTokenFile(t, "secret-token\n")
+	bearer, err := authn.NewBearerFromFile(tokPath)
+	if err != nil {
+		t.Fatalf("NewBearerFromFile: %v", err)
+	}
+	az := authz.NewRuntimeAuthorizer(staticPolicySource{doc: docAllowTeamAListReadDBOnly()})
+	base := service.NewMemorySecretService(map[string]string{"team-a/db": "pw"})
+
+	h := httpapi.NewRouter(httpapi.Deps{
+		SecretService: service.NewSecuredSecretService(base, az),
+		Authenticator: bearer,
+		Metrics:       metrics.Handler(),
+	})
+	srv := httptest.NewServer(h)
+	defer srv.Close()
+
+	resp := doReq(t, http.MethodGet, srv.URL+"/v1/secret?key=team-a/db", "secret-token", "")
+	resp.Body.Close()
+	resp = doReq(t, http.MethodGet, srv.URL+"/v1/secret?key=team-a/other", "secret-token", "")
+	resp.Body.Close()
+	resp = doReq(t, http.MethodGet, srv.URL+"/v1/secret?key=team-a/db", "wrong", "")
+	resp.Body.Close()
+
+	// /metrics liegt außerhalb von /v1 und braucht keinen Token
+	resp, err = http.Get(srv.URL + "/metrics")
+	if err != nil {
+		t.Fatalf("GET /metrics: %v", err)
+	}
+	body, _ := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("expected 200, got %d", resp.StatusCode)
+	}
+
+	for _, want := range []string{
+		`glass_http_requests_total{method="GET",route="/v1/secret",status="200"}`,
+		`glass_http_requests_total{method="GET",route="/v1/secret",status="403"}`,
+		`glass_http_request_duration_seconds_bucket{method="GET",route="/v1/secret",status="200",le="0.005"}`,
+		`glass_authn_failures_total`,
+		`glass_authz_decisions_total{action="read",decision="allow",reason="role=`,
+		`glass_authz_decisions_total{action="read",decision="deny",reason="no matching permission"}`,
+		`go_goroutines`,
+	} {
+		if !strings.Contains(string(body), want) {
+			t.Fatalf("metrics missing %s", want)
+		}
+	}
+	// Query-Parameter dürfen nicht in den Labels landen
+	if strings.Contains(string(body), "team-a/db") {
+		t.Fatalf("metrics must not contain keys")
+	}
+}
+
+func TestTracing_PropagatesContextAndHidesKeys(t *testing.T) {
+	recorder := tracetest.NewSpanRecorder()
+	tp := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder))
+	prevTP, prevProp := otel.GetTracerProvider(), otel.GetTextMapPropagator()
+	otel.SetTracerProvider(tp)
+	otel.SetTextMapPropagator(propagation.TraceContext{})
+	defer func() {
+		otel.SetTracerProvider(prevTP)
+		otel.SetTextMapPropagator(prevProp)
+	}()
+
+	// DB erst nach dem Provider öffnen, damit otelsql ihn verwendet
+	db := openTestDB(t)
+	tokPath := writeTempTokenFile(t, "secret-token\n")
+	bearer, err := authn.NewBearerFromFile(tokPath)
+	if err != nil {
+		t.Fatalf("NewBearerFromFile: %v", err)
+	}
+	az := authz.NewRuntimeAuthorizer(staticPolicySource{doc: docAllowTeamAListReadDBOnly()})
+	base := service.NewSQLiteSecretService(db, newTestEnvelope(t, "k1", 1))
+	for _, k := range []string{"team-a/db", "team-a/other"} {
+		if _, err := base.PutSecret(context.Background(), k, "pw-123"); err != nil {
+			t.Fatalf("PutSecret: %v", err)
+		}
+	}
+
+	h := httpapi.NewRouter(httpapi.Deps{
+		SecretService: service.NewSecuredSecretService(base, az),
+		Authenticator: bearer,
+	})
+	srv := httptest.NewServer(h)
+	defer srv.Close()
+
+	const traceID = "4bf92f3577b34da6a3ce929d0e0e4736"
+	req, _ := http.NewRequest(http.MethodGet, srv.URL+"/v1/secrets?prefix=team-a/", nil)
+	req.Header.Set("Authorization", "Bearer secret-token")
+	req.Header.Set("traceparent", "00-"+traceID+"-00f067aa0ba902b7-01")
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("GET: %v", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("expected 200, got %d", resp.StatusCode)
+	}
+
+	names := map[string]bool{}
+	for _, s := range recorder.Ended() {
+		if s.SpanContext().TraceID().String() != traceID {
+			continue
+		}
+		name := s.Name()
+		if strings.HasPrefix(name, "sql.") {
+			name = "sql"
+		}
+		names[name] = true
+		if s.Name() == "GET /v1/secrets" && s.Parent().SpanID().String() != "00f067aa0ba902b7" {
+			t.Fatalf("server span must continue the caller's span, parent=%s", s.Parent().SpanID())
+		}
+		for _, a := range s.Attributes() {
+			if v := a.Value.Emit(); strings.Contains(v, "team-a") || strings.Contains(v, "pw-123") {
+				t.Fatalf("span %s leaks key or value in %s=%q", s.Name(), a.Key, v)
+			}
+		}
+	}
+	for _, want := range []string{"GET /v1/secrets", "authn.Authenticate", "authz.Evaluate", "authz.Filter", "envelope.Decrypt", "sql"} {
+		if !names[want] {
+			t.Fatalf("missing span %q in trace, got %v", want, names)
+		}
+	}
+
+	// explizit eingeschaltet: Keys (nie Werte) als glass.key
+	tracing.SetIncludeKeys(true)
+	defer tracing.SetIncludeKeys(false)
+	resp = doReq(t, http.MethodGet, srv.URL+"/v1/secret?key=team-a/db", "secret-token", "")
+	resp.Body.Close()
+	found := false
+	for _, s := range recorder.Ended() {
+		for _, a := range s.Attributes() {
+			if a.Key == "glass.key" && a.Value.AsString() == "team-a/db" {
+				found = true
+			}
+			if strings.Contains(a.Value.Emit(), "pw-123") {
+				t.Fatalf("span %s leaks value", s.Name())
+			}
+		}
+	}
+	if !found {
+		t.Fatalf("expected glass.key attribute with TRACING_INCLUDE_KEYS")
+	}
+}
+
+// failingMetaService lässt GetSecretMeta mit einem internen Fehler scheitern
+type failingMetaService struct{ service.SecretService }
+
+func (failingMetaService) GetSecretMeta(context.Context, string) (service.SecretMeta, error) {
+	return service.SecretMeta{}, errors.New("disk I/O error")
+}
+
+func TestAccessLog_RequestIDInLogsAndErrors(t *testing.T) {
+	var buf bytes.Buffer
+	lh, err := logging.NewHandler("info", "json", &buf)
+	if err != nil {
+		t.Fatalf("NewHandler: %v", err)
+	}
+	prev := slog.Default()
+	slog.SetDefault(slog.New(lh))
+	defer slog.SetDefault(prev)
+
+	tokPath := writeTempTokenFile(t, "secret-token\n")
+	bearer, err := authn.NewBearerFromFile(tokPath)
+	if err != nil {
+		t.Fatalf("NewBearerFromFile: %v", err)
+	}
+	mem := service.NewMemorySecretService(map[string]string{"team-a/db": "pw-123"})
+	h := httpapi.NewRouter(httpapi.Deps{
+		SecretService: failingMetaService{mem},
+		Authenticator: bearer,
+	})
+	srv := httptest.NewServer(h)
+	defer srv.Close()
+
+	req, _ := http.NewRequest(http.MethodGet, srv.URL+"/v1/secret?key=team-a/db", nil)
+	req.Header.Set("Authorization", "Bearer secret-token")
+	req.Header.Set("X-Request-ID", "req-log-1")
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("GET: %v", err)
+	}
+	resp.Body.Close()
+	if resp.Header.Get("X-Request-ID") != "req-log-1" {
+		t.Fatalf("expected request id echoed, got %q", resp.Header.Get("X-Request-ID"))
+	}
+
+	// ungültige ID (Leerzeichen) wird ersetzt statt in Logs übernommen
+	req, _ = http.NewRequest(http.MethodGet, srv.URL+"/v1/secret/meta?key=team-a/db", nil)
+	req.Header.Set("Authorization", "Bearer secret-token")
+	req.Header.Set("X-Request-ID", "evil id")
+	resp, err = http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("GET meta: %v", err)
+	}
+	body, _ := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	genID := resp.Header.Get("X-Request-ID")
+	if resp.StatusCode != http.StatusInternalServerError || genID == "" || genID == "evil id" {
+		t.Fatalf("expected 500 with generated request id, got %d %q", resp.StatusCode, genID)
+	}
+	if !strings.Contains(string(body), "request_id: "+genID) || strings.Contains(string(body), "disk") {
+		t.Fatalf("error body must carry request id but not the cause: %q", body)
+	}
+
+	type line struct {
+		Level     string  `json:"level"`
+		Msg       string  `json:"msg"`
+		RequestID string  `json:"request_id"`
+		Route     string  `json:"route"`
+		Status    int     `json:"status"`
+		Subject   string  `json:"subject"`
+		Duration  float64 `json:"duration_ms"`
+		Err       string  `json:"err"`
+	}
+	var access, failed []line
+	for _, raw := range strings.Split(strings.TrimSpace(buf.String()), "\n") {
+		var l line
+		if err := json.Unmarshal([]byte(raw), &l); err != nil {
+			t.Fatalf("invalid log line %q: %v", raw, err)
+		}
+		switch l.Msg {
+		case "http request":
+			access = append(access, l)
+		case "request failed":
+			failed = append(failed, l)
+		}
+	}
+	if len(access) != 2 {
+		t.Fatalf("expected 2 access log lines, got %+v", access)
+	}
+	if a := access[0]; a.RequestID != "req-log-1" || a.Route != "/v1/secret" || a.Status != 200 || a.Subject != "bearer:webhook" {
+		t.Fatalf("unexpected access log %+v", a)
+	}
+	if a := access[1]; a.RequestID != genID || a.Status != 500 || a.Level != "ERROR" {
+		t.Fatalf("unexpected access log for 500 %+v", a)
+	}
+	if len(failed) != 1 || failed[0].RequestID != genID || failed[0].Err != "disk I/O error" {
+		t.Fatalf("expected cause logged with request id, got %+v", failed)
+	}
+	if strings.Contains(buf.String(), "pw-123") || strings.Contains(buf.String(), "team-a/db") {
+		t.Fatalf("logs must not contain keys or values: %s", buf.String())
+	}
+}
+
+// reloadFailedPolicy: Dokument vorhanden, letzter Reload aber fehlgeschlagen
+type reloadFailedPolicy struct{ staticPolicySource }
+
+func (reloadFailedPolicy) Status() policy.ReloadStatus {
+	return policy.ReloadStatus{LastError: "yaml: line 3: mapping values are not allowed", LastErrorAt: time.Now()}
+}
+
+func TestReadyz_ChecksAndStrictMode(t *testing.T) {
+	db := openTestDB(t)
+	env := newTestEnvelope(t, "kek-1", 0x11)
+	pol := reloadFailedPolicy{staticPolicySource{doc: docAllowDemo()}}
+
+	get := func(c *health.Checker) (int, health.Report) {
+		t.Helper()
+		srv := httptest.NewServer(httpapi.NewRouter(httpapi.Deps{Readiness: c}))
+		defer srv.Close()
+		resp, err := http.Get(srv.URL + "/readyz")
+		if err != nil {
+			t.Fatalf("GET /readyz: %v", err)
+		}
+		defer resp.Body.Close()
+		var rep health.Report
+		if err := json.NewDecoder(resp.Body).Decode(&rep); err != nil {
+			t.Fatalf("decode report: %v", err)
+		}
+		return resp.StatusCode, rep
+	}
+	checks := func() []health.Check {
+		return []health.Check{health.SQLite(db), health.Policy(pol), health.Keyring(env), health.DiskSpace(filepath.Join(t.TempDir(), "glass.db"), 0)}
+	}
+
+	// nicht strikt: degraded wird gemeldet, bleibt aber ready
+	code, rep := get(health.NewChecker(false, checks()...))
+	if code != http.StatusOK || !rep.Ready || rep.Status != health.StatusDegraded || len(rep.Checks) != 4 {
+		t.Fatalf("expected 200 degraded, got %d %+v", code, rep)
+	}
+	if c := rep.Checks[1]; c.Name != "policy" || c.Status != health.StatusDegraded || c.Message != "last reload failed, serving last known good" {
+		t.Fatalf("unexpected policy check %+v", c)
+	}
+	for _, c := range []health.Result{rep.Checks[0], rep.Checks[2], rep.Checks[3]} {
+		if c.Status != health.StatusOK {
+			t.Fatalf("expected %s ok, got %+v", c.Name, c)
+		}
+	}
+
+	// strikt: dieselbe Degradierung macht unready
+	code, rep = get(health.NewChecker(true, checks()...))
+	if code != http.StatusServiceUnavailable || rep.Ready || !rep.Strict {
+		t.Fatalf("expected 503 in strict mode, got %d %+v", code, rep)
+	}
+
+	// DB weg: fail auch ohne strict
+	_ = db.Close()
+	code, rep = get(health.NewChecker(false, checks()...))
+	if code != http.StatusServiceUnavailable || rep.Status != health.StatusFail || rep.Checks[0].Status != health.StatusFail || rep.Checks[0].Message != "database unavailable" {
+		t.Fatalf("expected 503 with failing sqlite check, got %d %+v", code, rep)
+	}
+}
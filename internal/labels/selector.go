@@ -0,0 +1,196 @@
+package labels
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+type Operator string
+
+const (
+	OpEquals       Operator = "="
+	OpNotEquals    Operator = "!="
+	OpIn           Operator = "in"
+	OpNotIn        Operator = "notin"
+	OpExists       Operator = "exists"
+	OpDoesNotExist Operator = "!"
+)
+
+type Requirement struct {
+	Key    string
+	Op     Operator
+	Values []string
+}
+
+// Selector ist eine UND-Verknüpfung von Requirements (wie bei Kubernetes labelSelector).
+// Ein leerer Selector matcht alles.
+type Selector []Requirement
+
+func (s Selector) Empty() bool { return len(s) == 0 }
+
+func (s Selector) Matches(lbls map[string]string) bool {
+	for _, r := range s {
+		if !r.matches(lbls) {
+			return false
+		}
+	}
+	return true
+}
+
+func (r Requirement) matches(lbls map[string]string) bool {
+	v, has := lbls[r.Key]
+	switch r.Op {
+	case OpExists:
+		return has
+	case OpDoesNotExist:
+		return !has
+	case OpEquals:
+		return has && v == r.Values[0]
+	case OpNotEquals:
+		// wie Kubernetes: fehlendes Label erfüllt "!="
+		return !has || v != r.Values[0]
+	case OpIn:
+		return has && contains(r.Values, v)
+	case OpNotIn:
+		return !has || !contains(r.Values, v)
+	}
+	return false
+}
+
+func contains(vs []string, v string) bool {
+	for _, x := range vs {
+		if x == v {
+			return true
+		}
+	}
+	return false
+}
+
+// Parse liest einen Selector im Kubernetes-Format, z.B.
+// "team=payments,env!=dev,tier in (a,b),!legacy,owner"
+func Parse(s string) (Selector, error) {
+	s = strings.TrimSpace(s)
+	if s == "" {
+		return nil, nil
+	}
+
+	parts, err := splitTopLevel(s)
+	if err != nil {
+		return nil, err
+	}
+
+	var sel Selector
+	for _, p := range parts {
+		r, err := parseRequirement(strings.TrimSpace(p))
+		if err != nil {
+			return nil, err
+		}
+		sel = append(sel, r)
+	}
+	return sel, nil
+}
+
+// splitTopLevel trennt an Kommas außerhalb von Klammern
+func splitTopLevel(s string) ([]string, error) {
+	var (
+		out   []string
+		depth int
+		start int
+	)
+	for i, c := range s {
+		switch c {
+		case '(':
+			depth++
+			if depth > 1 {
+				return nil, fmt.Errorf("labelSelector: nested parentheses")
+			}
+		case ')':
+			depth--
+			if depth < 0 {
+				return nil, fmt.Errorf("labelSelector: unbalanced parentheses")
+			}
+		case ',':
+			if depth == 0 {
+				out = append(out, s[start:i])
+				start = i + 1
+			}
+		}
+	}
+	if depth != 0 {
+		return nil, fmt.Errorf("labelSelector: unbalanced parentheses")
+	}
+	return append(out, s[start:]), nil
+}
+
+func parseRequirement(p string) (Requirement, error) {
+	if p == "" {
+		return Requirement{}, fmt.Errorf("labelSelector: empty requirement")
+	}
+
+	if strings.HasPrefix(p, "!") {
+		k := strings.TrimSpace(p[1:])
+		if err := ValidateKey(k); err != nil {
+			return Requirement{}, err
+		}
+		return Requirement{Key: k, Op: OpDoesNotExist}, nil
+	}
+
+	// Reihenfolge wichtig: "!=" und "==" vor "="
+	for _, op := range []string{"!=", "==", "="} {
+		if i := strings.Index(p, op); i >= 0 {
+			k := strings.TrimSpace(p[:i])
+			v := strings.TrimSpace(p[i+len(op):])
+			if err := ValidateKey(k); err != nil {
+				return Requirement{}, err
+			}
+			if err := ValidateValue(v); err != nil {
+				return Requirement{}, err
+			}
+			o := OpEquals
+			if op == "!=" {
+				o = OpNotEquals
+			}
+			return Requirement{Key: k, Op: o, Values: []string{v}}, nil
+		}
+	}
+
+	if i := strings.Index(p, "("); i >= 0 {
+		if !strings.HasSuffix(p, ")") {
+			return Requirement{}, fmt.Errorf("labelSelector: missing ')' in %q", p)
+		}
+		fields := strings.Fields(p[:i])
+		if len(fields) != 2 {
+			return Requirement{}, fmt.Errorf("labelSelector: invalid set requirement %q", p)
+		}
+		k, opStr := fields[0], strings.ToLower(fields[1])
+		if err := ValidateKey(k); err != nil {
+			return Requirement{}, err
+		}
+		var o Operator
+		switch opStr {
+		case "in":
+			o = OpIn
+		case "notin":
+			o = OpNotIn
+		default:
+			return Requirement{}, fmt.Errorf("labelSelector: unknown operator %q", fields[1])
+		}
+
+		var vals []string
+		for _, v := range strings.Split(p[i+1:len(p)-1], ",") {
+			v = strings.TrimSpace(v)
+			if err := ValidateValue(v); err != nil {
+				return Requirement{}, err
+			}
+			vals = append(vals, v)
+		}
+		sort.Strings(vals)
+		return Requirement{Key: k, Op: o, Values: vals}, nil
+	}
+
+	if err := ValidateKey(p); err != nil {
+		return Requirement{}, err
+	}
+	return Requirement{Key: p, Op: OpExists}, nil
+}
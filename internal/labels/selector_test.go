@@ -0,0 +1,50 @@
+package labels_test
+
+import (
+	"testing"
+
+	"github.com/timgst1/glass/internal/labels"
+)
+
+func TestParse_Matches(t *testing.T) {
+	sel, err := labels.Parse("team=payments, env in (prod,staging), !legacy, tier!=db, owner")
+	if err != nil {
+		t.Fatalf("Parse: %v", err)
+	}
+
+	ok := map[string]string{"team": "payments", "env": "prod", "tier": "api", "owner": "alice"}
+	if !sel.Matches(ok) {
+		t.Fatalf("expected match for %v", ok)
+	}
+
+	cases := []map[string]string{
+		{"team": "billing", "env": "prod", "owner": "a"},
+		{"team": "payments", "env": "dev", "owner": "a"},
+		{"team": "payments", "env": "prod", "owner": "a", "legacy": "true"},
+		{"team": "payments", "env": "prod", "owner": "a", "tier": "db"},
+		{"team": "payments", "env": "prod"},
+	}
+	for _, c := range cases {
+		if sel.Matches(c) {
+			t.Fatalf("expected no match for %v", c)
+		}
+	}
+}
+
+func TestParse_EmptyMatchesAll(t *testing.T) {
+	sel, err := labels.Parse("  ")
+	if err != nil {
+		t.Fatalf("Parse: %v", err)
+	}
+	if !sel.Empty() || !sel.Matches(nil) {
+		t.Fatalf("expected empty selector to match everything")
+	}
+}
+
+func TestParse_Invalid(t *testing.T) {
+	for _, s := range []string{"env in (a,b", "team=pay ments", "env foo (a)", "=x", "a,,b"} {
+		if _, err := labels.Parse(s); err == nil {
+			t.Fatalf("expected error for %q", s)
+		}
+	}
+}
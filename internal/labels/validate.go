@@ -0,0 +1,41 @@
+package labels
+
+import "fmt"
+
+const (
+	maxKeyLen   = 253
+	maxValueLen = 63
+)
+
+// ValidateKey: nicht leer, max. 253 Zeichen, [A-Za-z0-9._/-]
+func ValidateKey(k string) error {
+	if k == "" {
+		return fmt.Errorf("label key is empty")
+	}
+	if len(k) > maxKeyLen {
+		return fmt.Errorf("label key %q is longer than %d characters", k, maxKeyLen)
+	}
+	for _, c := range k {
+		if !isAlnum(c) && c != '.' && c != '_' && c != '-' && c != '/' {
+			return fmt.Errorf("label key %q contains invalid character %q", k, c)
+		}
+	}
+	return nil
+}
+
+// ValidateValue: darf leer sein, max. 63 Zeichen, [A-Za-z0-9._-]
+func ValidateValue(v string) error {
+	if len(v) > maxValueLen {
+		return fmt.Errorf("label value %q is longer than %d characters", v, maxValueLen)
+	}
+	for _, c := range v {
+		if !isAlnum(c) && c != '.' && c != '_' && c != '-' {
+			return fmt.Errorf("label value %q contains invalid character %q", v, c)
+		}
+	}
+	return nil
+}
+
+func isAlnum(c rune) bool {
+	return (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z') || (c >= '0' && c <= '9')
+}
@@ -0,0 +1,101 @@
+package logging
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"strings"
+	"sync"
+)
+
+// Setup setzt slog.Default aus LOG_LEVEL (debug, info, warn, error) und LOG_FORMAT (text, json).
+// Muss vor app.Build laufen: Hintergrundjobs übernehmen slog.Default() beim Erzeugen.
+func Setup(level, format string, w io.Writer) error {
+	h, err := NewHandler(level, format, w)
+	if err != nil {
+		return err
+	}
+	slog.SetDefault(slog.New(h))
+	return nil
+}
+
+// NewHandler liefert einen Handler, der request_id aus dem Context an jeden *Context-Logaufruf hängt
+func NewHandler(level, format string, w io.Writer) (slog.Handler, error) {
+	var lvl slog.Level
+	if err := lvl.UnmarshalText([]byte(level)); err != nil {
+		return nil, fmt.Errorf("invalid LOG_LEVEL: %q (allowed: debug, info, warn, error)", level)
+	}
+	opts := &slog.HandlerOptions{Level: lvl}
+
+	switch strings.ToLower(format) {
+	case "text":
+		return contextHandler{slog.NewTextHandler(w, opts)}, nil
+	case "json":
+		return contextHandler{slog.NewJSONHandler(w, opts)}, nil
+	default:
+		return nil, fmt.Errorf("invalid LOG_FORMAT: %q (allowed: text, json)", format)
+	}
+}
+
+type contextHandler struct {
+	slog.Handler
+}
+
+func (h contextHandler) Handle(ctx context.Context, r slog.Record) error {
+	if id := RequestID(ctx); id != "" {
+		r.AddAttrs(slog.String("request_id", id))
+	}
+	return h.Handler.Handle(ctx, r)
+}
+
+func (h contextHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	return contextHandler{h.Handler.WithAttrs(attrs)}
+}
+
+func (h contextHandler) WithGroup(name string) slog.Handler {
+	return contextHandler{h.Handler.WithGroup(name)}
+}
+
+type infoKey struct{}
+
+// RequestInfo legt die RequestID-Middleware pro Request an; RequireAuth trägt später das Subject ein,
+// damit das Access-Log (das außerhalb von RequireAuth läuft) es sieht.
+type RequestInfo struct {
+	ID string
+
+	mu      sync.Mutex
+	subject string
+}
+
+func WithRequestInfo(ctx context.Context, info *RequestInfo) context.Context {
+	return context.WithValue(ctx, infoKey{}, info)
+}
+
+func InfoFromContext(ctx context.Context) (*RequestInfo, bool) {
+	info, ok := ctx.Value(infoKey{}).(*RequestInfo)
+	return info, ok && info != nil
+}
+
+// RequestID liefert die ID des laufenden Requests ("" außerhalb eines Requests)
+func RequestID(ctx context.Context) string {
+	if info, ok := InfoFromContext(ctx); ok {
+		return info.ID
+	}
+	return ""
+}
+
+// SetSubject merkt das authentifizierte Subject ("kind:name") für das Access-Log
+func SetSubject(ctx context.Context, subject string) {
+	if info, ok := InfoFromContext(ctx); ok {
+		info.mu.Lock()
+		info.subject = subject
+		info.mu.Unlock()
+	}
+}
+
+func (i *RequestInfo) Subject() string {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+	return i.subject
+}
@@ -0,0 +1,152 @@
+package metrics
+
+import (
+	"context"
+	"net/http"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+// Registry enthält alle glass-Metriken plus Go-/Prozess-Metriken.
+// Eigenes Registry statt prometheus.DefaultRegisterer: Tests und Bibliotheken registrieren nichts dazwischen.
+var Registry = prometheus.NewRegistry()
+
+var (
+	HTTPRequests = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "glass_http_requests_total",
+		Help: "HTTP requests by route pattern, method and status.",
+	}, []string{"route", "method", "status"})
+
+	HTTPDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "glass_http_request_duration_seconds",
+		Help:    "HTTP request latency by route pattern, method and status.",
+		Buckets: prometheus.DefBuckets,
+	}, []string{"route", "method", "status"})
+
+	AuthnFailures = prometheus.NewCounter(prometheus.CounterOpts{
+		Name: "glass_authn_failures_total",
+		Help: "Requests rejected because authentication failed.",
+	})
+
+	AuthzDecisions = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "glass_authz_decisions_total",
+		Help: "Authorization decisions by action, decision and reason.",
+	}, []string{"action", "decision", "reason"})
+
+	PolicyReloads = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "glass_policy_reloads_total",
+		Help: "Policy reloads by result (success, failure).",
+	}, []string{"result"})
+
+	PolicyLastReload = prometheus.NewGauge(prometheus.GaugeOpts{
+		Name: "glass_policy_last_reload_success_timestamp_seconds",
+		Help: "Unix time of the last successful policy reload.",
+	})
+
+	SQLiteDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "glass_sqlite_query_duration_seconds",
+		Help:    "Latency of SQLite-backed service operations.",
+		Buckets: []float64{.0005, .001, .0025, .005, .01, .025, .05, .1, .25, .5, 1},
+	}, []string{"operation"})
+
+	CryptoOps = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "glass_crypto_operations_total",
+		Help: "Envelope encryption operations by operation (encrypt, decrypt, rewrap) and kek_id.",
+	}, []string{"operation", "kek_id"})
+)
+
+func init() {
+	Registry.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+		HTTPRequests, HTTPDuration,
+		AuthnFailures, AuthzDecisions,
+		PolicyReloads, PolicyLastReload,
+		SQLiteDuration, CryptoOps,
+		stats,
+	)
+}
+
+// Handler liefert /metrics im Prometheus-Textformat
+func Handler() http.Handler {
+	// ContinueOnError: schlägt z.B. die Stats-Abfrage fehl, kommen die übrigen Metriken trotzdem
+	return promhttp.HandlerFor(Registry, promhttp.HandlerOpts{ErrorHandling: promhttp.ContinueOnError})
+}
+
+// ObserveAuthz zählt eine AuthZ-Entscheidung. Als reason bleibt bei Allow nur die Rolle ("role=x"),
+// Fehlertexte (z.B. Compile-Fehler) werden abgeschnitten: Keys und Prefixes landen nicht in Labels.
+func ObserveAuthz(action string, allowed bool, reason string) {
+	decision := "deny"
+	if allowed {
+		decision = "allow"
+	}
+	if i := strings.Index(reason, ":"); i >= 0 {
+		reason = reason[:i]
+	}
+	if strings.HasPrefix(reason, "role=") {
+		reason, _, _ = strings.Cut(reason, " ")
+	}
+	AuthzDecisions.WithLabelValues(action, decision, reason).Inc()
+}
+
+// ObservePolicyReload zählt einen Reload; bei Erfolg wird der Zeitstempel gesetzt
+func ObservePolicyReload(err error) {
+	if err != nil {
+		PolicyReloads.WithLabelValues("failure").Inc()
+		return
+	}
+	PolicyReloads.WithLabelValues("success").Inc()
+	PolicyLastReload.SetToCurrentTime()
+}
+
+// ObserveQuery misst eine SQLite-Operation: defer metrics.ObserveQuery("get_secret", time.Now())
+func ObserveQuery(operation string, start time.Time) {
+	SQLiteDuration.WithLabelValues(operation).Observe(time.Since(start).Seconds())
+}
+
+// SecretStatsFunc liefert lebende Keys und gespeicherte (nicht vernichtete) Versionen
+type SecretStatsFunc func(ctx context.Context) (keys, versions int64, err error)
+
+// secretStats fragt die Zahlen erst beim Scrape ab; ohne Quelle fehlen die beiden Metriken
+type secretStats struct {
+	fn       atomic.Pointer[SecretStatsFunc]
+	keys     *prometheus.Desc
+	versions *prometheus.Desc
+}
+
+var stats = &secretStats{
+	keys:     prometheus.NewDesc("glass_secrets", "Live (not deleted) secret keys.", nil, nil),
+	versions: prometheus.NewDesc("glass_secret_versions", "Stored secret versions, excluding destroyed ones.", nil, nil),
+}
+
+// SetSecretStats setzt die Quelle für glass_secrets und glass_secret_versions; ein weiterer Aufruf
+// (z.B. zweites app.Build im selben Prozess) ersetzt die vorherige
+func SetSecretStats(fn SecretStatsFunc) {
+	stats.fn.Store(&fn)
+}
+
+func (c *secretStats) Describe(ch chan<- *prometheus.Desc) {
+	ch <- c.keys
+	ch <- c.versions
+}
+
+func (c *secretStats) Collect(ch chan<- prometheus.Metric) {
+	fn := c.fn.Load()
+	if fn == nil || *fn == nil {
+		return
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	keys, versions, err := (*fn)(ctx)
+	if err != nil {
+		ch <- prometheus.NewInvalidMetric(c.keys, err)
+		return
+	}
+	ch <- prometheus.MustNewConstMetric(c.keys, prometheus.GaugeValue, float64(keys))
+	ch <- prometheus.MustNewConstMetric(c.versions, prometheus.GaugeValue, float64(versions))
+}
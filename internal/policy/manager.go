@@ -4,10 +4,12 @@ import (
 	"context"
 	"log/slog"
 	"path/filepath"
+	"sync"
 	"sync/atomic"
 	"time"
 
 	"github.com/fsnotify/fsnotify"
+	"github.com/timgst1/glass/internal/metrics"
 )
 
 type Manager struct {
@@ -20,6 +22,17 @@ type Manager struct {
 	interval time.Duration
 
 	current atomic.Value
+
+	mu     sync.Mutex
+	status ReloadStatus
+}
+
+// ReloadStatus beschreibt den letzten Reload (für /readyz)
+type ReloadStatus struct {
+	LastSuccess time.Time
+	// LastError ist gesetzt, wenn der letzte Reload fehlschlug (das zuletzt gültige Dokument bleibt aktiv)
+	LastError   string
+	LastErrorAt time.Time
 }
 
 type Options struct {
@@ -113,11 +126,26 @@ func (m *Manager) Start(ctx context.Context) error {
 	return nil
 }
 
+// Status liefert den Zustand des letzten Reloads
+func (m *Manager) Status() ReloadStatus {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.status
+}
+
 func (m *Manager) reload() error {
 	doc, err := LoadFromFile(m.filePath)
+	metrics.ObservePolicyReload(err)
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
 	if err != nil {
+		m.status.LastError = err.Error()
+		m.status.LastErrorAt = time.Now()
 		return err
 	}
 	m.current.Store(doc)
+	m.status.LastSuccess = time.Now()
+	m.status.LastError = ""
 	return nil
 }
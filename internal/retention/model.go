@@ -0,0 +1,95 @@
+package retention
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Rule begrenzt die Anzahl bzw. das Alter der Versionen eines Keys.
+// 0 bedeutet jeweils "keine Begrenzung". Die neueste Version wird nie entfernt.
+type Rule struct {
+	MaxVersions int           `yaml:"maxVersions"`
+	MaxAge      time.Duration `yaml:"maxAge"`
+}
+
+func (r Rule) IsZero() bool { return r.MaxVersions == 0 && r.MaxAge == 0 }
+
+type PrefixRule struct {
+	KeyPrefix string `yaml:"keyPrefix"`
+	Rule      `yaml:",inline"`
+}
+
+// Rules: globale Default-Regel plus Regeln pro Key-Prefix (längster Prefix gewinnt)
+type Rules struct {
+	Default Rule         `yaml:"default"`
+	Rules   []PrefixRule `yaml:"rules"`
+}
+
+func LoadFromFile(path string) (*Rules, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var r Rules
+	if err := yaml.Unmarshal(b, &r); err != nil {
+		return nil, err
+	}
+	if err := Validate(&r); err != nil {
+		return nil, err
+	}
+	return &r, nil
+}
+
+func Validate(r *Rules) error {
+	if err := validateRule("default", r.Default); err != nil {
+		return err
+	}
+
+	seen := map[string]struct{}{}
+	for _, pr := range r.Rules {
+		if pr.KeyPrefix == "" {
+			return fmt.Errorf("retention: rule keyPrefix missing")
+		}
+		if !strings.HasSuffix(pr.KeyPrefix, "/") {
+			return fmt.Errorf("retention: keyPrefix %q must end with '/'", pr.KeyPrefix)
+		}
+		if _, ok := seen[pr.KeyPrefix]; ok {
+			return fmt.Errorf("retention: duplicate keyPrefix %q", pr.KeyPrefix)
+		}
+		seen[pr.KeyPrefix] = struct{}{}
+		if err := validateRule(pr.KeyPrefix, pr.Rule); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func validateRule(name string, r Rule) error {
+	if r.MaxVersions < 0 {
+		return fmt.Errorf("retention: maxVersions must be >= 0 (%s)", name)
+	}
+	if r.MaxAge < 0 {
+		return fmt.Errorf("retention: maxAge must be >= 0 (%s)", name)
+	}
+	return nil
+}
+
+// For liefert die Regel für einen Key: längster passender Prefix, sonst Default
+func (r *Rules) For(key string) Rule {
+	if r == nil {
+		return Rule{}
+	}
+	best := -1
+	out := r.Default
+	for _, pr := range r.Rules {
+		if strings.HasPrefix(key, pr.KeyPrefix) && len(pr.KeyPrefix) > best {
+			best = len(pr.KeyPrefix)
+			out = pr.Rule
+		}
+	}
+	return out
+}
@@ -0,0 +1,195 @@
+package retention
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+	"time"
+)
+
+type PruneOptions struct {
+	BatchSize int
+	DryRun    bool
+	// Now ist der Referenzzeitpunkt für maxAge (default: time.Now())
+	Now time.Time
+}
+
+type PruneResult struct {
+	Keys   int
+	Pruned int
+}
+
+// Prune entfernt Versionen, die gegen die Retention-Regeln verstoßen.
+// Die neueste Version eines Keys und vernichtete Marker-Zeilen bleiben immer erhalten.
+// Keys werden wie bei admin.RewrapKEK per Cursor in Batches abgearbeitet.
+func Prune(ctx context.Context, db *sql.DB, rules *Rules, opt PruneOptions) (PruneResult, error) {
+	if db == nil {
+		return PruneResult{}, fmt.Errorf("db is nil")
+	}
+	if rules == nil {
+		return PruneResult{}, fmt.Errorf("rules are nil")
+	}
+	if opt.BatchSize <= 0 {
+		opt.BatchSize = 500
+	}
+	if opt.Now.IsZero() {
+		opt.Now = time.Now()
+	}
+
+	var res PruneResult
+
+	// Cursor for stable pagination
+	lastKey := ""
+
+	for {
+		keys, err := nextKeys(ctx, db, lastKey, opt.BatchSize)
+		if err != nil {
+			return res, err
+		}
+		if len(keys) == 0 {
+			break
+		}
+		lastKey = keys[len(keys)-1]
+
+		tx, err := db.BeginTx(ctx, nil)
+		if err != nil {
+			return res, err
+		}
+
+		for _, k := range keys {
+			rule := rules.For(k)
+			if rule.IsZero() {
+				continue
+			}
+
+			versions, err := prunableVersions(ctx, tx, k, rule, opt.Now)
+			if err != nil {
+				_ = tx.Rollback()
+				return res, err
+			}
+			if len(versions) == 0 {
+				continue
+			}
+			res.Keys++
+
+			if opt.DryRun {
+				res.Pruned += len(versions)
+				continue
+			}
+
+			n, err := deleteVersions(ctx, tx, k, versions)
+			if err != nil {
+				_ = tx.Rollback()
+				return res, fmt.Errorf("prune %s: %w", k, err)
+			}
+			res.Pruned += n
+		}
+
+		if opt.DryRun {
+			_ = tx.Rollback()
+			continue
+		}
+		if err := tx.Commit(); err != nil {
+			_ = tx.Rollback()
+			return res, err
+		}
+	}
+
+	return res, nil
+}
+
+func nextKeys(ctx context.Context, db *sql.DB, after string, limit int) ([]string, error) {
+	rows, err := db.QueryContext(ctx, `
+SELECT DISTINCT key
+FROM secrets
+WHERE key > ?
+ORDER BY key
+LIMIT ?;`, after, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var keys []string
+	for rows.Next() {
+		var k string
+		if err := rows.Scan(&k); err != nil {
+			return nil, err
+		}
+		keys = append(keys, k)
+	}
+	return keys, rows.Err()
+}
+
+// prunableVersions liefert die Versionen eines Keys, die laut rule entfernt werden dürfen
+func prunableVersions(ctx context.Context, tx *sql.Tx, key string, rule Rule, now time.Time) ([]int64, error) {
+	rows, err := tx.QueryContext(ctx, `
+SELECT version, created_at, deleted, destroyed
+FROM secrets
+WHERE key = ?
+ORDER BY version DESC;`, key)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var out []int64
+	idx := 0
+	keptLive := false
+	for rows.Next() {
+		var (
+			version   int64
+			createdAt string
+			deleted   bool
+			destroyed bool
+		)
+		if err := rows.Scan(&version, &createdAt, &deleted, &destroyed); err != nil {
+			return nil, err
+		}
+		pos := idx
+		idx++
+
+		// neueste Version nie entfernen, Destroy-Marker bleiben für das Audit
+		if pos == 0 || destroyed {
+			keptLive = keptLive || (!deleted && !destroyed)
+			continue
+		}
+		// neueste lebende Version hinter einem Tombstone bleibt, sonst schlägt Undelete fehl
+		if !deleted && !keptLive {
+			keptLive = true
+			continue
+		}
+
+		if rule.MaxVersions > 0 && pos >= rule.MaxVersions {
+			out = append(out, version)
+			continue
+		}
+		if rule.MaxAge > 0 {
+			ts, err := time.Parse(time.RFC3339Nano, createdAt)
+			if err != nil {
+				return nil, fmt.Errorf("parse created_at of %s@%d: %w", key, version, err)
+			}
+			if now.Sub(ts) > rule.MaxAge {
+				out = append(out, version)
+			}
+		}
+	}
+	return out, rows.Err()
+}
+
+func deleteVersions(ctx context.Context, tx *sql.Tx, key string, versions []int64) (int, error) {
+	ph := make([]string, 0, len(versions))
+	args := []any{key}
+	for _, v := range versions {
+		ph = append(ph, "?")
+		args = append(args, v)
+	}
+
+	r, err := tx.ExecContext(ctx, `DELETE FROM secrets WHERE key = ? AND version IN (`+strings.Join(ph, ",")+`);`, args...)
+	if err != nil {
+		return 0, err
+	}
+	n, err := r.RowsAffected()
+	return int(n), err
+}
@@ -0,0 +1,171 @@
+package retention_test
+
+import (
+	"context"
+	"database/sql"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/timgst1/glass/internal/retention"
+	"github.com/timgst1/glass/internal/service"
+	"github.com/timgst1/glass/internal/storage/sqlite"
+)
+
+func openTestDB(t *testing.T) *sql.DB {
+	t.Helper()
+	db, err := sqlite.Open(filepath.Join(t.TempDir(), "test.sqlite"))
+	if err != nil {
+		t.Fatalf("sqlite.Open: %v", err)
+	}
+	t.Cleanup(func() { _ = db.Close() })
+	if err := sqlite.Migrate(db); err != nil {
+		t.Fatalf("sqlite.Migrate: %v", err)
+	}
+	return db
+}
+
+func putN(t *testing.T, svc service.SecretService, key string, n int) {
+	t.Helper()
+	for i := 0; i < n; i++ {
+		if _, err := svc.PutSecret(context.Background(), key, "v"); err != nil {
+			t.Fatalf("PutSecret %s: %v", key, err)
+		}
+	}
+}
+
+func versionCount(t *testing.T, db *sql.DB, key string) int {
+	t.Helper()
+	var n int
+	if err := db.QueryRow(`SELECT COUNT(*) FROM secrets WHERE key = ?`, key).Scan(&n); err != nil {
+		t.Fatalf("count: %v", err)
+	}
+	return n
+}
+
+func TestRulesFor_LongestPrefixWins(t *testing.T) {
+	r := &retention.Rules{
+		Default: retention.Rule{MaxVersions: 10},
+		Rules: []retention.PrefixRule{
+			{KeyPrefix: "team-a/", Rule: retention.Rule{MaxVersions: 5}},
+			{KeyPrefix: "team-a/ci/", Rule: retention.Rule{MaxVersions: 2}},
+		},
+	}
+
+	if got := r.For("team-a/ci/token").MaxVersions; got != 2 {
+		t.Fatalf("expected 2, got %d", got)
+	}
+	if got := r.For("team-a/db").MaxVersions; got != 5 {
+		t.Fatalf("expected 5, got %d", got)
+	}
+	if got := r.For("other").MaxVersions; got != 10 {
+		t.Fatalf("expected default 10, got %d", got)
+	}
+}
+
+func TestValidate_KeyPrefixMustEndWithSlash(t *testing.T) {
+	r := &retention.Rules{Rules: []retention.PrefixRule{{KeyPrefix: "team-a", Rule: retention.Rule{MaxVersions: 1}}}}
+	if err := retention.Validate(r); err == nil {
+		t.Fatalf("expected error for keyPrefix without trailing '/', got nil")
+	}
+}
+
+func TestPrune_MaxVersionsPerPrefix(t *testing.T) {
+	db := openTestDB(t)
+	svc := service.NewSQLiteSecretService(db, nil)
+
+	putN(t, svc, "team-a/db", 5)
+	putN(t, svc, "team-b/db", 5)
+
+	rules := &retention.Rules{
+		Rules: []retention.PrefixRule{
+			{KeyPrefix: "team-a/", Rule: retention.Rule{MaxVersions: 2}},
+		},
+	}
+
+	// dry-run zählt nur
+	res, err := retention.Prune(context.Background(), db, rules, retention.PruneOptions{DryRun: true, BatchSize: 1})
+	if err != nil {
+		t.Fatalf("Prune dry-run: %v", err)
+	}
+	if res.Pruned != 3 || res.Keys != 1 {
+		t.Fatalf("unexpected dry-run result: %+v", res)
+	}
+	if n := versionCount(t, db, "team-a/db"); n != 5 {
+		t.Fatalf("dry-run must not delete, got %d versions", n)
+	}
+
+	res, err = retention.Prune(context.Background(), db, rules, retention.PruneOptions{BatchSize: 1})
+	if err != nil {
+		t.Fatalf("Prune: %v", err)
+	}
+	if res.Pruned != 3 {
+		t.Fatalf("expected 3 pruned, got %+v", res)
+	}
+	if n := versionCount(t, db, "team-a/db"); n != 2 {
+		t.Fatalf("expected 2 versions left, got %d", n)
+	}
+	if n := versionCount(t, db, "team-b/db"); n != 5 {
+		t.Fatalf("expected team-b untouched, got %d", n)
+	}
+
+	got, err := svc.GetSecretVersion(context.Background(), "team-a/db", 0)
+	if err != nil {
+		t.Fatalf("GetSecretVersion: %v", err)
+	}
+	if got.Version != 5 {
+		t.Fatalf("expected latest version 5 to survive, got %d", got.Version)
+	}
+}
+
+func TestPrune_MaxAgeNeverRemovesLatest(t *testing.T) {
+	db := openTestDB(t)
+	svc := service.NewSQLiteSecretService(db, nil)
+
+	putN(t, svc, "demo", 3)
+
+	rules := &retention.Rules{Default: retention.Rule{MaxAge: time.Hour}}
+	res, err := retention.Prune(context.Background(), db, rules, retention.PruneOptions{Now: time.Now().Add(48 * time.Hour)})
+	if err != nil {
+		t.Fatalf("Prune: %v", err)
+	}
+	if res.Pruned != 2 {
+		t.Fatalf("expected 2 pruned, got %+v", res)
+	}
+	if n := versionCount(t, db, "demo"); n != 1 {
+		t.Fatalf("expected only latest version left, got %d", n)
+	}
+}
+
+func TestPrune_KeepsLatestLiveVersionBehindTombstone(t *testing.T) {
+	db := openTestDB(t)
+	svc := service.NewSQLiteSecretService(db, nil)
+	ctx := context.Background()
+
+	putN(t, svc, "demo", 2)
+	if _, err := svc.PutSecret(ctx, "demo", "latest"); err != nil {
+		t.Fatalf("PutSecret: %v", err)
+	}
+	if _, err := svc.DeleteSecret(ctx, "demo"); err != nil {
+		t.Fatalf("DeleteSecret: %v", err)
+	}
+
+	for _, rule := range []retention.Rule{{MaxVersions: 1}, {MaxAge: time.Hour}} {
+		_, err := retention.Prune(ctx, db, &retention.Rules{Default: rule}, retention.PruneOptions{Now: time.Now().Add(48 * time.Hour)})
+		if err != nil {
+			t.Fatalf("Prune %+v: %v", rule, err)
+		}
+		// Tombstone + letzte lebende Version
+		if n := versionCount(t, db, "demo"); n != 2 {
+			t.Fatalf("rule %+v: expected 2 versions left, got %d", rule, n)
+		}
+	}
+
+	if _, err := svc.UndeleteSecret(ctx, "demo"); err != nil {
+		t.Fatalf("UndeleteSecret after prune: %v", err)
+	}
+	v, err := svc.GetSecret(ctx, "demo")
+	if err != nil || v != "latest" {
+		t.Fatalf("expected restored latest value, got %q %v", v, err)
+	}
+}
@@ -0,0 +1,61 @@
+package retention
+
+import (
+	"context"
+	"database/sql"
+	"log/slog"
+	"time"
+)
+
+// Pruner setzt die Retention-Regeln periodisch im Hintergrund durch
+type Pruner struct {
+	db       *sql.DB
+	rules    *Rules
+	interval time.Duration
+	batch    int
+
+	log *slog.Logger
+}
+
+func NewPruner(db *sql.DB, rules *Rules, interval time.Duration) *Pruner {
+	if interval <= 0 {
+		interval = time.Hour
+	}
+	return &Pruner{
+		db:       db,
+		rules:    rules,
+		interval: interval,
+		batch:    500,
+		log:      slog.Default(),
+	}
+}
+
+func (p *Pruner) Start(ctx context.Context) {
+	go func() {
+		ticker := time.NewTicker(p.interval)
+		defer ticker.Stop()
+
+		p.runOnce(ctx)
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				p.runOnce(ctx)
+			}
+		}
+	}()
+}
+
+func (p *Pruner) runOnce(ctx context.Context) {
+	res, err := Prune(ctx, p.db, p.rules, PruneOptions{BatchSize: p.batch})
+	if err != nil {
+		if ctx.Err() == nil {
+			p.log.Error("retention prune failed", "err", err)
+		}
+		return
+	}
+	if res.Pruned > 0 {
+		p.log.Info("retention prune finished", "keys", res.Keys, "pruned", res.Pruned)
+	}
+}
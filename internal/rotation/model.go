@@ -0,0 +1,114 @@
+package rotation
+
+import (
+	"fmt"
+	"os"
+	"path"
+	"strings"
+	"time"
+
+	"github.com/timgst1/glass/internal/generator"
+	"gopkg.in/yaml.v3"
+)
+
+// Rule rotiert alle passenden Keys, sobald ihre neueste Version älter als Interval ist.
+// Genau eins von KeyPrefix oder KeyPattern (path.Match-Syntax, "*" matcht kein "/") muss gesetzt sein.
+type Rule struct {
+	KeyPrefix  string         `yaml:"keyPrefix"`
+	KeyPattern string         `yaml:"keyPattern"`
+	Interval   time.Duration  `yaml:"interval"`
+	Generator  generator.Spec `yaml:"generator"`
+}
+
+// Rules: die erste passende Regel (Reihenfolge in der Datei) gilt
+type Rules struct {
+	Rules []Rule `yaml:"rules"`
+}
+
+func LoadFromFile(path string) (*Rules, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var r Rules
+	if err := yaml.Unmarshal(b, &r); err != nil {
+		return nil, err
+	}
+	if err := Validate(&r); err != nil {
+		return nil, err
+	}
+	return &r, nil
+}
+
+func Validate(r *Rules) error {
+	for i, rl := range r.Rules {
+		name := rl.name()
+		if (rl.KeyPrefix == "") == (rl.KeyPattern == "") {
+			return fmt.Errorf("rotation: rule %d needs exactly one of keyPrefix or keyPattern", i)
+		}
+		if rl.KeyPattern != "" {
+			if _, err := path.Match(rl.KeyPattern, ""); err != nil {
+				return fmt.Errorf("rotation: invalid keyPattern %q: %w", rl.KeyPattern, err)
+			}
+		}
+		if rl.Interval <= 0 {
+			return fmt.Errorf("rotation: interval must be > 0 (%s)", name)
+		}
+		if err := rl.Generator.Validate(); err != nil {
+			return fmt.Errorf("rotation: generator (%s): %w", name, err)
+		}
+	}
+	return nil
+}
+
+func (rl Rule) name() string {
+	if rl.KeyPattern != "" {
+		return rl.KeyPattern
+	}
+	return rl.KeyPrefix
+}
+
+func (rl Rule) matches(key string) bool {
+	if rl.KeyPrefix != "" {
+		return strings.HasPrefix(key, rl.KeyPrefix)
+	}
+	ok, _ := path.Match(rl.KeyPattern, key)
+	return ok
+}
+
+// listPrefix ist der feste Anfang der Regel, unter dem nach Kandidaten gesucht wird
+func (rl Rule) listPrefix() string {
+	if rl.KeyPrefix != "" {
+		return rl.KeyPrefix
+	}
+	if i := strings.IndexAny(rl.KeyPattern, `*?[\`); i >= 0 {
+		return rl.KeyPattern[:i]
+	}
+	return rl.KeyPattern
+}
+
+// For liefert die erste passende Regel für einen Key
+func (r *Rules) For(key string) (Rule, bool) {
+	if r == nil {
+		return Rule{}, false
+	}
+	for _, rl := range r.Rules {
+		if rl.matches(key) {
+			return rl, true
+		}
+	}
+	return Rule{}, false
+}
+
+// NextRotation liefert den Zeitpunkt der nächsten Rotation für einen Key mit der gegebenen neuesten Version
+func (r *Rules) NextRotation(key, createdAt string) (time.Time, bool) {
+	rl, ok := r.For(key)
+	if !ok {
+		return time.Time{}, false
+	}
+	t, err := time.Parse(time.RFC3339Nano, createdAt)
+	if err != nil {
+		return time.Time{}, false
+	}
+	return t.Add(rl.Interval), true
+}
@@ -0,0 +1,135 @@
+package rotation
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"time"
+
+	"github.com/timgst1/glass/internal/authn"
+	"github.com/timgst1/glass/internal/generator"
+	"github.com/timgst1/glass/internal/service"
+)
+
+// Rotator erzeugt periodisch neue Versionen für fällige Keys (Subject system:rotator)
+type Rotator struct {
+	svc      service.SecretService
+	rules    *Rules
+	interval time.Duration
+
+	log *slog.Logger
+}
+
+type RunResult struct {
+	Checked int
+	Rotated int
+}
+
+// NewRotator erwartet den ungesicherten Service: der Rotator ist selbst die Autorität für seine Keys
+func NewRotator(svc service.SecretService, rules *Rules, interval time.Duration) *Rotator {
+	if interval <= 0 {
+		interval = time.Minute
+	}
+	return &Rotator{
+		svc:      svc,
+		rules:    rules,
+		interval: interval,
+		log:      slog.Default(),
+	}
+}
+
+func (r *Rotator) Start(ctx context.Context) {
+	go func() {
+		ticker := time.NewTicker(r.interval)
+		defer ticker.Stop()
+
+		r.runOnce(ctx)
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				r.runOnce(ctx)
+			}
+		}
+	}()
+}
+
+func (r *Rotator) runOnce(ctx context.Context) {
+	res, err := r.RunOnce(ctx, time.Now())
+	if err != nil {
+		if ctx.Err() == nil {
+			r.log.Error("rotation failed", "err", err)
+		}
+		return
+	}
+	if res.Rotated > 0 {
+		r.log.Info("rotation finished", "checked", res.Checked, "rotated", res.Rotated)
+	}
+}
+
+// RunOnce rotiert alle Keys, deren neueste Version älter als das Intervall ihrer Regel ist
+func (r *Rotator) RunOnce(ctx context.Context, now time.Time) (RunResult, error) {
+	ctx = authn.WithSubject(ctx, authn.Subject{Kind: "system", Name: "rotator"})
+
+	var res RunResult
+	seen := map[string]bool{}
+	for _, rl := range r.rules.Rules {
+		listing, err := r.svc.ListKeys(ctx, rl.listPrefix(), "")
+		if err != nil {
+			return res, err
+		}
+
+		for _, m := range listing.Keys {
+			if seen[m.Key] {
+				continue
+			}
+			seen[m.Key] = true
+
+			// Überlappende Regeln: es gilt immer die erste passende
+			eff, ok := r.rules.For(m.Key)
+			if !ok {
+				continue
+			}
+			res.Checked++
+
+			next, ok := r.rules.NextRotation(m.Key, m.CreatedAt)
+			if !ok || now.Before(next) {
+				continue
+			}
+
+			rotated, err := r.rotate(ctx, m, eff)
+			if err != nil {
+				return res, err
+			}
+			if rotated {
+				res.Rotated++
+			}
+		}
+	}
+	return res, nil
+}
+
+func (r *Rotator) rotate(ctx context.Context, m service.SecretMeta, rl Rule) (bool, error) {
+	gen, err := generator.Generate(rl.Generator)
+	if err != nil {
+		return false, err
+	}
+
+	opts := []service.PutOption{service.WithExpectedVersion(m.Version)}
+	if gen.ContentType != "" {
+		opts = append(opts, service.WithContentType(gen.ContentType))
+	}
+
+	ver, err := r.svc.PutSecret(ctx, m.Key, gen.Value, opts...)
+	if errors.Is(err, service.ErrConflict) {
+		// zwischenzeitlich neu geschrieben -> beim nächsten Lauf neu bewerten
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+
+	r.log.Info("secret rotated", "key", m.Key, "version", ver)
+	return true, nil
+}
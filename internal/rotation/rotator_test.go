@@ -0,0 +1,112 @@
+package rotation_test
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/timgst1/glass/internal/generator"
+	"github.com/timgst1/glass/internal/rotation"
+	"github.com/timgst1/glass/internal/service"
+)
+
+func TestLoadFromFile_ValidatesRules(t *testing.T) {
+	dir := t.TempDir()
+	good := filepath.Join(dir, "good.yaml")
+	if err := os.WriteFile(good, []byte(`
+rules:
+  - keyPattern: "team-a/*/password"
+    interval: 720h
+    generator:
+      type: password
+      length: 40
+  - keyPrefix: "team-a/"
+    interval: 24h
+    generator:
+      type: uuid
+`), 0o600); err != nil {
+		t.Fatalf("write: %v", err)
+	}
+	rules, err := rotation.LoadFromFile(good)
+	if err != nil {
+		t.Fatalf("LoadFromFile: %v", err)
+	}
+
+	// erste passende Regel gewinnt
+	rl, ok := rules.For("team-a/db/password")
+	if !ok || rl.Interval != 720*time.Hour || rl.Generator.Length != 40 {
+		t.Fatalf("unexpected rule for password: %+v", rl)
+	}
+	rl, ok = rules.For("team-a/db/user")
+	if !ok || rl.Generator.Type != "uuid" {
+		t.Fatalf("unexpected rule for user: %+v", rl)
+	}
+	if _, ok := rules.For("team-b/x"); ok {
+		t.Fatalf("expected no rule for team-b/x")
+	}
+
+	for _, bad := range []rotation.Rules{
+		{Rules: []rotation.Rule{{KeyPrefix: "a/", Interval: time.Hour}}},
+		{Rules: []rotation.Rule{{Interval: time.Hour, Generator: generator.Spec{Type: "uuid"}}}},
+		{Rules: []rotation.Rule{{KeyPrefix: "a/", Generator: generator.Spec{Type: "uuid"}}}},
+		{Rules: []rotation.Rule{{KeyPattern: "a/[", Interval: time.Hour, Generator: generator.Spec{Type: "uuid"}}}},
+	} {
+		if err := rotation.Validate(&bad); err == nil {
+			t.Fatalf("expected validation error for %+v", bad)
+		}
+	}
+}
+
+func TestRotator_RunOnceRotatesDueKeys(t *testing.T) {
+	svc := service.NewMemorySecretService(map[string]string{
+		"team-a/db/password": "initial",
+		"team-b/db/password": "untouched",
+	})
+	rules := &rotation.Rules{Rules: []rotation.Rule{{
+		KeyPattern: "team-a/*/password",
+		Interval:   time.Hour,
+		Generator:  generator.Spec{Type: "password", Length: 24},
+	}}}
+	rot := rotation.NewRotator(svc, rules, time.Minute)
+	ctx := context.Background()
+
+	res, err := rot.RunOnce(ctx, time.Now())
+	if err != nil {
+		t.Fatalf("RunOnce: %v", err)
+	}
+	if res.Checked != 1 || res.Rotated != 0 {
+		t.Fatalf("expected nothing due yet, got %+v", res)
+	}
+
+	res, err = rot.RunOnce(ctx, time.Now().Add(2*time.Hour))
+	if err != nil {
+		t.Fatalf("RunOnce: %v", err)
+	}
+	if res.Rotated != 1 {
+		t.Fatalf("expected 1 rotation, got %+v", res)
+	}
+
+	meta, err := svc.GetSecretMeta(ctx, "team-a/db/password")
+	if err != nil {
+		t.Fatalf("GetSecretMeta: %v", err)
+	}
+	if meta.Version != 2 || meta.CreatedBy != "system:rotator" {
+		t.Fatalf("expected v2 by system:rotator, got %+v", meta)
+	}
+	val, _ := svc.GetSecret(ctx, "team-a/db/password")
+	if len(val) != 24 || val == "initial" {
+		t.Fatalf("expected generated password, got %q", val)
+	}
+
+	other, _ := svc.GetSecretMeta(ctx, "team-b/db/password")
+	if other.Version != 1 {
+		t.Fatalf("expected team-b untouched, got %+v", other)
+	}
+
+	next, ok := rules.NextRotation(meta.Key, meta.CreatedAt)
+	if !ok || next.Before(time.Now()) {
+		t.Fatalf("expected next rotation in the future, got %v", next)
+	}
+}
@@ -0,0 +1,44 @@
+package service
+
+// ChangeType beschreibt die Art einer Änderung im Change-Feed
+type ChangeType string
+
+const (
+	ChangePut      ChangeType = "put"
+	ChangeDelete   ChangeType = "delete"
+	ChangeUndelete ChangeType = "undelete"
+	ChangeDestroy  ChangeType = "destroy"
+	ChangeMetadata ChangeType = "metadata"
+)
+
+// Change ist ein Eintrag im Change-Feed. Revision ist global und streng monoton steigend.
+type Change struct {
+	Revision int64
+	Key      string
+	Type     ChangeType
+	// Version der betroffenen Secret-Version (0 bei Metadaten-Änderungen)
+	Version   int64
+	CreatedAt string
+	CreatedBy string
+}
+
+// ChangeFeed ist eine Seite des Change-Feeds
+type ChangeFeed struct {
+	Changes []Change
+	// Revision: bis hierhin ist der Feed gelesen -> beim nächsten Aufruf als since verwenden
+	Revision int64
+	// More: nach Revision gibt es weitere (passende) Änderungen
+	More bool
+}
+
+// changeTypeFor leitet den Typ einer neuen Version ab, wenn der Builder keinen gesetzt hat
+func changeTypeFor(nv newVersion) ChangeType {
+	switch {
+	case nv.Change != "":
+		return nv.Change
+	case nv.Deleted:
+		return ChangeDelete
+	default:
+		return ChangePut
+	}
+}
@@ -0,0 +1,144 @@
+package service
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+	"time"
+)
+
+type DestroyOptions struct {
+	Key string
+	// Versions, die vernichtet werden sollen. Leer + All=true => alle Versionen des Keys.
+	Versions    []int64
+	All         bool
+	DestroyedBy string
+	DryRun      bool
+}
+
+type DestroyResult struct {
+	Matched   int
+	Destroyed int
+}
+
+// DestroyVersions vernichtet Secret-Versionen unwiderruflich (Crypto-Shredding).
+//
+// enc=1: wrapped_dek/wrap_nonce werden geleert -> DEK ist weg, Ciphertext nicht mehr entschlüsselbar.
+// enc=0: value wird überschrieben.
+// Die Zeile bleibt als auditierbarer Marker (destroyed=1, destroyed_at, destroyed_by) erhalten.
+// Existiert der Key gar nicht, kommt ErrNotFound. Wird von DestroySecretVersions und der CLI (glass destroy) genutzt.
+func DestroyVersions(ctx context.Context, db *sql.DB, opt DestroyOptions) (DestroyResult, error) {
+	if db == nil {
+		return DestroyResult{}, fmt.Errorf("db is nil")
+	}
+	if strings.TrimSpace(opt.Key) == "" {
+		return DestroyResult{}, fmt.Errorf("Key is empty")
+	}
+	if opt.All == (len(opt.Versions) > 0) {
+		return DestroyResult{}, fmt.Errorf("exactly one of Versions or All must be set")
+	}
+	if opt.DestroyedBy == "" {
+		opt.DestroyedBy = "unknown"
+	}
+
+	where := `key = ? AND destroyed = 0 AND deleted = 0`
+	args := []any{opt.Key}
+	if !opt.All {
+		ph := make([]string, 0, len(opt.Versions))
+		for _, v := range opt.Versions {
+			ph = append(ph, "?")
+			args = append(args, v)
+		}
+		where += ` AND version IN (` + strings.Join(ph, ",") + `)`
+	}
+
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return DestroyResult{}, err
+	}
+
+	var exists int
+	if err := tx.QueryRowContext(ctx, `SELECT COUNT(*) FROM secrets WHERE key = ?`, opt.Key).Scan(&exists); err != nil {
+		_ = tx.Rollback()
+		return DestroyResult{}, err
+	}
+	if exists == 0 {
+		_ = tx.Rollback()
+		return DestroyResult{}, ErrNotFound
+	}
+
+	var res DestroyResult
+	versions, err := matchedVersions(ctx, tx, where, args)
+	if err != nil {
+		_ = tx.Rollback()
+		return DestroyResult{}, err
+	}
+	res.Matched = len(versions)
+
+	// Dry-run: just report
+	if opt.DryRun {
+		_ = tx.Rollback()
+		return res, nil
+	}
+
+	now := time.Now().UTC().Format(time.RFC3339Nano)
+	upd := `
+UPDATE secrets
+SET value = CASE WHEN enc = 1 THEN value ELSE '' END,
+    value_nonce = CASE WHEN enc = 1 THEN value_nonce ELSE '' END,
+    wrapped_dek = '',
+    wrap_nonce = '',
+    destroyed = 1,
+    destroyed_at = ?,
+    destroyed_by = ?
+WHERE ` + where
+
+	r, err := tx.ExecContext(ctx, upd, append([]any{now, opt.DestroyedBy}, args...)...)
+	if err != nil {
+		_ = tx.Rollback()
+		return res, err
+	}
+	n, err := r.RowsAffected()
+	if err != nil {
+		_ = tx.Rollback()
+		return res, err
+	}
+	res.Destroyed = int(n)
+
+	// Change-Feed: ein Eintrag pro vernichteter Version
+	for _, v := range versions {
+		if err := recordChange(ctx, tx, opt.Key, ChangeDestroy, v, opt.DestroyedBy); err != nil {
+			_ = tx.Rollback()
+			return res, err
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		_ = tx.Rollback()
+		return res, err
+	}
+
+	// Alte Seiten aus dem WAL ins Hauptfile schreiben und WAL leeren (secure_delete nullt die Seiten dort)
+	_, _ = db.ExecContext(ctx, `PRAGMA wal_checkpoint(TRUNCATE);`)
+
+	return res, nil
+}
+
+func matchedVersions(ctx context.Context, tx *sql.Tx, where string, args []any) ([]int64, error) {
+	rows, err := tx.QueryContext(ctx, `SELECT version FROM secrets WHERE `+where+` ORDER BY version`, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var out []int64
+	for rows.Next() {
+		var v int64
+		if err := rows.Scan(&v); err != nil {
+			return nil, err
+		}
+		out = append(out, v)
+	}
+	return out, rows.Err()
+}
@@ -0,0 +1,69 @@
+package service
+
+import (
+	"context"
+	"log/slog"
+	"time"
+
+	"github.com/timgst1/glass/internal/authn"
+)
+
+// expiresAtLayout ist fix breit, damit expires_at in SQLite lexikografisch vergleichbar ist
+const expiresAtLayout = "2006-01-02T15:04:05.000Z"
+
+func formatExpiresAt(t time.Time) string {
+	if t.IsZero() {
+		return ""
+	}
+	return t.UTC().Format(expiresAtLayout)
+}
+
+// isExpired: leeres expires_at = läuft nie ab
+func isExpired(expiresAt string, now time.Time) bool {
+	if expiresAt == "" {
+		return false
+	}
+	t, err := time.Parse(time.RFC3339Nano, expiresAt)
+	if err != nil {
+		return false
+	}
+	return !now.Before(t)
+}
+
+// ExpiryReaper wird von Backends implementiert, die abgelaufene Secrets tombstonen können
+type ExpiryReaper interface {
+	// ReapExpired schreibt für jeden abgelaufenen Key einen Tombstone und liefert die Anzahl
+	ReapExpired(ctx context.Context, now time.Time) (int, error)
+}
+
+// StartExpiryReaper tombstoned abgelaufene Secrets periodisch als Subject system:reaper
+func StartExpiryReaper(ctx context.Context, r ExpiryReaper, interval time.Duration) {
+	if interval <= 0 {
+		interval = time.Minute
+	}
+	log := slog.Default()
+	ctx = authn.WithSubject(ctx, authn.Subject{Kind: "system", Name: "reaper"})
+
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				n, err := r.ReapExpired(ctx, time.Now())
+				if err != nil {
+					if ctx.Err() == nil {
+						log.Error("expiry reaper failed", "err", err)
+					}
+					continue
+				}
+				if n > 0 {
+					log.Info("expired secrets tombstoned", "count", n)
+				}
+			}
+		}
+	}()
+}
@@ -0,0 +1,43 @@
+package service
+
+import (
+	"sort"
+	"strings"
+)
+
+// KeyListing ist eine Key-Übersicht ohne Werte (S3-Style mit Delimiter)
+type KeyListing struct {
+	// Keys direkt unterhalb des Prefix (nur Metadaten, keine Werte)
+	Keys []SecretMeta
+	// Prefixes: "Ordner" unterhalb des Prefix, jeweils inkl. Delimiter am Ende
+	Prefixes []string
+}
+
+// groupByDelimiter fasst Keys, die nach dem Prefix noch den Delimiter enthalten, zu CommonPrefixes zusammen.
+// metas muss nach Key sortiert sein; delimiter "" = keine Gruppierung.
+func groupByDelimiter(prefix, delimiter string, metas []SecretMeta) KeyListing {
+	out := KeyListing{Keys: []SecretMeta{}, Prefixes: []string{}}
+	seen := map[string]bool{}
+
+	for _, m := range metas {
+		// nur list auf den Prefix wurde geprüft: was nicht darunter liegt, darf nie in der Antwort landen
+		if !strings.HasPrefix(m.Key, prefix) {
+			continue
+		}
+		if delimiter != "" {
+			rest := strings.TrimPrefix(m.Key, prefix)
+			if i := strings.Index(rest, delimiter); i >= 0 {
+				p := prefix + rest[:i+len(delimiter)]
+				if !seen[p] {
+					seen[p] = true
+					out.Prefixes = append(out.Prefixes, p)
+				}
+				continue
+			}
+		}
+		out.Keys = append(out.Keys, m)
+	}
+
+	sort.Strings(out.Prefixes)
+	return out
+}
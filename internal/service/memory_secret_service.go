@@ -2,107 +2,454 @@ package service
 
 import (
 	"context"
+	"fmt"
 	"sort"
 	"strings"
 	"sync"
 	"time"
-
-	"github.com/timgst1/glass/internal/authn"
 )
 
 type entry struct {
-	Value     string
-	Version   int64
-	CreatedAt string
-	CreatedBy string
+	Value       string
+	Version     int64
+	CreatedAt   string
+	CreatedBy   string
+	Deleted     bool
+	Destroyed   bool
+	ExpiresAt   string
+	Binary      bool
+	ContentType string
 }
 
 type MemorySecretService struct {
 	mu sync.RWMutex
-	m  map[string]entry
+	// key -> alle Versionen, aufsteigend sortiert
+	m map[string][]entry
+	// key -> Metadaten (unabhängig von Versionen)
+	md map[string]KeyMetadata
+	// Change-Feed, aufsteigend nach Revision
+	changes []Change
 }
 
 func NewMemorySecretService(seed map[string]string) *MemorySecretService {
 	now := time.Now().UTC().Format(time.RFC3339Nano)
-	m := map[string]entry{}
+	m := map[string][]entry{}
 	for k, v := range seed {
-		m[k] = entry{Value: v, Version: 1, CreatedAt: now, CreatedBy: "seed"}
+		m[k] = []entry{{Value: v, Version: 1, CreatedAt: now, CreatedBy: "seed"}}
 	}
-	return &MemorySecretService{m: m}
+	return &MemorySecretService{m: m, md: map[string]KeyMetadata{}}
+}
+
+func (s *MemorySecretService) latest(key string) (entry, bool) {
+	vs := s.m[key]
+	if len(vs) == 0 {
+		return entry{}, false
+	}
+	return vs[len(vs)-1], true
 }
 
 func (s *MemorySecretService) GetSecret(ctx context.Context, key string) (string, error) {
+	it, err := s.GetSecretVersion(ctx, key, 0)
+	if err != nil {
+		return "", err
+	}
+	return it.Value, nil
+}
+
+func (s *MemorySecretService) GetSecretVersion(ctx context.Context, key string, version int64) (SecretItem, error) {
 	s.mu.RLock()
 	defer s.mu.RUnlock()
 
-	e, ok := s.m[key]
-	if !ok {
-		return "", ErrNotFound
+	var (
+		e     entry
+		found bool
+	)
+	if version == 0 {
+		e, found = s.latest(key)
+	} else {
+		for _, c := range s.m[key] {
+			if c.Version == version {
+				e, found = c, true
+				break
+			}
+		}
 	}
-	return e.Value, nil
+	if !found || e.Deleted || isExpired(e.ExpiresAt, time.Now()) {
+		return SecretItem{}, ErrNotFound
+	}
+	if e.Destroyed {
+		return SecretItem{}, ErrDestroyed
+	}
+	return SecretItem{
+		Key:         key,
+		Value:       e.Value,
+		Version:     e.Version,
+		CreatedAt:   e.CreatedAt,
+		CreatedBy:   e.CreatedBy,
+		ExpiresAt:   e.ExpiresAt,
+		Binary:      e.Binary,
+		ContentType: e.ContentType,
+	}, nil
 }
 
 func (s *MemorySecretService) GetSecretMeta(ctx context.Context, key string) (SecretMeta, error) {
 	s.mu.RLock()
 	defer s.mu.RUnlock()
 
-	e, ok := s.m[key]
-	if !ok {
+	e, ok := s.latest(key)
+	if !ok || e.Deleted {
 		return SecretMeta{}, ErrNotFound
 	}
 	return SecretMeta{
-		Key:       key,
-		Version:   e.Version,
-		CreatedAt: e.CreatedAt,
-		CreatedBy: e.CreatedBy,
+		Key:         key,
+		Version:     e.Version,
+		CreatedAt:   e.CreatedAt,
+		CreatedBy:   e.CreatedBy,
+		Destroyed:   e.Destroyed,
+		ExpiresAt:   e.ExpiresAt,
+		Binary:      e.Binary,
+		ContentType: e.ContentType,
+		Metadata:    copyMetadata(s.md[key]),
 	}, nil
 }
 
-func (s *MemorySecretService) ListSecrets(ctx context.Context, prefix string) ([]SecretItem, error) {
+func (s *MemorySecretService) ListSecretVersions(ctx context.Context, key string, limit int, before int64) ([]SecretMeta, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	vs := s.m[key]
+	if len(vs) == 0 {
+		return nil, ErrNotFound
+	}
+
+	out := []SecretMeta{}
+	for i := len(vs) - 1; i >= 0; i-- {
+		e := vs[i]
+		if before > 0 && e.Version >= before {
+			continue
+		}
+		if limit > 0 && len(out) >= limit {
+			break
+		}
+		out = append(out, SecretMeta{
+			Key:         key,
+			Version:     e.Version,
+			CreatedAt:   e.CreatedAt,
+			CreatedBy:   e.CreatedBy,
+			Deleted:     e.Deleted,
+			Destroyed:   e.Destroyed,
+			ExpiresAt:   e.ExpiresAt,
+			Binary:      e.Binary,
+			ContentType: e.ContentType,
+		})
+	}
+	return out, nil
+}
+
+func (s *MemorySecretService) ListSecrets(ctx context.Context, prefix string, opts ...ListOption) ([]SecretItem, error) {
+	o := applyListOptions(opts)
+
 	s.mu.RLock()
 	defer s.mu.RUnlock()
 
 	keys := make([]string, 0, len(s.m))
 	for k := range s.m {
-		if strings.HasPrefix(k, prefix) {
+		if strings.HasPrefix(k, prefix) && k > o.After {
 			keys = append(keys, k)
 		}
 	}
 	sort.Strings(keys)
 
+	now := time.Now()
 	items := make([]SecretItem, 0, len(keys))
 	for _, k := range keys {
-		e := s.m[k]
+		e, ok := s.latest(k)
+		if !ok || e.Deleted || e.Destroyed || isExpired(e.ExpiresAt, now) {
+			continue
+		}
+		md := s.md[k]
+		if !o.LabelSelector.Matches(md.Labels) {
+			continue
+		}
 		items = append(items, SecretItem{
-			Key:       k,
-			Value:     e.Value,
-			Version:   e.Version,
-			CreatedAt: e.CreatedAt,
-			CreatedBy: e.CreatedBy,
+			Key:         k,
+			Value:       e.Value,
+			Version:     e.Version,
+			CreatedAt:   e.CreatedAt,
+			CreatedBy:   e.CreatedBy,
+			ExpiresAt:   e.ExpiresAt,
+			Binary:      e.Binary,
+			ContentType: e.ContentType,
+			Metadata:    copyMetadata(md),
 		})
+		if o.Limit > 0 && len(items) == o.Limit {
+			break
+		}
 	}
 	return items, nil
 }
 
-func (s *MemorySecretService) PutSecret(ctx context.Context, key, value string) (int64, error) {
+func (s *MemorySecretService) ListKeys(ctx context.Context, prefix, delimiter string) (KeyListing, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	keys := make([]string, 0, len(s.m))
+	for k := range s.m {
+		if strings.HasPrefix(k, prefix) {
+			keys = append(keys, k)
+		}
+	}
+	sort.Strings(keys)
+
+	now := time.Now()
+	var metas []SecretMeta
+	for _, k := range keys {
+		e, ok := s.latest(k)
+		if !ok || e.Deleted || e.Destroyed || isExpired(e.ExpiresAt, now) {
+			continue
+		}
+		metas = append(metas, SecretMeta{
+			Key:         k,
+			Version:     e.Version,
+			CreatedAt:   e.CreatedAt,
+			CreatedBy:   e.CreatedBy,
+			ExpiresAt:   e.ExpiresAt,
+			Binary:      e.Binary,
+			ContentType: e.ContentType,
+		})
+	}
+	return groupByDelimiter(prefix, delimiter, metas), nil
+}
+
+func (s *MemorySecretService) UpdateSecretMetadata(ctx context.Context, key string, patch MetadataPatch) (KeyMetadata, error) {
 	s.mu.Lock()
 	defer s.mu.Unlock()
 
-	sub, _ := authn.SubjectFromContext(ctx)
-	createdBy := sub.Kind + ":" + sub.Name
-	if createdBy == ":" {
-		createdBy = "unknown"
+	e, ok := s.latest(key)
+	if !ok || e.Deleted {
+		return KeyMetadata{}, ErrNotFound
+	}
+
+	next, err := applyMetadataPatch(s.md[key], patch)
+	if err != nil {
+		return KeyMetadata{}, err
+	}
+	next.UpdatedAt = time.Now().UTC().Format(time.RFC3339Nano)
+	next.UpdatedBy = subjectString(ctx)
+	s.md[key] = next
+	s.recordLocked(key, ChangeMetadata, 0, next.UpdatedBy)
+	return copyMetadata(next), nil
+}
+
+// copyMetadata verhindert, dass Aufrufer die intern gespeicherten Maps verändern
+func copyMetadata(md KeyMetadata) KeyMetadata {
+	md.Labels = mergeStringMap(md.Labels, nil)
+	md.Annotations = mergeStringMap(md.Annotations, nil)
+	return md
+}
+
+func (s *MemorySecretService) PutSecret(ctx context.Context, key, value string, opts ...PutOption) (int64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	o := applyPutOptions(opts)
+	if err := checkExpectedVersion(key, o, s.liveVersion(key)); err != nil {
+		return 0, err
+	}
+	return s.appendLocked(ctx, key, putEntry(value, o), ChangePut), nil
+}
+
+func (s *MemorySecretService) PutSecrets(ctx context.Context, writes []SecretWrite) ([]int64, error) {
+	if err := validateBatch(writes); err != nil {
+		return nil, err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	// erst alle Bedingungen prüfen, dann schreiben: alles oder nichts
+	opts := make([]PutOptions, len(writes))
+	for i, w := range writes {
+		opts[i] = applyPutOptions(w.Opts)
+		if err := checkExpectedVersion(w.Key, opts[i], s.liveVersion(w.Key)); err != nil {
+			return nil, err
+		}
 	}
-	now := time.Now().UTC().Format(time.RFC3339Nano)
 
-	e := s.m[key]
-	e.Version++
-	if e.Version == 1 {
-		e.Version = 1
+	out := make([]int64, len(writes))
+	for i, w := range writes {
+		out[i] = s.appendLocked(ctx, w.Key, putEntry(w.Value, opts[i]), ChangePut)
+	}
+	return out, nil
+}
+
+func putEntry(value string, o PutOptions) entry {
+	return entry{
+		Value:       value,
+		ExpiresAt:   formatExpiresAt(o.ExpiresAt),
+		Binary:      o.Binary,
+		ContentType: o.ContentType,
+	}
+}
+
+// liveVersion liefert die aktuelle Version, oder 0 wenn der Key nicht existiert, gelöscht oder abgelaufen ist
+func (s *MemorySecretService) liveVersion(key string) int64 {
+	e, ok := s.latest(key)
+	if !ok || e.Deleted || isExpired(e.ExpiresAt, time.Now()) {
+		return 0
+	}
+	return e.Version
+}
+
+func (s *MemorySecretService) DeleteSecret(ctx context.Context, key string) (int64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	cur, ok := s.latest(key)
+	if !ok || cur.Deleted {
+		return 0, ErrNotFound
+	}
+	return s.appendLocked(ctx, key, entry{Deleted: true}, ChangeDelete), nil
+}
+
+func (s *MemorySecretService) UndeleteSecret(ctx context.Context, key string) (int64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	cur, ok := s.latest(key)
+	if !ok {
+		return 0, ErrNotFound
+	}
+	if !cur.Deleted {
+		return 0, fmt.Errorf("%w: secret is not deleted", ErrConflict)
+	}
+
+	vs := s.m[key]
+	for i := len(vs) - 1; i >= 0; i-- {
+		if vs[i].Deleted {
+			continue
+		}
+		// wie SQLite (getItem): abgelaufene Versionen kommen nicht zurück
+		if isExpired(vs[i].ExpiresAt, time.Now()) {
+			return 0, ErrNotFound
+		}
+		if vs[i].Destroyed {
+			return 0, ErrDestroyed
+		}
+		return s.appendLocked(ctx, key, entry{Value: vs[i].Value, ExpiresAt: vs[i].ExpiresAt, Binary: vs[i].Binary, ContentType: vs[i].ContentType}, ChangeUndelete), nil
+	}
+	return 0, ErrNotFound
+}
+
+func (s *MemorySecretService) DestroySecretVersions(ctx context.Context, key string, versions []int64) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	vs := s.m[key]
+	if len(vs) == 0 {
+		return 0, ErrNotFound
+	}
+
+	want := map[int64]bool{}
+	for _, v := range versions {
+		want[v] = true
+	}
+
+	n := 0
+	for i := range vs {
+		if vs[i].Deleted || vs[i].Destroyed {
+			continue
+		}
+		if len(versions) > 0 && !want[vs[i].Version] {
+			continue
+		}
+		vs[i].Value = ""
+		vs[i].Destroyed = true
+		s.recordLocked(key, ChangeDestroy, vs[i].Version, subjectString(ctx))
+		n++
+	}
+	return n, nil
+}
+
+func (s *MemorySecretService) ReapExpired(ctx context.Context, now time.Time) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	n := 0
+	for k := range s.m {
+		e, ok := s.latest(k)
+		if !ok || e.Deleted || !isExpired(e.ExpiresAt, now) {
+			continue
+		}
+		s.appendLocked(ctx, k, entry{Deleted: true}, ChangeDelete)
+		n++
+	}
+	return n, nil
+}
+
+// appendLocked hängt e als nächste Version an; s.mu muss gehalten werden
+func (s *MemorySecretService) appendLocked(ctx context.Context, key string, e entry, typ ChangeType) int64 {
+	e.Version = 1
+	if cur, ok := s.latest(key); ok {
+		e.Version = cur.Version + 1
+	}
+	e.CreatedAt = time.Now().UTC().Format(time.RFC3339Nano)
+	e.CreatedBy = subjectString(ctx)
+	s.m[key] = append(s.m[key], e)
+	s.recordLocked(key, typ, e.Version, e.CreatedBy)
+	return e.Version
+}
+
+// recordLocked hängt einen Eintrag an den Change-Feed an; s.mu muss gehalten werden
+func (s *MemorySecretService) recordLocked(key string, typ ChangeType, version int64, createdBy string) {
+	s.changes = append(s.changes, Change{
+		Revision:  int64(len(s.changes)) + 1,
+		Key:       key,
+		Type:      typ,
+		Version:   version,
+		CreatedAt: time.Now().UTC().Format(time.RFC3339Nano),
+		CreatedBy: createdBy,
+	})
+}
+
+func (s *MemorySecretService) ListChanges(ctx context.Context, prefix string, since int64, limit int) (ChangeFeed, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	head := int64(len(s.changes))
+	if since < 0 {
+		since = head
+	}
+	feed := ChangeFeed{Changes: []Change{}, Revision: max(since, head)}
+	// Revision n liegt an Index n-1
+	for i := since; i < head; i++ {
+		c := s.changes[i]
+		if !strings.HasPrefix(c.Key, prefix) {
+			continue
+		}
+		if limit > 0 && len(feed.Changes) == limit {
+			feed.More = true
+			feed.Revision = feed.Changes[limit-1].Revision
+			break
+		}
+		feed.Changes = append(feed.Changes, c)
+	}
+	return feed, nil
+}
+
+// Stats zählt lebende Keys und nicht vernichtete Versionen (für /metrics)
+func (s *MemorySecretService) Stats(ctx context.Context) (keys, versions int64, err error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	for _, vs := range s.m {
+		if len(vs) > 0 && !vs[len(vs)-1].Deleted && !vs[len(vs)-1].Destroyed {
+			keys++
+		}
+		for _, e := range vs {
+			if !e.Destroyed {
+				versions++
+			}
+		}
 	}
-	e.Value = value
-	e.CreatedAt = now
-	e.CreatedBy = createdBy
-	s.m[key] = e
-	return e.Version, nil
+	return keys, versions, nil
 }
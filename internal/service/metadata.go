@@ -0,0 +1,132 @@
+package service
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/timgst1/glass/internal/labels"
+)
+
+const (
+	maxDescriptionLen     = 1024
+	maxOwnerLen           = 256
+	maxAnnotationValueLen = 4096
+)
+
+// KeyMetadata hängt am Key (nicht an einer Version) und überlebt damit neue Versionen, Delete und Undelete.
+type KeyMetadata struct {
+	Labels      map[string]string
+	Annotations map[string]string
+	Description string
+	Owner       string
+	UpdatedAt   string
+	UpdatedBy   string
+}
+
+// MetadataPatch: JSON-Merge-Patch-Semantik.
+// Labels/Annotations: Wert nil = Eintrag entfernen; Description/Owner: nil = unverändert, "" = leeren.
+type MetadataPatch struct {
+	Labels      map[string]*string
+	Annotations map[string]*string
+	Description *string
+	Owner       *string
+}
+
+// ListOptions steuert optionales Verhalten von ListSecrets
+type ListOptions struct {
+	// LabelSelector: nur Keys, deren Labels matchen (leer = alle)
+	LabelSelector labels.Selector
+	// Keyset-Pagination: nur Keys > After (sortiert), höchstens Limit Einträge (0 = unbegrenzt)
+	After string
+	Limit int
+}
+
+type ListOption func(*ListOptions)
+
+func WithLabelSelector(sel labels.Selector) ListOption {
+	return func(o *ListOptions) { o.LabelSelector = sel }
+}
+
+func WithPage(after string, limit int) ListOption {
+	return func(o *ListOptions) {
+		o.After = after
+		o.Limit = limit
+	}
+}
+
+func applyListOptions(opts []ListOption) ListOptions {
+	var o ListOptions
+	for _, fn := range opts {
+		fn(&o)
+	}
+	return o
+}
+
+// applyMetadataPatch validiert p und liefert die neuen Metadaten (cur bleibt unverändert)
+func applyMetadataPatch(cur KeyMetadata, p MetadataPatch) (KeyMetadata, error) {
+	out := KeyMetadata{
+		Labels:      mergeStringMap(cur.Labels, p.Labels),
+		Annotations: mergeStringMap(cur.Annotations, p.Annotations),
+		Description: cur.Description,
+		Owner:       cur.Owner,
+	}
+	if p.Description != nil {
+		out.Description = *p.Description
+	}
+	if p.Owner != nil {
+		out.Owner = *p.Owner
+	}
+
+	for _, k := range sortedKeys(p.Labels) {
+		if err := labels.ValidateKey(k); err != nil {
+			return KeyMetadata{}, fmt.Errorf("%w: %v", ErrInvalid, err)
+		}
+		if v := p.Labels[k]; v != nil {
+			if err := labels.ValidateValue(*v); err != nil {
+				return KeyMetadata{}, fmt.Errorf("%w: %v", ErrInvalid, err)
+			}
+		}
+	}
+	for _, k := range sortedKeys(p.Annotations) {
+		if err := labels.ValidateKey(k); err != nil {
+			return KeyMetadata{}, fmt.Errorf("%w: annotation: %v", ErrInvalid, err)
+		}
+		if v := p.Annotations[k]; v != nil && len(*v) > maxAnnotationValueLen {
+			return KeyMetadata{}, fmt.Errorf("%w: annotation %q is longer than %d bytes", ErrInvalid, k, maxAnnotationValueLen)
+		}
+	}
+	if len(out.Description) > maxDescriptionLen {
+		return KeyMetadata{}, fmt.Errorf("%w: description is longer than %d bytes", ErrInvalid, maxDescriptionLen)
+	}
+	if len(out.Owner) > maxOwnerLen {
+		return KeyMetadata{}, fmt.Errorf("%w: owner is longer than %d bytes", ErrInvalid, maxOwnerLen)
+	}
+	return out, nil
+}
+
+func mergeStringMap(cur map[string]string, patch map[string]*string) map[string]string {
+	out := make(map[string]string, len(cur)+len(patch))
+	for k, v := range cur {
+		out[k] = v
+	}
+	for k, v := range patch {
+		if v == nil {
+			delete(out, k)
+			continue
+		}
+		out[k] = *v
+	}
+	if len(out) == 0 {
+		return nil
+	}
+	return out
+}
+
+func sortedKeys(m map[string]*string) []string {
+	ks := make([]string, 0, len(m))
+	for k := range m {
+		ks = append(ks, k)
+	}
+	sort.Strings(ks)
+	return ks
+}
@@ -0,0 +1,104 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// maxReferenceDepth begrenzt verschachtelte Referenzen (a -> b -> c ...)
+const maxReferenceDepth = 8
+
+// ${secret:key} wird ersetzt, $${secret:key} ist ein Escape und ergibt das Literal ${secret:key}
+var referencePattern = regexp.MustCompile(`\$?\$\{secret:([^}]*)\}`)
+
+// ResolvedRef ist eine beim Auflösen gelesene Referenz samt Version
+type ResolvedRef struct {
+	Key     string
+	Version int64
+}
+
+// ResolveReferences ersetzt ${secret:...}-Referenzen in value durch die aktuellen Werte der referenzierten Keys.
+// Gelesen wird über svc – mit einem SecuredSecretService wird also jede Referenz einzeln auf read geprüft.
+// key ist der Key, zu dem value gehört (für die Zykluserkennung).
+func ResolveReferences(ctx context.Context, svc SecretService, key, value string) (string, []ResolvedRef, error) {
+	r := &resolver{svc: svc, done: map[string]string{}}
+	out, err := r.resolve(ctx, value, []string{normalizeKey(key)})
+	if err != nil {
+		return "", nil, err
+	}
+	return out, r.refs, nil
+}
+
+type resolver struct {
+	svc SecretService
+	// done: bereits vollständig aufgelöste Keys (jeder Key wird nur einmal gelesen)
+	done map[string]string
+	refs []ResolvedRef
+}
+
+func (r *resolver) resolve(ctx context.Context, value string, stack []string) (string, error) {
+	matches := referencePattern.FindAllStringSubmatchIndex(value, -1)
+	if len(matches) == 0 {
+		return value, nil
+	}
+
+	var b strings.Builder
+	last := 0
+	for _, m := range matches {
+		b.WriteString(value[last:m[0]])
+		last = m[1]
+
+		if strings.HasPrefix(value[m[0]:], "$$") {
+			b.WriteString(value[m[0]+1 : m[1]])
+			continue
+		}
+
+		ref := normalizeKey(value[m[2]:m[3]])
+		v, err := r.lookup(ctx, ref, stack)
+		if err != nil {
+			return "", err
+		}
+		b.WriteString(v)
+	}
+	b.WriteString(value[last:])
+	return b.String(), nil
+}
+
+func (r *resolver) lookup(ctx context.Context, ref string, stack []string) (string, error) {
+	if ref == "" {
+		return "", fmt.Errorf("%w: empty reference", ErrUnresolvable)
+	}
+	if v, ok := r.done[ref]; ok {
+		return v, nil
+	}
+	for _, k := range stack {
+		if k == ref {
+			return "", fmt.Errorf("%w: reference cycle %s", ErrUnresolvable, strings.Join(append(stack, ref), " -> "))
+		}
+	}
+	if len(stack) > maxReferenceDepth {
+		return "", fmt.Errorf("%w: references nested deeper than %d", ErrUnresolvable, maxReferenceDepth)
+	}
+
+	it, err := r.svc.GetSecretVersion(ctx, ref, 0)
+	if err != nil {
+		if errors.Is(err, ErrNotFound) || errors.Is(err, ErrDestroyed) {
+			return "", fmt.Errorf("%w: %q not found", ErrUnresolvable, ref)
+		}
+		return "", fmt.Errorf("reference %q: %w", ref, err)
+	}
+	if it.Binary {
+		return "", fmt.Errorf("%w: %q is binary", ErrUnresolvable, ref)
+	}
+
+	v, err := r.resolve(ctx, it.Value, append(stack, ref))
+	if err != nil {
+		return "", err
+	}
+	r.done[ref] = v
+	r.refs = append(r.refs, ResolvedRef{Key: ref, Version: it.Version})
+	return v, nil
+}
@@ -3,11 +3,20 @@ package service
 import (
 	"context"
 	"errors"
+	"fmt"
+	"time"
+
+	"github.com/timgst1/glass/internal/authn"
 )
 
 var (
 	ErrNotFound  = errors.New("secret not found")
 	ErrForbidden = errors.New("forbidden")
+	ErrConflict  = errors.New("conflict")
+	ErrDestroyed = errors.New("secret version destroyed")
+	ErrInvalid   = errors.New("invalid argument")
+	// ErrUnresolvable: eine ${secret:...}-Referenz kann nicht aufgelöst werden (fehlt, Zyklus, binär)
+	ErrUnresolvable = errors.New("unresolvable reference")
 )
 
 type SecretMeta struct {
@@ -15,6 +24,17 @@ type SecretMeta struct {
 	Version   int64
 	CreatedAt string
 	CreatedBy string
+	// Deleted markiert eine Tombstone-Version (nur in der Versionshistorie sichtbar)
+	Deleted bool
+	// Destroyed markiert eine unwiderruflich vernichtete Version
+	Destroyed bool
+	// ExpiresAt (RFC3339, leer = kein Ablauf)
+	ExpiresAt string
+	// Binary: Wert ist ein Binär-Blob (API liefert ihn base64-kodiert)
+	Binary      bool
+	ContentType string
+	// Metadata gilt für den Key, nicht für die Version (nur bei GetSecretMeta gesetzt)
+	Metadata KeyMetadata
 }
 
 type SecretItem struct {
@@ -23,12 +43,143 @@ type SecretItem struct {
 	Version   int64
 	CreatedAt string
 	CreatedBy string
+	ExpiresAt string
+	// Binary: Value enthält rohe Bytes (kein UTF-8-Text)
+	Binary      bool
+	ContentType string
+	// Metadata ist nur bei ListSecrets gesetzt
+	Metadata KeyMetadata
 }
 
 type SecretService interface {
 	GetSecret(ctx context.Context, key string) (string, error)
-	PutSecret(ctx context.Context, key, value string) (int64, error)
+	PutSecret(ctx context.Context, key, value string, opts ...PutOption) (int64, error)
 
 	GetSecretMeta(ctx context.Context, key string) (SecretMeta, error)
-	ListSecrets(ctx context.Context, prefix string) ([]SecretItem, error)
+	ListSecrets(ctx context.Context, prefix string, opts ...ListOption) ([]SecretItem, error)
+
+	// ListKeys liefert Keys unter prefix ohne Werte; mit delimiter werden tiefere Ebenen zu Prefixes zusammengefasst.
+	ListKeys(ctx context.Context, prefix, delimiter string) (KeyListing, error)
+
+	// UpdateSecretMetadata ändert Labels/Annotations/Description/Owner eines (lebenden) Keys.
+	UpdateSecretMetadata(ctx context.Context, key string, patch MetadataPatch) (KeyMetadata, error)
+
+	// GetSecretVersion liefert Wert + Metadaten einer bestimmten (auch älteren) Version.
+	// version 0 = neueste Version.
+	GetSecretVersion(ctx context.Context, key string, version int64) (SecretItem, error)
+	// ListSecretVersions liefert Versions-Metadaten absteigend nach Version.
+	// before > 0 liefert nur Versionen < before (Keyset-Pagination).
+	ListSecretVersions(ctx context.Context, key string, limit int, before int64) ([]SecretMeta, error)
+
+	// DeleteSecret schreibt eine Tombstone-Version und liefert deren Versionsnummer.
+	DeleteSecret(ctx context.Context, key string) (int64, error)
+	// UndeleteSecret stellt die letzte Version vor dem Tombstone als neue Version wieder her.
+	UndeleteSecret(ctx context.Context, key string) (int64, error)
+
+	// PutSecrets schreibt alle Einträge atomar (alles oder nichts) und liefert die neuen Versionen in Eingabereihenfolge.
+	PutSecrets(ctx context.Context, writes []SecretWrite) ([]int64, error)
+
+	// DestroySecretVersions vernichtet die angegebenen Versionen unwiderruflich
+	// (versions leer = alle Versionen) und liefert die Anzahl vernichteter Versionen.
+	DestroySecretVersions(ctx context.Context, key string, versions []int64) (int, error)
+
+	// ListChanges liefert Änderungen mit Revision > since für Keys unter prefix, aufsteigend nach Revision.
+	// since < 0 = ab der aktuellen Revision (leere Seite, nur Revision); limit <= 0 = unbegrenzt.
+	ListChanges(ctx context.Context, prefix string, since int64, limit int) (ChangeFeed, error)
+}
+
+// PutOptions steuert optionales Verhalten von PutSecret
+type PutOptions struct {
+	// ExpectedVersion: wenn gesetzt, muss die aktuelle Version exakt passen.
+	// 0 bedeutet "Key darf (lebend) nicht existieren" (create only).
+	ExpectedVersion *int64
+	// ExpiresAt: ab diesem Zeitpunkt gilt die Version als nicht mehr vorhanden (zero = kein Ablauf)
+	ExpiresAt time.Time
+	// Binary: value enthält rohe Bytes (z.B. Keystore, Keytab)
+	Binary bool
+	// ContentType wird nur gespeichert und mit ausgeliefert (z.B. "application/x-pkcs12")
+	ContentType string
+}
+
+type PutOption func(*PutOptions)
+
+func WithExpectedVersion(v int64) PutOption {
+	return func(o *PutOptions) { o.ExpectedVersion = &v }
+}
+
+func WithExpiresAt(t time.Time) PutOption {
+	return func(o *PutOptions) { o.ExpiresAt = t }
+}
+
+func WithBinary() PutOption {
+	return func(o *PutOptions) { o.Binary = true }
+}
+
+func WithContentType(ct string) PutOption {
+	return func(o *PutOptions) { o.ContentType = ct }
+}
+
+func applyPutOptions(opts []PutOption) PutOptions {
+	var o PutOptions
+	for _, fn := range opts {
+		fn(&o)
+	}
+	return o
+}
+
+// VersionConflictError wird geliefert, wenn ExpectedVersion nicht zur aktuellen Version passt.
+// Current ist 0, wenn der Key nicht (mehr) existiert.
+type VersionConflictError struct {
+	Key      string
+	Expected int64
+	Current  int64
+}
+
+func (e *VersionConflictError) Error() string {
+	return fmt.Sprintf("version conflict for %q: expected %d, current %d", e.Key, e.Expected, e.Current)
+}
+
+func (e *VersionConflictError) Unwrap() error { return ErrConflict }
+
+// checkExpectedVersion prüft ExpectedVersion gegen die aktuelle (lebende) Version
+func checkExpectedVersion(key string, o PutOptions, current int64) error {
+	if o.ExpectedVersion == nil || *o.ExpectedVersion == current {
+		return nil
+	}
+	return &VersionConflictError{Key: key, Expected: *o.ExpectedVersion, Current: current}
+}
+
+// SecretWrite ist ein Eintrag für PutSecrets
+type SecretWrite struct {
+	Key   string
+	Value string
+	Opts  []PutOption
+}
+
+// validateBatch: ein Key darf pro Batch nur einmal vorkommen
+func validateBatch(writes []SecretWrite) error {
+	if len(writes) == 0 {
+		return fmt.Errorf("%w: batch is empty", ErrInvalid)
+	}
+	seen := make(map[string]bool, len(writes))
+	for _, w := range writes {
+		if w.Key == "" {
+			return fmt.Errorf("%w: empty key in batch", ErrInvalid)
+		}
+		if seen[w.Key] {
+			return fmt.Errorf("%w: duplicate key %q in batch", ErrInvalid, w.Key)
+		}
+		seen[w.Key] = true
+	}
+	return nil
+}
+
+// subjectString liefert "kind:name" des Aufrufers für created_by/updated_by
+func subjectString(ctx context.Context) string {
+	sub, _ := authn.SubjectFromContext(ctx)
+	str := sub.Kind + ":" + sub.Name
+	if str == ":" {
+		return "unknown"
+	}
+	return str
 }
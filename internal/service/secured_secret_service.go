@@ -3,10 +3,16 @@ package service
 import (
 	"context"
 	"fmt"
+	"log/slog"
+	"slices"
 	"strings"
 
+	"github.com/timgst1/glass/internal/audit"
 	"github.com/timgst1/glass/internal/authn"
 	"github.com/timgst1/glass/internal/authz"
+	"github.com/timgst1/glass/internal/tracing"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
 )
 
 type SecuredSecretService struct {
@@ -18,6 +24,32 @@ func NewSecuredSecretService(inner SecretService, az authz.Authorizer) *SecuredS
 	return &SecuredSecretService{inner: inner, az: az}
 }
 
+// evaluate prüft eine Berechtigung und merkt die Entscheidung für das Audit-Log vor.
+// Reine Filter (z.B. read pro Item beim Listen) rufen s.az.Evaluate direkt auf (ein authz.Filter-Span pro Seite).
+func (s *SecuredSecretService) evaluate(ctx context.Context, sub authn.Subject, action, key string) authz.Decision {
+	_, span := tracing.Start(ctx, "authz.Evaluate", trace.WithAttributes(attribute.String("glass.authz.action", action)))
+	defer span.End()
+	span.SetAttributes(tracing.KeyAttrs(key)...)
+
+	dec := s.az.Evaluate(sub, action, key)
+	span.SetAttributes(attribute.Bool("glass.authz.allowed", dec.Allowed))
+	if !dec.Allowed {
+		// ohne Key: Logs sollen keine Secret-Namen sammeln (die stehen im Audit-Log)
+		slog.DebugContext(ctx, "authz denied", "action", action, "reason", dec.Reason)
+	}
+	audit.NoteDecision(ctx, action, key, dec)
+	return dec
+}
+
+// startFilter öffnet den Span für das read-Filtern einer Seite
+func startFilter(ctx context.Context, candidates int) trace.Span {
+	_, span := tracing.Start(ctx, "authz.Filter", trace.WithAttributes(
+		attribute.String("glass.authz.action", authz.ActionRead),
+		attribute.Int("glass.authz.candidates", candidates),
+	))
+	return span
+}
+
 func normalizeKey(k string) string {
 	k = strings.TrimSpace(k)
 	k = strings.TrimPrefix(k, "/")
@@ -38,7 +70,7 @@ func (s *SecuredSecretService) GetSecret(ctx context.Context, key string) (strin
 		return "", fmt.Errorf("%w: subject missing", ErrForbidden)
 	}
 
-	dec := s.az.Evaluate(sub, authz.ActionRead, key)
+	dec := s.evaluate(ctx, sub, authz.ActionRead, key)
 	if !dec.Allowed {
 		return "", fmt.Errorf("%w: %s", ErrForbidden, dec.Reason)
 	}
@@ -46,7 +78,7 @@ func (s *SecuredSecretService) GetSecret(ctx context.Context, key string) (strin
 	return s.inner.GetSecret(ctx, key)
 }
 
-func (s *SecuredSecretService) PutSecret(ctx context.Context, key, value string) (int64, error) {
+func (s *SecuredSecretService) PutSecret(ctx context.Context, key, value string, opts ...PutOption) (int64, error) {
 	key = normalizeKey(key)
 
 	sub, ok := authn.SubjectFromContext(ctx)
@@ -54,12 +86,32 @@ func (s *SecuredSecretService) PutSecret(ctx context.Context, key, value string)
 		return 0, fmt.Errorf("%w: subject missing", ErrForbidden)
 	}
 
-	dec := s.az.Evaluate(sub, authz.ActionWrite, key)
+	dec := s.evaluate(ctx, sub, authz.ActionWrite, key)
 	if !dec.Allowed {
 		return 0, fmt.Errorf("%w: %s", ErrForbidden, dec.Reason)
 	}
 
-	return s.inner.PutSecret(ctx, key, value)
+	return s.inner.PutSecret(ctx, key, value, opts...)
+}
+
+// PutSecrets prüft write für ALLE Keys, bevor irgendetwas geschrieben wird
+func (s *SecuredSecretService) PutSecrets(ctx context.Context, writes []SecretWrite) ([]int64, error) {
+	sub, ok := authn.SubjectFromContext(ctx)
+	if !ok {
+		return nil, fmt.Errorf("%w: subject missing", ErrForbidden)
+	}
+
+	norm := make([]SecretWrite, len(writes))
+	for i, w := range writes {
+		w.Key = normalizeKey(w.Key)
+		dec := s.evaluate(ctx, sub, authz.ActionWrite, w.Key)
+		if !dec.Allowed {
+			return nil, fmt.Errorf("%w: %s: %s", ErrForbidden, w.Key, dec.Reason)
+		}
+		norm[i] = w
+	}
+
+	return s.inner.PutSecrets(ctx, norm)
 }
 
 func (s *SecuredSecretService) GetSecretMeta(ctx context.Context, key string) (SecretMeta, error) {
@@ -71,7 +123,7 @@ func (s *SecuredSecretService) GetSecretMeta(ctx context.Context, key string) (S
 	}
 
 	// WICHTIG: AuthZ muss den gleichen key prüfen, der auch gelesen wird
-	dec := s.az.Evaluate(sub, authz.ActionRead, key)
+	dec := s.evaluate(ctx, sub, authz.ActionRead, key)
 	if !dec.Allowed {
 		return SecretMeta{}, fmt.Errorf("%w: %s", ErrForbidden, dec.Reason)
 	}
@@ -79,7 +131,7 @@ func (s *SecuredSecretService) GetSecretMeta(ctx context.Context, key string) (S
 	return s.inner.GetSecretMeta(ctx, key)
 }
 
-func (s *SecuredSecretService) ListSecrets(ctx context.Context, prefix string) ([]SecretItem, error) {
+func (s *SecuredSecretService) ListSecrets(ctx context.Context, prefix string, opts ...ListOption) ([]SecretItem, error) {
 	prefix = normalizePrefix(prefix)
 
 	sub, ok := authn.SubjectFromContext(ctx)
@@ -87,23 +139,193 @@ func (s *SecuredSecretService) ListSecrets(ctx context.Context, prefix string) (
 		return nil, fmt.Errorf("%w: subject missing", ErrForbidden)
 	}
 
-	dec := s.az.Evaluate(sub, authz.ActionList, prefix)
+	dec := s.evaluate(ctx, sub, authz.ActionList, prefix)
 	if !dec.Allowed {
 		return nil, fmt.Errorf("%w: %s", ErrForbidden, dec.Reason)
 	}
 
-	items, err := s.inner.ListSecrets(ctx, prefix)
-	if err != nil {
-		return nil, err
-	}
+	o := applyListOptions(opts)
 
-	// Serverseitiges Filtern: nur read-allowed Keys zurückgeben
-	out := make([]SecretItem, 0, len(items))
-	for _, it := range items {
-		rd := s.az.Evaluate(sub, authz.ActionRead, it.Key)
-		if rd.Allowed {
+	// Serverseitiges Filtern: nur read-allowed Keys zurückgeben.
+	// Bei Limit so lange nachladen, bis die Seite voll ist – sonst entstehen kurze oder leere Seiten.
+	out := []SecretItem{}
+	after := o.After
+	for {
+		items, err := s.inner.ListSecrets(ctx, prefix, append(slices.Clip(opts), WithPage(after, o.Limit))...)
+		if err != nil {
+			return nil, err
+		}
+		span := startFilter(ctx, len(items))
+		for _, it := range items {
+			rd := s.az.Evaluate(sub, authz.ActionRead, it.Key)
+			if !rd.Allowed {
+				continue
+			}
 			out = append(out, it)
+			if o.Limit > 0 && len(out) == o.Limit {
+				span.End()
+				return out, nil
+			}
+		}
+		span.End()
+		if o.Limit <= 0 || len(items) < o.Limit {
+			return out, nil
 		}
+		after = items[len(items)-1].Key
 	}
-	return out, nil
+}
+
+// ListChanges benötigt list auf den Prefix und liefert (wie ListSecrets) nur Änderungen an read-allowed Keys
+func (s *SecuredSecretService) ListChanges(ctx context.Context, prefix string, since int64, limit int) (ChangeFeed, error) {
+	prefix = normalizePrefix(prefix)
+
+	sub, ok := authn.SubjectFromContext(ctx)
+	if !ok {
+		return ChangeFeed{}, fmt.Errorf("%w: subject missing", ErrForbidden)
+	}
+
+	dec := s.evaluate(ctx, sub, authz.ActionList, prefix)
+	if !dec.Allowed {
+		return ChangeFeed{}, fmt.Errorf("%w: %s", ErrForbidden, dec.Reason)
+	}
+
+	// nachladen, bis die Seite voll ist; Revision zeigt auch hinter ausgefilterte Einträge
+	out := ChangeFeed{Changes: []Change{}}
+	for {
+		want := 0
+		if limit > 0 {
+			want = limit - len(out.Changes)
+		}
+		feed, err := s.inner.ListChanges(ctx, prefix, since, want)
+		if err != nil {
+			return ChangeFeed{}, err
+		}
+		span := startFilter(ctx, len(feed.Changes))
+		for _, c := range feed.Changes {
+			if s.az.Evaluate(sub, authz.ActionRead, c.Key).Allowed {
+				out.Changes = append(out.Changes, c)
+			}
+		}
+		span.End()
+		out.Revision, out.More = feed.Revision, feed.More
+		if !feed.More || (limit > 0 && len(out.Changes) == limit) {
+			return out, nil
+		}
+		since = feed.Revision
+	}
+}
+
+// ListKeys benötigt nur list auf den Prefix: Werte werden nicht geliefert, daher kein read-Filter pro Key
+func (s *SecuredSecretService) ListKeys(ctx context.Context, prefix, delimiter string) (KeyListing, error) {
+	prefix = normalizePrefix(prefix)
+
+	sub, ok := authn.SubjectFromContext(ctx)
+	if !ok {
+		return KeyListing{}, fmt.Errorf("%w: subject missing", ErrForbidden)
+	}
+
+	dec := s.evaluate(ctx, sub, authz.ActionList, prefix)
+	if !dec.Allowed {
+		return KeyListing{}, fmt.Errorf("%w: %s", ErrForbidden, dec.Reason)
+	}
+
+	return s.inner.ListKeys(ctx, prefix, delimiter)
+}
+
+// UpdateSecretMetadata benötigt write: Labels steuern z.B., welche Keys ESO synchronisiert
+func (s *SecuredSecretService) UpdateSecretMetadata(ctx context.Context, key string, patch MetadataPatch) (KeyMetadata, error) {
+	key = normalizeKey(key)
+
+	sub, ok := authn.SubjectFromContext(ctx)
+	if !ok {
+		return KeyMetadata{}, fmt.Errorf("%w: subject missing", ErrForbidden)
+	}
+
+	dec := s.evaluate(ctx, sub, authz.ActionWrite, key)
+	if !dec.Allowed {
+		return KeyMetadata{}, fmt.Errorf("%w: %s", ErrForbidden, dec.Reason)
+	}
+
+	return s.inner.UpdateSecretMetadata(ctx, key, patch)
+}
+
+func (s *SecuredSecretService) GetSecretVersion(ctx context.Context, key string, version int64) (SecretItem, error) {
+	key = normalizeKey(key)
+
+	sub, ok := authn.SubjectFromContext(ctx)
+	if !ok {
+		return SecretItem{}, fmt.Errorf("%w: subject missing", ErrForbidden)
+	}
+
+	dec := s.evaluate(ctx, sub, authz.ActionRead, key)
+	if !dec.Allowed {
+		return SecretItem{}, fmt.Errorf("%w: %s", ErrForbidden, dec.Reason)
+	}
+
+	return s.inner.GetSecretVersion(ctx, key, version)
+}
+
+func (s *SecuredSecretService) ListSecretVersions(ctx context.Context, key string, limit int, before int64) ([]SecretMeta, error) {
+	key = normalizeKey(key)
+
+	sub, ok := authn.SubjectFromContext(ctx)
+	if !ok {
+		return nil, fmt.Errorf("%w: subject missing", ErrForbidden)
+	}
+
+	dec := s.evaluate(ctx, sub, authz.ActionRead, key)
+	if !dec.Allowed {
+		return nil, fmt.Errorf("%w: %s", ErrForbidden, dec.Reason)
+	}
+
+	return s.inner.ListSecretVersions(ctx, key, limit, before)
+}
+
+func (s *SecuredSecretService) DeleteSecret(ctx context.Context, key string) (int64, error) {
+	key = normalizeKey(key)
+
+	sub, ok := authn.SubjectFromContext(ctx)
+	if !ok {
+		return 0, fmt.Errorf("%w: subject missing", ErrForbidden)
+	}
+
+	dec := s.evaluate(ctx, sub, authz.ActionDelete, key)
+	if !dec.Allowed {
+		return 0, fmt.Errorf("%w: %s", ErrForbidden, dec.Reason)
+	}
+
+	return s.inner.DeleteSecret(ctx, key)
+}
+
+// UndeleteSecret benötigt ebenfalls delete: wer löschen darf, darf das Löschen rückgängig machen
+func (s *SecuredSecretService) UndeleteSecret(ctx context.Context, key string) (int64, error) {
+	key = normalizeKey(key)
+
+	sub, ok := authn.SubjectFromContext(ctx)
+	if !ok {
+		return 0, fmt.Errorf("%w: subject missing", ErrForbidden)
+	}
+
+	dec := s.evaluate(ctx, sub, authz.ActionDelete, key)
+	if !dec.Allowed {
+		return 0, fmt.Errorf("%w: %s", ErrForbidden, dec.Reason)
+	}
+
+	return s.inner.UndeleteSecret(ctx, key)
+}
+
+func (s *SecuredSecretService) DestroySecretVersions(ctx context.Context, key string, versions []int64) (int, error) {
+	key = normalizeKey(key)
+
+	sub, ok := authn.SubjectFromContext(ctx)
+	if !ok {
+		return 0, fmt.Errorf("%w: subject missing", ErrForbidden)
+	}
+
+	dec := s.evaluate(ctx, sub, authz.ActionAdmin, key)
+	if !dec.Allowed {
+		return 0, fmt.Errorf("%w: %s", ErrForbidden, dec.Reason)
+	}
+
+	return s.inner.DestroySecretVersions(ctx, key, versions)
 }
@@ -3,12 +3,15 @@ package service
 import (
 	"context"
 	"database/sql"
+	"encoding/base64"
+	"encoding/json"
 	"errors"
 	"fmt"
 	"strings"
+	"time"
 
-	"github.com/timgst1/glass/internal/authn"
 	"github.com/timgst1/glass/internal/crypto/envelope"
+	"github.com/timgst1/glass/internal/metrics"
 )
 
 type SQLiteSecretService struct {
@@ -20,47 +23,110 @@ func NewSQLiteSecretService(db *sql.DB, enc *envelope.Envelope) *SQLiteSecretSer
 	return &SQLiteSecretService{db: db, enc: enc}
 }
 
+// queryRower wird von *sql.DB und *sql.Tx erfüllt
+type queryRower interface {
+	QueryRowContext(ctx context.Context, query string, args ...any) *sql.Row
+}
+
+// storedValue ist eine Zeile aus secrets, so wie sie in der DB liegt (ggf. verschlüsselt)
+type storedValue struct {
+	Version    int64
+	Value      string
+	Enc        int
+	ValueNonce string
+	WrappedDEK string
+	WrapNonce  string
+	KekID      string
+	Deleted    bool
+	Destroyed  bool
+	CreatedAt  string
+	CreatedBy  string
+	ExpiresAt  string
+	// IsBinary: bei Enc=0 ist Value base64(bytes)
+	IsBinary    bool
+	ContentType string
+}
+
+const selectStoredValue = `SELECT version, value, enc, value_nonce, wrapped_dek, wrap_nonce, kek_id, deleted, destroyed, created_at, created_by, expires_at, is_binary, content_type FROM secrets`
+
+// keyHasPrefix filtert byte-genau auf einen Key-Prefix (Parameter: prefix, prefix). Nicht LIKE: dort sind
+// _ und % Platzhalter und ASCII wird ohne Groß-/Kleinschreibung verglichen – "team_b/" träfe auch "team-b/".
+const keyHasPrefix = `substr(key, 1, length(?)) = ?`
+
 func (s *SQLiteSecretService) GetSecret(ctx context.Context, key string) (string, error) {
-	const q = `
-SELECT version, value, enc, value_nonce, wrapped_dek, wrap_nonce, kek_id
-FROM secrets
+	it, err := s.GetSecretVersion(ctx, key, 0)
+	if err != nil {
+		return "", err
+	}
+	return it.Value, nil
+}
+
+func (s *SQLiteSecretService) GetSecretVersion(ctx context.Context, key string, version int64) (SecretItem, error) {
+	defer metrics.ObserveQuery("get_secret", time.Now())
+	if version == 0 {
+		const q = selectStoredValue + `
 WHERE key = ?
 ORDER BY version DESC
 LIMIT 1`
+		return s.getItem(ctx, s.db, key, q, key)
+	}
 
-	var (
-		version    int64
-		value      string
-		encFlag    int
-		valueNonce string
-		wrappedDEK string
-		wrapNonce  string
-		kekID      string
-	)
+	const q = selectStoredValue + `
+WHERE key = ? AND version = ?`
+
+	return s.getItem(ctx, s.db, key, q, key, version)
+}
 
-	err := s.db.QueryRowContext(ctx, q, key).
-		Scan(&version, &value, &encFlag, &valueNonce, &wrappedDEK, &wrapNonce, &kekID)
+// getItem liest genau eine Zeile und entschlüsselt sie bei Bedarf. Tombstones und abgelaufene Versionen gelten als nicht vorhanden.
+func (s *SQLiteSecretService) getItem(ctx context.Context, qr queryRower, key, q string, args ...any) (SecretItem, error) {
+	var sv storedValue
+	err := qr.QueryRowContext(ctx, q, args...).
+		Scan(&sv.Version, &sv.Value, &sv.Enc, &sv.ValueNonce, &sv.WrappedDEK, &sv.WrapNonce, &sv.KekID, &sv.Deleted, &sv.Destroyed, &sv.CreatedAt, &sv.CreatedBy, &sv.ExpiresAt, &sv.IsBinary, &sv.ContentType)
 	if err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
-			return "", ErrNotFound
+			return SecretItem{}, ErrNotFound
 		}
-		return "", err
+		return SecretItem{}, err
+	}
+	if sv.Deleted || isExpired(sv.ExpiresAt, time.Now()) {
+		return SecretItem{}, ErrNotFound
+	}
+	if sv.Destroyed {
+		return SecretItem{}, ErrDestroyed
 	}
 
-	if encFlag == 0 {
-		return value, nil
+	val, err := s.open(ctx, key, sv)
+	if err != nil {
+		return SecretItem{}, err
+	}
+	return SecretItem{
+		Key:         key,
+		Value:       val,
+		Version:     sv.Version,
+		CreatedAt:   sv.CreatedAt,
+		CreatedBy:   sv.CreatedBy,
+		ExpiresAt:   sv.ExpiresAt,
+		Binary:      sv.IsBinary,
+		ContentType: sv.ContentType,
+	}, nil
+}
+
+// open liefert den Klartext einer gespeicherten Zeile
+func (s *SQLiteSecretService) open(ctx context.Context, key string, sv storedValue) (string, error) {
+	if sv.Enc == 0 {
+		return decodeStoredPlaintext(sv.Value, sv.IsBinary)
 	}
 	if s.enc == nil {
 		return "", fmt.Errorf("encrypted secret but encryption is not configured")
 	}
 
-	pt, err := s.enc.Decrypt(key, version, envelope.EncryptedValue{
-		Enc:        encFlag,
-		KekID:      kekID,
-		Ciphertext: value,
-		Nonce:      valueNonce,
-		WrappedDEK: wrappedDEK,
-		WrapNonce:  wrapNonce,
+	pt, err := s.enc.Decrypt(ctx, key, sv.Version, envelope.EncryptedValue{
+		Enc:        sv.Enc,
+		KekID:      sv.KekID,
+		Ciphertext: sv.Value,
+		Nonce:      sv.ValueNonce,
+		WrappedDEK: sv.WrappedDEK,
+		WrapNonce:  sv.WrapNonce,
 	})
 	if err != nil {
 		return "", err
@@ -68,134 +134,689 @@ LIMIT 1`
 	return string(pt), nil
 }
 
-func (s *SQLiteSecretService) GetSecretMeta(ctx context.Context, key string) (SecretMeta, error) {
-	const q = `SELECT key, version, created_at, created_by FROM secrets WHERE key = ? ORDER BY version DESC LIMIT 1`
+// decodeStoredPlaintext: unverschlüsselte Binärwerte liegen base64-kodiert in der TEXT-Spalte
+func decodeStoredPlaintext(v string, isBinary bool) (string, error) {
+	if !isBinary {
+		return v, nil
+	}
+	b, err := base64.StdEncoding.DecodeString(v)
+	if err != nil {
+		return "", fmt.Errorf("decode binary value: %w", err)
+	}
+	return string(b), nil
+}
 
-	var (
-		k         string
-		version   int64
-		createdAt string
-		createdBy string
-	)
+func (s *SQLiteSecretService) GetSecretMeta(ctx context.Context, key string) (SecretMeta, error) {
+	defer metrics.ObserveQuery("get_secret_meta", time.Now())
+	const q = `SELECT key, version, created_at, created_by, deleted, destroyed, expires_at, is_binary, content_type FROM secrets WHERE key = ? ORDER BY version DESC LIMIT 1`
 
-	err := s.db.QueryRowContext(ctx, q, key).Scan(&k, &version, &createdAt, &createdBy)
+	var m SecretMeta
+	err := s.db.QueryRowContext(ctx, q, key).Scan(&m.Key, &m.Version, &m.CreatedAt, &m.CreatedBy, &m.Deleted, &m.Destroyed, &m.ExpiresAt, &m.Binary, &m.ContentType)
 	if err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
 			return SecretMeta{}, ErrNotFound
 		}
 		return SecretMeta{}, err
 	}
+	if m.Deleted {
+		return SecretMeta{}, ErrNotFound
+	}
+
+	md, err := s.loadMetadata(ctx, s.db, key)
+	if err != nil {
+		return SecretMeta{}, err
+	}
+	m.Metadata = md
 
-	return SecretMeta{
-		Key:       k,
-		Version:   version,
-		CreatedAt: createdAt,
-		CreatedBy: createdBy,
-	}, nil
+	return m, nil
+}
+
+func (s *SQLiteSecretService) ListSecretVersions(ctx context.Context, key string, limit int, before int64) ([]SecretMeta, error) {
+	defer metrics.ObserveQuery("list_secret_versions", time.Now())
+	if limit <= 0 {
+		limit = -1 // SQLite: LIMIT -1 = unbegrenzt
+	}
+
+	const q = `
+SELECT version, created_at, created_by, deleted, destroyed, expires_at, is_binary, content_type
+FROM secrets
+WHERE key = ?
+  AND (? = 0 OR version < ?)
+ORDER BY version DESC
+LIMIT ?`
+
+	rows, err := s.db.QueryContext(ctx, q, key, before, before, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	out := []SecretMeta{}
+	for rows.Next() {
+		m := SecretMeta{Key: key}
+		if err := rows.Scan(&m.Version, &m.CreatedAt, &m.CreatedBy, &m.Deleted, &m.Destroyed, &m.ExpiresAt, &m.Binary, &m.ContentType); err != nil {
+			return nil, err
+		}
+		out = append(out, m)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	if len(out) == 0 {
+		// Unterscheiden: Key existiert gar nicht vs. Seite hinter der letzten Version
+		var n int
+		if err := s.db.QueryRowContext(ctx, `SELECT COUNT(*) FROM secrets WHERE key = ?`, key).Scan(&n); err != nil {
+			return nil, err
+		}
+		if n == 0 {
+			return nil, ErrNotFound
+		}
+	}
+	return out, nil
+}
+
+func (s *SQLiteSecretService) ListSecrets(ctx context.Context, prefix string, opts ...ListOption) ([]SecretItem, error) {
+	defer metrics.ObserveQuery("list_secrets", time.Now())
+	o := applyListOptions(opts)
+
+	chunk := o.Limit
+	if chunk <= 0 {
+		chunk = -1 // SQLite: LIMIT -1 = unbegrenzt
+	}
+
+	// Der LabelSelector filtert erst nach der Query -> ggf. weitere Chunks laden, bis die Seite voll ist
+	items := []SecretItem{}
+	after := o.After
+	for {
+		rows, last, err := s.listChunk(ctx, prefix, after, chunk)
+		if err != nil {
+			return nil, err
+		}
+		for _, r := range rows {
+			// Selector vor dem Entschlüsseln prüfen: nicht gewählte Keys werden gar nicht erst geöffnet
+			if !o.LabelSelector.Matches(r.Metadata.Labels) {
+				continue
+			}
+			// Ein nicht entschlüsselbarer Key lässt die ganze Liste fehlschlagen, statt still Ciphertext oder Lücken zu liefern
+			val, err := s.open(ctx, r.Key, r.stored)
+			if err != nil {
+				return nil, fmt.Errorf("decrypt %q (version %d, kek_id %q): %w", r.Key, r.stored.Version, r.stored.KekID, err)
+			}
+			r.SecretItem.Value = val
+			items = append(items, r.SecretItem)
+			if o.Limit > 0 && len(items) == o.Limit {
+				return items, nil
+			}
+		}
+		if chunk < 0 || len(rows) < chunk {
+			return items, nil
+		}
+		after = last
+	}
 }
 
-func (s *SQLiteSecretService) ListSecrets(ctx context.Context, prefix string) ([]SecretItem, error) {
-	//Latest version per key for a prefix
+// listedRow ist eine Zeile aus listChunk, der Wert ist noch nicht entschlüsselt
+type listedRow struct {
+	SecretItem
+	stored storedValue
+}
+
+// listChunk liefert bis zu limit lebende Keys > after (neueste Version je Key) und den letzten gelesenen Key
+func (s *SQLiteSecretService) listChunk(ctx context.Context, prefix, after string, limit int) ([]listedRow, string, error) {
+	//Latest version per key for a prefix (gelöschte, vernichtete und abgelaufene Keys ausblenden)
 	const q = `
-SELECT s.key, s.value, s.version, s.created_at, s.created_by
+SELECT s.key, s.version, s.value, s.enc, s.value_nonce, s.wrapped_dek, s.wrap_nonce, s.kek_id,
+       s.created_at, s.created_by, s.expires_at, s.is_binary, s.content_type,
+       COALESCE(md.labels, '{}'), COALESCE(md.annotations, '{}'), COALESCE(md.description, ''),
+       COALESCE(md.owner, ''), COALESCE(md.updated_at, ''), COALESCE(md.updated_by, '')
 FROM secrets s
 JOIN (
     SELECT key, MAX(version) AS max_version
     FROM secrets
-    WHERE key LIKE ?
+    WHERE ` + keyHasPrefix + ` AND key > ?
     GROUP BY key
 ) m ON s.key = m.key AND s.version = m.max_version
-ORDER BY s.key;
+LEFT JOIN secret_metadata md ON md.key = s.key
+WHERE s.deleted = 0 AND s.destroyed = 0
+  AND (s.expires_at = '' OR s.expires_at > ?)
+ORDER BY s.key
+LIMIT ?;
 `
-	like := prefix + "%"
+	rows, err := s.db.QueryContext(ctx, q, prefix, prefix, after, formatExpiresAt(time.Now()), limit)
+	if err != nil {
+		return nil, "", err
+	}
+	defer rows.Close()
 
-	rows, err := s.db.QueryContext(ctx, q, like)
+	var (
+		out  []listedRow
+		last string
+	)
+	for rows.Next() {
+		var (
+			r                     listedRow
+			labelsJSON, annotJSON string
+		)
+		sv := &r.stored
+		md := &r.Metadata
+		if err := rows.Scan(&r.Key, &sv.Version, &sv.Value, &sv.Enc, &sv.ValueNonce, &sv.WrappedDEK, &sv.WrapNonce, &sv.KekID,
+			&sv.CreatedAt, &sv.CreatedBy, &sv.ExpiresAt, &sv.IsBinary, &sv.ContentType,
+			&labelsJSON, &annotJSON, &md.Description, &md.Owner, &md.UpdatedAt, &md.UpdatedBy); err != nil {
+			return nil, "", err
+		}
+		if err := decodeMetadataMaps(md, labelsJSON, annotJSON); err != nil {
+			return nil, "", fmt.Errorf("metadata for %q: %w", r.Key, err)
+		}
+		r.Version = sv.Version
+		r.CreatedAt = sv.CreatedAt
+		r.CreatedBy = sv.CreatedBy
+		r.ExpiresAt = sv.ExpiresAt
+		r.Binary = sv.IsBinary
+		r.ContentType = sv.ContentType
+
+		out = append(out, r)
+		last = r.Key
+	}
+	if err := rows.Err(); err != nil {
+		return nil, "", err
+	}
+	return out, last, nil
+}
+
+func (s *SQLiteSecretService) ListKeys(ctx context.Context, prefix, delimiter string) (KeyListing, error) {
+	defer metrics.ObserveQuery("list_keys", time.Now())
+	// wie ListSecrets, aber ohne Werte zu lesen oder zu entschlüsseln
+	const q = `
+SELECT s.key, s.version, s.created_at, s.created_by, s.expires_at, s.is_binary, s.content_type
+FROM secrets s
+JOIN (
+    SELECT key, MAX(version) AS max_version
+    FROM secrets
+    WHERE ` + keyHasPrefix + `
+    GROUP BY key
+) m ON s.key = m.key AND s.version = m.max_version
+WHERE s.deleted = 0 AND s.destroyed = 0
+  AND (s.expires_at = '' OR s.expires_at > ?)
+ORDER BY s.key;
+`
+	rows, err := s.db.QueryContext(ctx, q, prefix, prefix, formatExpiresAt(time.Now()))
 	if err != nil {
-		return nil, err
+		return KeyListing{}, err
 	}
 	defer rows.Close()
 
-	items := []SecretItem{}
+	var metas []SecretMeta
 	for rows.Next() {
-		var it SecretItem
-		if err := rows.Scan(&it.Key, &it.Value, &it.Version, &it.CreatedAt, &it.CreatedBy); err != nil {
-			return nil, err
+		var m SecretMeta
+		if err := rows.Scan(&m.Key, &m.Version, &m.CreatedAt, &m.CreatedBy, &m.ExpiresAt, &m.Binary, &m.ContentType); err != nil {
+			return KeyListing{}, err
 		}
-		items = append(items, it)
+		metas = append(metas, m)
 	}
 	if err := rows.Err(); err != nil {
+		return KeyListing{}, err
+	}
+
+	return groupByDelimiter(prefix, delimiter, metas), nil
+}
+
+func (s *SQLiteSecretService) UpdateSecretMetadata(ctx context.Context, key string, patch MetadataPatch) (KeyMetadata, error) {
+	defer metrics.ObserveQuery("update_metadata", time.Now())
+	updatedBy := subjectString(ctx)
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return KeyMetadata{}, err
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	var deleted bool
+	err = tx.QueryRowContext(ctx, `SELECT deleted FROM secrets WHERE key = ? ORDER BY version DESC LIMIT 1`, key).Scan(&deleted)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return KeyMetadata{}, ErrNotFound
+		}
+		return KeyMetadata{}, err
+	}
+	if deleted {
+		return KeyMetadata{}, ErrNotFound
+	}
+
+	cur, err := s.loadMetadata(ctx, tx, key)
+	if err != nil {
+		return KeyMetadata{}, err
+	}
+	next, err := applyMetadataPatch(cur, patch)
+	if err != nil {
+		return KeyMetadata{}, err
+	}
+	next.UpdatedAt = time.Now().UTC().Format(time.RFC3339Nano)
+	next.UpdatedBy = updatedBy
+
+	labelsJSON, err := json.Marshal(orEmpty(next.Labels))
+	if err != nil {
+		return KeyMetadata{}, err
+	}
+	annotJSON, err := json.Marshal(orEmpty(next.Annotations))
+	if err != nil {
+		return KeyMetadata{}, err
+	}
+
+	_, err = tx.ExecContext(ctx, `
+INSERT INTO secret_metadata(key, labels, annotations, description, owner, updated_at, updated_by)
+VALUES(?, ?, ?, ?, ?, ?, ?)
+ON CONFLICT(key) DO UPDATE SET
+    labels = excluded.labels,
+    annotations = excluded.annotations,
+    description = excluded.description,
+    owner = excluded.owner,
+    updated_at = excluded.updated_at,
+    updated_by = excluded.updated_by`,
+		key, string(labelsJSON), string(annotJSON), next.Description, next.Owner, next.UpdatedAt, next.UpdatedBy,
+	)
+	if err != nil {
+		return KeyMetadata{}, err
+	}
+	if err := recordChange(ctx, tx, key, ChangeMetadata, 0, updatedBy); err != nil {
+		return KeyMetadata{}, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return KeyMetadata{}, err
+	}
+	return next, nil
+}
+
+// loadMetadata liest die Key-Metadaten; fehlende Zeile = leere Metadaten
+func (s *SQLiteSecretService) loadMetadata(ctx context.Context, qr queryRower, key string) (KeyMetadata, error) {
+	const q = `SELECT labels, annotations, description, owner, updated_at, updated_by FROM secret_metadata WHERE key = ?`
+
+	var (
+		md                    KeyMetadata
+		labelsJSON, annotJSON string
+	)
+	err := qr.QueryRowContext(ctx, q, key).Scan(&labelsJSON, &annotJSON, &md.Description, &md.Owner, &md.UpdatedAt, &md.UpdatedBy)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return KeyMetadata{}, nil
+		}
+		return KeyMetadata{}, err
+	}
+	if err := decodeMetadataMaps(&md, labelsJSON, annotJSON); err != nil {
+		return KeyMetadata{}, fmt.Errorf("metadata for %q: %w", key, err)
+	}
+	return md, nil
+}
+
+func decodeMetadataMaps(md *KeyMetadata, labelsJSON, annotJSON string) error {
+	if err := json.Unmarshal([]byte(labelsJSON), &md.Labels); err != nil {
+		return fmt.Errorf("decode labels: %w", err)
+	}
+	if err := json.Unmarshal([]byte(annotJSON), &md.Annotations); err != nil {
+		return fmt.Errorf("decode annotations: %w", err)
+	}
+	if len(md.Labels) == 0 {
+		md.Labels = nil
+	}
+	if len(md.Annotations) == 0 {
+		md.Annotations = nil
+	}
+	return nil
+}
+
+func orEmpty(m map[string]string) map[string]string {
+	if m == nil {
+		return map[string]string{}
+	}
+	return m
+}
+
+func (s *SQLiteSecretService) PutSecret(ctx context.Context, key, value string, opts ...PutOption) (int64, error) {
+	defer metrics.ObserveQuery("put_secret", time.Now())
+	return s.appendVersion(ctx, key, putBuilder(key, value, applyPutOptions(opts)))
+}
+
+func (s *SQLiteSecretService) PutSecrets(ctx context.Context, writes []SecretWrite) ([]int64, error) {
+	defer metrics.ObserveQuery("put_secrets", time.Now())
+	if err := validateBatch(writes); err != nil {
 		return nil, err
 	}
 
-	return items, nil
+	pending := make([]pendingWrite, 0, len(writes))
+	for _, w := range writes {
+		pending = append(pending, pendingWrite{Key: w.Key, Build: putBuilder(w.Key, w.Value, applyPutOptions(w.Opts))})
+	}
+	return s.appendVersions(ctx, pending)
 }
 
-func (s *SQLiteSecretService) PutSecret(ctx context.Context, key, value string) (int64, error) {
-	sub, _ := authn.SubjectFromContext(ctx)
-	createdBy := sub.Kind + ":" + sub.Name
-	if createdBy == ":" {
-		createdBy = "unknown"
+func putBuilder(key, value string, o PutOptions) versionBuilder {
+	return func(tx *sql.Tx, cur latestState) (newVersion, error) {
+		if err := checkExpectedVersion(key, o, cur.live()); err != nil {
+			return newVersion{}, err
+		}
+		return newVersion{Value: value, ExpiresAt: formatExpiresAt(o.ExpiresAt), Binary: o.Binary, ContentType: o.ContentType}, nil
 	}
+}
 
-	// Retry bei Versions-Kollision (UNIQUE constraint) unter Concurrent Writes
-	for attempt := 0; attempt < 3; attempt++ {
-		tx, err := s.db.BeginTx(ctx, nil)
+func (s *SQLiteSecretService) DeleteSecret(ctx context.Context, key string) (int64, error) {
+	defer metrics.ObserveQuery("delete_secret", time.Now())
+	return s.appendVersion(ctx, key, func(tx *sql.Tx, cur latestState) (newVersion, error) {
+		if !cur.Exists || cur.Deleted {
+			return newVersion{}, ErrNotFound
+		}
+		return newVersion{Deleted: true}, nil
+	})
+}
+
+func (s *SQLiteSecretService) UndeleteSecret(ctx context.Context, key string) (int64, error) {
+	defer metrics.ObserveQuery("undelete_secret", time.Now())
+	return s.appendVersion(ctx, key, func(tx *sql.Tx, cur latestState) (newVersion, error) {
+		if !cur.Exists {
+			return newVersion{}, ErrNotFound
+		}
+		if !cur.Deleted {
+			return newVersion{}, fmt.Errorf("%w: secret is not deleted", ErrConflict)
+		}
+
+		// letzte lebende Version vor dem Tombstone wiederherstellen
+		const q = selectStoredValue + `
+WHERE key = ? AND version < ? AND deleted = 0
+ORDER BY version DESC
+LIMIT 1`
+		prev, err := s.getItem(ctx, tx, key, q, key, cur.Version)
 		if err != nil {
+			return newVersion{}, err
+		}
+		// Ablauf bleibt erhalten, sonst würde ein Undelete aus einem befristeten Secret ein dauerhaftes machen
+		return newVersion{Value: prev.Value, ExpiresAt: prev.ExpiresAt, Binary: prev.Binary, ContentType: prev.ContentType, Change: ChangeUndelete}, nil
+	})
+}
+
+func (s *SQLiteSecretService) DestroySecretVersions(ctx context.Context, key string, versions []int64) (int, error) {
+	defer metrics.ObserveQuery("destroy_versions", time.Now())
+	res, err := DestroyVersions(ctx, s.db, DestroyOptions{
+		Key:         key,
+		Versions:    versions,
+		All:         len(versions) == 0,
+		DestroyedBy: subjectString(ctx),
+	})
+	if err != nil {
+		return 0, err
+	}
+	return res.Destroyed, nil
+}
+
+func (s *SQLiteSecretService) ReapExpired(ctx context.Context, now time.Time) (int, error) {
+	defer metrics.ObserveQuery("reap_expired", time.Now())
+	// Keys, deren neueste Version lebt, aber abgelaufen ist
+	const q = `
+SELECT s.key
+FROM secrets s
+JOIN (
+    SELECT key, MAX(version) AS max_version
+    FROM secrets
+    GROUP BY key
+) m ON s.key = m.key AND s.version = m.max_version
+WHERE s.deleted = 0 AND s.expires_at != '' AND s.expires_at <= ?
+ORDER BY s.key;
+`
+	rows, err := s.db.QueryContext(ctx, q, formatExpiresAt(now))
+	if err != nil {
+		return 0, err
+	}
+	var keys []string
+	for rows.Next() {
+		var k string
+		if err := rows.Scan(&k); err != nil {
+			rows.Close()
 			return 0, err
 		}
+		keys = append(keys, k)
+	}
+	if err := rows.Err(); err != nil {
+		rows.Close()
+		return 0, err
+	}
+	_ = rows.Close()
 
-		var cur sql.NullInt64
-		err = tx.QueryRowContext(ctx, `SELECT MAX(version) FROM secrets WHERE key = ?`, key).Scan(&cur)
+	n := 0
+	for _, k := range keys {
+		_, err := s.appendVersion(ctx, k, func(tx *sql.Tx, cur latestState) (newVersion, error) {
+			// erneut prüfen: zwischenzeitlich könnte eine neue Version geschrieben worden sein
+			if !cur.Exists || cur.Deleted || !isExpired(cur.ExpiresAt, now) {
+				return newVersion{}, ErrConflict
+			}
+			return newVersion{Deleted: true}, nil
+		})
+		if errors.Is(err, ErrConflict) {
+			continue
+		}
 		if err != nil {
-			_ = tx.Rollback()
-			return 0, err
+			return n, err
 		}
+		n++
+	}
+	return n, nil
+}
+
+func (s *SQLiteSecretService) ListChanges(ctx context.Context, prefix string, since int64, limit int) (ChangeFeed, error) {
+	defer metrics.ObserveQuery("list_changes", time.Now())
+	// Lese-Tx: Head-Revision und Änderungen aus demselben Snapshot
+	tx, err := s.db.BeginTx(ctx, &sql.TxOptions{ReadOnly: true})
+	if err != nil {
+		return ChangeFeed{}, err
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	var head int64
+	if err := tx.QueryRowContext(ctx, `SELECT COALESCE(MAX(revision), 0) FROM secret_changes`).Scan(&head); err != nil {
+		return ChangeFeed{}, err
+	}
+	if since < 0 {
+		since = head
+	}
+
+	// limit+1 lesen, um "More" zu erkennen
+	sqlLimit := -1
+	if limit > 0 {
+		sqlLimit = limit + 1
+	}
+	rows, err := tx.QueryContext(ctx, `
+SELECT revision, key, type, version, created_at, created_by
+FROM secret_changes
+WHERE revision > ? AND revision <= ? AND `+keyHasPrefix+`
+ORDER BY revision
+LIMIT ?`, since, head, prefix, prefix, sqlLimit)
+	if err != nil {
+		return ChangeFeed{}, err
+	}
+	defer rows.Close()
 
-		next := int64(1)
-		if cur.Valid {
-			next = cur.Int64 + 1
+	feed := ChangeFeed{Changes: []Change{}}
+	for rows.Next() {
+		var (
+			c   Change
+			typ string
+		)
+		if err := rows.Scan(&c.Revision, &c.Key, &typ, &c.Version, &c.CreatedAt, &c.CreatedBy); err != nil {
+			return ChangeFeed{}, err
 		}
+		c.Type = ChangeType(typ)
+		feed.Changes = append(feed.Changes, c)
+	}
+	if err := rows.Err(); err != nil {
+		return ChangeFeed{}, err
+	}
+
+	if limit > 0 && len(feed.Changes) > limit {
+		feed.Changes = feed.Changes[:limit]
+		feed.More = true
+		feed.Revision = feed.Changes[limit-1].Revision
+		return feed, nil
+	}
+	// alles bis head gelesen (auch Änderungen außerhalb des Prefix)
+	feed.Revision = max(since, head)
+	return feed, nil
+}
+
+// latestState beschreibt die neueste Version eines Keys innerhalb einer Write-Tx
+type latestState struct {
+	Exists    bool
+	Version   int64
+	Deleted   bool
+	ExpiresAt string
+}
+
+// live liefert die aktuelle Version, oder 0 wenn der Key nicht existiert, gelöscht oder abgelaufen ist
+func (c latestState) live() int64 {
+	if !c.Exists || c.Deleted || isExpired(c.ExpiresAt, time.Now()) {
+		return 0
+	}
+	return c.Version
+}
+
+// newVersion ist der Klartext-Inhalt der nächsten Version; Verschlüsselung passiert in appendVersion
+type newVersion struct {
+	Value       string
+	Deleted     bool
+	ExpiresAt   string
+	Binary      bool
+	ContentType string
+	// Change: Typ im Change-Feed (leer = aus Deleted abgeleitet)
+	Change ChangeType
+}
+
+// versionBuilder sieht den aktuellen Stand eines Keys und entscheidet über den Inhalt der nächsten Version (oder bricht mit Fehler ab)
+type versionBuilder func(tx *sql.Tx, cur latestState) (newVersion, error)
+
+// pendingWrite ist ein Eintrag für appendVersions
+type pendingWrite struct {
+	Key   string
+	Build versionBuilder
+}
+
+// appendVersion legt in einer Transaktion die nächste Version eines Keys an.
+func (s *SQLiteSecretService) appendVersion(ctx context.Context, key string, build versionBuilder) (int64, error) {
+	vs, err := s.appendVersions(ctx, []pendingWrite{{Key: key, Build: build}})
+	if err != nil {
+		return 0, err
+	}
+	return vs[0], nil
+}
+
+// appendVersions legt für alle Einträge in EINER Transaktion die nächste Version an: alles oder nichts.
+func (s *SQLiteSecretService) appendVersions(ctx context.Context, writes []pendingWrite) ([]int64, error) {
+	createdBy := subjectString(ctx)
 
-		encFlag := 0
-		storeValue := value
-		valueNonce := ""
-		wrappedDEK := ""
-		wrapNonce := ""
-		kekID := ""
+	// Retry bei Versions-Kollision (UNIQUE constraint) unter Concurrent Writes
+	for attempt := 0; attempt < 3; attempt++ {
+		tx, err := s.db.BeginTx(ctx, nil)
+		if err != nil {
+			return nil, err
+		}
 
-		if s.enc != nil {
-			ev, err := s.enc.Encrypt(key, next, []byte(value))
+		out := make([]int64, len(writes))
+		for i, w := range writes {
+			out[i], err = s.insertNextVersion(ctx, tx, w.Key, createdBy, w.Build)
 			if err != nil {
-				_ = tx.Rollback()
-				return 0, err
+				break
 			}
-			encFlag = ev.Enc
-			storeValue = ev.Ciphertext
-			valueNonce = ev.Nonce
-			wrappedDEK = ev.WrappedDEK
-			wrapNonce = ev.WrapNonce
-			kekID = ev.KekID
-		}
-
-		_, err = tx.ExecContext(ctx, `
-INSERT INTO secrets(key, version, value, enc, value_nonce, wrapped_dek, wrap_nonce, kek_id, created_by)
-VALUES(?, ?, ?, ?, ?, ?, ?, ?, ?)`,
-			key, next, storeValue, encFlag, valueNonce, wrappedDEK, wrapNonce, kekID, createdBy,
-		)
+		}
 		if err != nil {
 			_ = tx.Rollback()
 			if strings.Contains(err.Error(), "UNIQUE constraint failed") {
 				continue
 			}
-			return 0, err
+			return nil, err
 		}
 
 		if err := tx.Commit(); err != nil {
 			_ = tx.Rollback()
+			return nil, err
+		}
+		return out, nil
+	}
+
+	return nil, fmt.Errorf("write conflict: could not allocate new version")
+}
+
+// insertNextVersion liest den aktuellen Stand, ruft build auf und schreibt (ggf. verschlüsselt) die nächste Version
+func (s *SQLiteSecretService) insertNextVersion(ctx context.Context, tx *sql.Tx, key, createdBy string, build versionBuilder) (int64, error) {
+	var cur latestState
+	err := tx.QueryRowContext(ctx, `SELECT version, deleted, expires_at FROM secrets WHERE key = ? ORDER BY version DESC LIMIT 1`, key).
+		Scan(&cur.Version, &cur.Deleted, &cur.ExpiresAt)
+	switch {
+	case err == nil:
+		cur.Exists = true
+	case errors.Is(err, sql.ErrNoRows):
+	default:
+		return 0, err
+	}
+
+	nv, err := build(tx, cur)
+	if err != nil {
+		return 0, err
+	}
+
+	next := cur.Version + 1
+
+	encFlag := 0
+	storeValue := nv.Value
+	valueNonce := ""
+	wrappedDEK := ""
+	wrapNonce := ""
+	kekID := ""
+
+	if nv.Binary {
+		// TEXT-Spalte: Binärwerte unverschlüsselt als base64 ablegen
+		storeValue = base64.StdEncoding.EncodeToString([]byte(nv.Value))
+	}
+
+	if s.enc != nil && !nv.Deleted {
+		ev, err := s.enc.Encrypt(ctx, key, next, []byte(nv.Value))
+		if err != nil {
 			return 0, err
 		}
-		return next, nil
+		encFlag = ev.Enc
+		storeValue = ev.Ciphertext
+		valueNonce = ev.Nonce
+		wrappedDEK = ev.WrappedDEK
+		wrapNonce = ev.WrapNonce
+		kekID = ev.KekID
 	}
 
-	return 0, fmt.Errorf("write conflict: could not allocate new version")
+	_, err = tx.ExecContext(ctx, `
+INSERT INTO secrets(key, version, value, enc, value_nonce, wrapped_dek, wrap_nonce, kek_id, deleted, expires_at, is_binary, content_type, created_by)
+VALUES(?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+		key, next, storeValue, encFlag, valueNonce, wrappedDEK, wrapNonce, kekID, nv.Deleted, nv.ExpiresAt, nv.Binary, nv.ContentType, createdBy,
+	)
+	if err != nil {
+		return 0, err
+	}
+	if err := recordChange(ctx, tx, key, changeTypeFor(nv), next, createdBy); err != nil {
+		return 0, err
+	}
+	return next, nil
+}
+
+// recordChange schreibt einen Eintrag in den Change-Feed (in derselben Tx wie die Änderung)
+func recordChange(ctx context.Context, tx *sql.Tx, key string, typ ChangeType, version int64, createdBy string) error {
+	_, err := tx.ExecContext(ctx, `INSERT INTO secret_changes(key, type, version, created_by) VALUES(?, ?, ?, ?)`,
+		key, string(typ), version, createdBy)
+	return err
+}
+
+// Stats zählt lebende Keys (neueste Version weder Tombstone noch vernichtet) und nicht vernichtete Versionen (für /metrics)
+func (s *SQLiteSecretService) Stats(ctx context.Context) (keys, versions int64, err error) {
+	defer metrics.ObserveQuery("stats", time.Now())
+	const q = `
+SELECT
+    (SELECT COUNT(*) FROM secrets s
+     JOIN (SELECT key, MAX(version) AS max_version FROM secrets GROUP BY key) m
+       ON s.key = m.key AND s.version = m.max_version
+     WHERE s.deleted = 0 AND s.destroyed = 0),
+    (SELECT COUNT(*) FROM secrets WHERE destroyed = 0)`
+	err = s.db.QueryRowContext(ctx, q).Scan(&keys, &versions)
+	return keys, versions, err
 }
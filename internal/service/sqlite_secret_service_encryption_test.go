@@ -84,3 +84,159 @@ func TestSQLiteSecretService_EncryptionAtRest(t *testing.T) {
 		t.Fatalf("expected ciphertext in DB, found plaintext")
 	}
 }
+
+func TestSQLiteSecretService_EncryptedOldVersionReadable(t *testing.T) {
+	db := openTestDB(t)
+	svc := NewSQLiteSecretService(db, newTestEnvelope(t))
+	ctx := context.Background()
+
+	if _, err := svc.PutSecret(ctx, "demo", "old"); err != nil {
+		t.Fatalf("PutSecret v1: %v", err)
+	}
+	if _, err := svc.PutSecret(ctx, "demo", "new"); err != nil {
+		t.Fatalf("PutSecret v2: %v", err)
+	}
+
+	got, err := svc.GetSecretVersion(ctx, "demo", 1)
+	if err != nil {
+		t.Fatalf("GetSecretVersion: %v", err)
+	}
+	if got.Value != "old" || got.Version != 1 {
+		t.Fatalf("expected plaintext of v1, got %+v", got)
+	}
+}
+
+func TestSQLiteSecretService_UndeleteReencryptsUnderNewVersion(t *testing.T) {
+	db := openTestDB(t)
+	svc := NewSQLiteSecretService(db, newTestEnvelope(t))
+	ctx := context.Background()
+
+	if _, err := svc.PutSecret(ctx, "demo", "super-secret"); err != nil {
+		t.Fatalf("PutSecret: %v", err)
+	}
+	if _, err := svc.DeleteSecret(ctx, "demo"); err != nil {
+		t.Fatalf("DeleteSecret: %v", err)
+	}
+	if _, err := svc.UndeleteSecret(ctx, "demo"); err != nil {
+		t.Fatalf("UndeleteSecret: %v", err)
+	}
+
+	got, err := svc.GetSecret(ctx, "demo")
+	if err != nil {
+		t.Fatalf("GetSecret: %v", err)
+	}
+	if got != "super-secret" {
+		t.Fatalf("expected plaintext after undelete, got %q", got)
+	}
+}
+
+func TestSQLiteSecretService_DestroyShredsDEK(t *testing.T) {
+	db := openTestDB(t)
+	svc := NewSQLiteSecretService(db, newTestEnvelope(t))
+	ctx := context.Background()
+
+	if _, err := svc.PutSecret(ctx, "demo", "leaked"); err != nil {
+		t.Fatalf("PutSecret v1: %v", err)
+	}
+	if _, err := svc.PutSecret(ctx, "demo", "rotated"); err != nil {
+		t.Fatalf("PutSecret v2: %v", err)
+	}
+
+	n, err := svc.DestroySecretVersions(ctx, "demo", []int64{1})
+	if err != nil {
+		t.Fatalf("DestroySecretVersions: %v", err)
+	}
+	if n != 1 {
+		t.Fatalf("expected 1 destroyed version, got %d", n)
+	}
+
+	if _, err := svc.GetSecretVersion(ctx, "demo", 1); err != ErrDestroyed {
+		t.Fatalf("expected ErrDestroyed for v1, got %v", err)
+	}
+	got, err := svc.GetSecret(ctx, "demo")
+	if err != nil {
+		t.Fatalf("GetSecret: %v", err)
+	}
+	if got != "rotated" {
+		t.Fatalf("expected latest untouched, got %q", got)
+	}
+
+	var wrappedDEK, wrapNonce string
+	var destroyed int
+	err = db.QueryRowContext(ctx, `SELECT wrapped_dek, wrap_nonce, destroyed FROM secrets WHERE key=? AND version=1`, "demo").
+		Scan(&wrappedDEK, &wrapNonce, &destroyed)
+	if err != nil {
+		t.Fatalf("query db: %v", err)
+	}
+	if wrappedDEK != "" || wrapNonce != "" {
+		t.Fatalf("expected wrapped DEK to be cleared")
+	}
+	if destroyed != 1 {
+		t.Fatalf("expected destroyed marker, got %d", destroyed)
+	}
+}
+
+func TestSQLiteSecretService_EncryptedBinaryValue(t *testing.T) {
+	db := openTestDB(t)
+	svc := NewSQLiteSecretService(db, newTestEnvelope(t))
+	ctx := context.Background()
+
+	blob := string([]byte{0x00, 0xff, 0xfe, 0x10, 0x80})
+	if _, err := svc.PutSecret(ctx, "keytab", blob, WithBinary()); err != nil {
+		t.Fatalf("PutSecret: %v", err)
+	}
+
+	it, err := svc.GetSecretVersion(ctx, "keytab", 0)
+	if err != nil {
+		t.Fatalf("GetSecretVersion: %v", err)
+	}
+	if it.Value != blob || !it.Binary {
+		t.Fatalf("unexpected item: %+v", it)
+	}
+}
+
+func TestSQLiteSecretService_EncryptedPutSecrets(t *testing.T) {
+	db := openTestDB(t)
+	svc := NewSQLiteSecretService(db, newTestEnvelope(t))
+	ctx := context.Background()
+
+	if _, err := svc.PutSecrets(ctx, []SecretWrite{
+		{Key: "db/user", Value: "app"},
+		{Key: "db/password", Value: "s3cret"},
+	}); err != nil {
+		t.Fatalf("PutSecrets: %v", err)
+	}
+
+	for k, want := range map[string]string{"db/user": "app", "db/password": "s3cret"} {
+		got, err := svc.GetSecret(ctx, k)
+		if err != nil {
+			t.Fatalf("GetSecret %s: %v", k, err)
+		}
+		if got != want {
+			t.Fatalf("expected %q for %s, got %q", want, k, got)
+		}
+	}
+}
+
+func TestSQLiteSecretService_EncryptedListSecrets(t *testing.T) {
+	db := openTestDB(t)
+	svc := NewSQLiteSecretService(db, newTestEnvelope(t))
+	ctx := context.Background()
+
+	if _, err := svc.PutSecret(ctx, "team-a/db", "super-secret"); err != nil {
+		t.Fatalf("PutSecret: %v", err)
+	}
+
+	items, err := svc.ListSecrets(ctx, "team-a/")
+	if err != nil {
+		t.Fatalf("ListSecrets: %v", err)
+	}
+	if len(items) != 1 || items[0].Value != "super-secret" {
+		t.Fatalf("expected decrypted value, got %+v", items)
+	}
+
+	// ohne Keyring: klarer Fehler statt Ciphertext
+	if _, err := NewSQLiteSecretService(db, nil).ListSecrets(ctx, "team-a/"); err == nil {
+		t.Fatalf("expected error when encryption is not configured")
+	}
+}
@@ -1,11 +1,16 @@
 package sqlite
 
 import (
+	"context"
 	"database/sql"
+	"database/sql/driver"
 	"fmt"
 	"os"
 	"path/filepath"
 
+	"github.com/XSAM/otelsql"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
 	_ "modernc.org/sqlite"
 )
 
@@ -18,9 +23,20 @@ func Open(path string) (*sql.DB, error) {
 	}
 
 	// pragmas via DSN
-	dsn := fmt.Sprintf("file:%s?_pragma=busy_timeout(5000)&_pragma=journal_mode(WAL)&_pragma=foreign_keys(ON)", path)
+	// secure_delete: überschriebene/gelöschte Inhalte (z.B. vernichtete Secrets) werden in der Datei genullt
+	dsn := fmt.Sprintf("file:%s?_pragma=busy_timeout(5000)&_pragma=journal_mode(WAL)&_pragma=foreign_keys(ON)&_pragma=secure_delete(ON)", path)
 
-	db, err := sql.Open("sqlite", dsn)
+	// otelsql: ein Span pro Query, aber nur innerhalb eines Traces (Hintergrundjobs erzeugen keine Root-Spans).
+	// Nur der SQL-Text (mit Platzhaltern) landet im Span, nie die Argumente.
+	db, err := otelsql.Open("sqlite", dsn,
+		otelsql.WithAttributes(attribute.String("db.system.name", "sqlite")),
+		otelsql.WithSpanOptions(otelsql.SpanOptions{
+			OmitConnResetSession: true,
+			SpanFilter: func(ctx context.Context, _ otelsql.Method, _ string, _ []driver.NamedValue) bool {
+				return trace.SpanContextFromContext(ctx).IsValid()
+			},
+		}),
+	)
 	if err != nil {
 		return nil, err
 	}
@@ -47,12 +63,121 @@ CREATE TABLE IF NOT EXISTS secrets (
 	wrap_nonce TEXT NOT NULL DEFAULT '',
 	kek_id TEXT NOT NULL DEFAULT '',
 
+	-- deleted=1: Tombstone-Version (value leer), Key gilt als gelöscht
+	deleted INTEGER NOT NULL DEFAULT 0,
+
+	-- destroyed=1: Version wurde vernichtet (DEK bzw. value geleert), Zeile bleibt als Marker
+	destroyed INTEGER NOT NULL DEFAULT 0,
+	destroyed_at TEXT NOT NULL DEFAULT '',
+	destroyed_by TEXT NOT NULL DEFAULT '',
+
+	-- is_binary=1: Wert sind rohe Bytes; bei enc=0 steht base64(bytes) in value
+	is_binary INTEGER NOT NULL DEFAULT 0,
+	content_type TEXT NOT NULL DEFAULT '',
+
+	-- expires_at: leer = kein Ablauf, sonst UTC 'YYYY-MM-DDTHH:MM:SS.sssZ' (lexikografisch vergleichbar)
+	expires_at TEXT NOT NULL DEFAULT '',
+
 	created_at TEXT NOT NULL DEFAULT (strftime('%Y-%m-%dT%H:%M:%fZ','now')),
 	created_by TEXT NOT NULL,
 	PRIMARY KEY (key, version)
 );
 
 CREATE INDEX IF NOT EXISTS idx_secrets_key_version ON secrets(key, version DESC);
+
+-- Metadaten pro Key (nicht pro Version); labels/annotations als JSON-Objekt
+CREATE TABLE IF NOT EXISTS secret_metadata (
+	key TEXT PRIMARY KEY,
+	labels TEXT NOT NULL DEFAULT '{}',
+	annotations TEXT NOT NULL DEFAULT '{}',
+	description TEXT NOT NULL DEFAULT '',
+	owner TEXT NOT NULL DEFAULT '',
+	updated_at TEXT NOT NULL DEFAULT (strftime('%Y-%m-%dT%H:%M:%fZ','now')),
+	updated_by TEXT NOT NULL DEFAULT ''
+);
+
+-- Change-Feed: jede Änderung bekommt eine globale, streng monotone Revision
+CREATE TABLE IF NOT EXISTS secret_changes (
+	revision INTEGER PRIMARY KEY AUTOINCREMENT,
+	key TEXT NOT NULL,
+	type TEXT NOT NULL,
+	version INTEGER NOT NULL DEFAULT 0,
+	created_at TEXT NOT NULL DEFAULT (strftime('%Y-%m-%dT%H:%M:%fZ','now')),
+	created_by TEXT NOT NULL DEFAULT ''
+);
+
+CREATE INDEX IF NOT EXISTS idx_secret_changes_key ON secret_changes(key, revision);
+
+-- Webhooks: per API angelegte Subscriptions (Datei-Subscriptions liegen nur im Speicher)
+CREATE TABLE IF NOT EXISTS webhook_subscriptions (
+	id TEXT PRIMARY KEY,
+	url TEXT NOT NULL,
+	key_prefix TEXT NOT NULL DEFAULT '',
+	events TEXT NOT NULL DEFAULT '[]',
+	-- secret: HMAC-Secret; bei secret_enc=1 base64(ciphertext), Felder wie bei secrets
+	secret TEXT NOT NULL,
+	secret_enc INTEGER NOT NULL DEFAULT 0,
+	secret_nonce TEXT NOT NULL DEFAULT '',
+	secret_wrapped_dek TEXT NOT NULL DEFAULT '',
+	secret_wrap_nonce TEXT NOT NULL DEFAULT '',
+	secret_kek_id TEXT NOT NULL DEFAULT '',
+	created_at TEXT NOT NULL DEFAULT (strftime('%Y-%m-%dT%H:%M:%fZ','now')),
+	created_by TEXT NOT NULL DEFAULT ''
+);
+
+-- Outbox: eine Zeile pro (Subscription, Änderung); zugestellte Einträge werden gelöscht,
+-- status='failed' bleibt nach dem letzten Versuch liegen. next_attempt_at in Unix-Millisekunden.
+CREATE TABLE IF NOT EXISTS webhook_outbox (
+	id INTEGER PRIMARY KEY AUTOINCREMENT,
+	subscription_id TEXT NOT NULL,
+	revision INTEGER NOT NULL,
+	event TEXT NOT NULL,
+	key TEXT NOT NULL,
+	version INTEGER NOT NULL DEFAULT 0,
+	created_at TEXT NOT NULL,
+	created_by TEXT NOT NULL,
+	status TEXT NOT NULL DEFAULT 'pending',
+	attempts INTEGER NOT NULL DEFAULT 0,
+	next_attempt_at INTEGER NOT NULL,
+	last_error TEXT NOT NULL DEFAULT ''
+);
+
+CREATE INDEX IF NOT EXISTS idx_webhook_outbox_due ON webhook_outbox(status, next_attempt_at);
+
+-- bis zu welcher Revision der Change-Feed in die Outbox übertragen wurde
+CREATE TABLE IF NOT EXISTS webhook_cursor (
+	id INTEGER PRIMARY KEY CHECK (id = 1),
+	revision INTEGER NOT NULL
+);
+
+-- Audit-Log: ein Eintrag pro authentifiziertem Request, hash-verkettet (hash = sha256(prev_hash + Eintrag))
+CREATE TABLE IF NOT EXISTS audit_log (
+	seq INTEGER PRIMARY KEY,
+	time TEXT NOT NULL,
+	request_id TEXT NOT NULL DEFAULT '',
+	subject TEXT NOT NULL DEFAULT '',
+	method TEXT NOT NULL DEFAULT '',
+	path TEXT NOT NULL DEFAULT '',
+	action TEXT NOT NULL DEFAULT '',
+	key TEXT NOT NULL DEFAULT '',
+	decision TEXT NOT NULL DEFAULT '',
+	reason TEXT NOT NULL DEFAULT '',
+	status INTEGER NOT NULL DEFAULT 0,
+	source_ip TEXT NOT NULL DEFAULT '',
+	prev_hash TEXT NOT NULL DEFAULT '',
+	hash TEXT NOT NULL
+);
+
+-- append-only: Änderungen und Löschungen werden abgewiesen (wer die Trigger entfernt, fällt bei "glass audit verify" auf)
+CREATE TRIGGER IF NOT EXISTS audit_log_no_update BEFORE UPDATE ON audit_log
+BEGIN
+	SELECT RAISE(ABORT, 'audit_log is append-only');
+END;
+
+CREATE TRIGGER IF NOT EXISTS audit_log_no_delete BEFORE DELETE ON audit_log
+BEGIN
+	SELECT RAISE(ABORT, 'audit_log is append-only');
+END;
 `
 	if _, err := db.Exec(schema); err != nil {
 		return err
@@ -74,6 +199,38 @@ CREATE INDEX IF NOT EXISTS idx_secrets_key_version ON secrets(key, version DESC)
 	if err := ensureColumn(db, "secrets", "kek_id", "kek_id TEXT NOT NULL DEFAULT ''"); err != nil {
 		return err
 	}
+	if err := ensureColumn(db, "secrets", "deleted", "deleted INTEGER NOT NULL DEFAULT 0"); err != nil {
+		return err
+	}
+	if err := ensureColumn(db, "secrets", "destroyed", "destroyed INTEGER NOT NULL DEFAULT 0"); err != nil {
+		return err
+	}
+	if err := ensureColumn(db, "secrets", "destroyed_at", "destroyed_at TEXT NOT NULL DEFAULT ''"); err != nil {
+		return err
+	}
+	if err := ensureColumn(db, "secrets", "destroyed_by", "destroyed_by TEXT NOT NULL DEFAULT ''"); err != nil {
+		return err
+	}
+	if err := ensureColumn(db, "secrets", "expires_at", "expires_at TEXT NOT NULL DEFAULT ''"); err != nil {
+		return err
+	}
+	if err := ensureColumn(db, "secrets", "is_binary", "is_binary INTEGER NOT NULL DEFAULT 0"); err != nil {
+		return err
+	}
+	if err := ensureColumn(db, "secrets", "content_type", "content_type TEXT NOT NULL DEFAULT ''"); err != nil {
+		return err
+	}
+	for _, c := range []struct{ col, ddl string }{
+		{"secret_enc", "secret_enc INTEGER NOT NULL DEFAULT 0"},
+		{"secret_nonce", "secret_nonce TEXT NOT NULL DEFAULT ''"},
+		{"secret_wrapped_dek", "secret_wrapped_dek TEXT NOT NULL DEFAULT ''"},
+		{"secret_wrap_nonce", "secret_wrap_nonce TEXT NOT NULL DEFAULT ''"},
+		{"secret_kek_id", "secret_kek_id TEXT NOT NULL DEFAULT ''"},
+	} {
+		if err := ensureColumn(db, "webhook_subscriptions", c.col, c.ddl); err != nil {
+			return err
+		}
+	}
 
 	return nil
 }
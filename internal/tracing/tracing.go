@@ -0,0 +1,114 @@
+package tracing
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strings"
+	"sync/atomic"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/exporters/stdout/stdouttrace"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const instrumentationName = "github.com/timgst1/glass"
+
+// Options aus TRACING_*; Endpoint/Header/TLS des OTLP-Exporters kommen aus den Standard-Variablen OTEL_EXPORTER_OTLP_*
+type Options struct {
+	// Exporter: none, otlp oder stdout
+	Exporter    string
+	SampleRatio float64
+	// IncludeKeys: Secret-Keys als Span-Attribut glass.key (Werte nie)
+	IncludeKeys bool
+	// Endpoint/Headers (optional) haben Vorrang vor OTEL_EXPORTER_OTLP_ENDPOINT/_HEADERS
+	Endpoint string
+	Headers  map[string]string
+}
+
+var includeKeys atomic.Bool
+
+// Setup setzt TracerProvider und W3C-Propagator global. Bei Exporter "none" bleibt der No-op-Provider aktiv,
+// eingehender Trace-Context wird aber trotzdem übernommen (und an Webhooks o.ä. weitergereicht, falls instrumentiert).
+func Setup(ctx context.Context, opt Options) (shutdown func(context.Context) error, err error) {
+	otel.SetTextMapPropagator(propagation.NewCompositeTextMapPropagator(propagation.TraceContext{}, propagation.Baggage{}))
+	includeKeys.Store(opt.IncludeKeys)
+
+	var exp sdktrace.SpanExporter
+	switch opt.Exporter {
+	case "none", "":
+		return func(context.Context) error { return nil }, nil
+	case "otlp":
+		var opts []otlptracehttp.Option
+		if opt.Endpoint != "" {
+			opts = append(opts, otlptracehttp.WithEndpointURL(opt.Endpoint))
+		}
+		if len(opt.Headers) > 0 {
+			opts = append(opts, otlptracehttp.WithHeaders(opt.Headers))
+		}
+		exp, err = otlptracehttp.New(ctx, opts...)
+	case "stdout":
+		exp, err = stdouttrace.New(stdouttrace.WithWriter(os.Stdout))
+	default:
+		return nil, fmt.Errorf("invalid tracing exporter: %q", opt.Exporter)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	// OTEL_SERVICE_NAME / OTEL_RESOURCE_ATTRIBUTES überschreiben den Default
+	res, err := resource.New(ctx,
+		resource.WithAttributes(attribute.String("service.name", "glass")),
+		resource.WithFromEnv(),
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	tp := sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(exp),
+		sdktrace.WithResource(res),
+		sdktrace.WithSampler(sdktrace.ParentBased(sdktrace.TraceIDRatioBased(opt.SampleRatio))),
+	)
+	otel.SetTracerProvider(tp)
+	return tp.Shutdown, nil
+}
+
+// ParseHeaders liest Header im Format von OTEL_EXPORTER_OTLP_HEADERS ("k1=v1,k2=v2").
+// Fehler nennen nur die Position, nie den Wert (enthält meist Tokens).
+func ParseHeaders(s string) (map[string]string, error) {
+	if strings.TrimSpace(s) == "" {
+		return nil, nil
+	}
+	h := map[string]string{}
+	for i, pair := range strings.Split(s, ",") {
+		k, v, ok := strings.Cut(pair, "=")
+		k = strings.TrimSpace(k)
+		if !ok || k == "" {
+			return nil, fmt.Errorf("header %d: want key=value", i+1)
+		}
+		h[k] = strings.TrimSpace(v)
+	}
+	return h, nil
+}
+
+// Start beginnt einen Span mit dem glass-Tracer (über den globalen Provider, also auch vor Setup nutzbar)
+func Start(ctx context.Context, name string, opts ...trace.SpanStartOption) (context.Context, trace.Span) {
+	return otel.Tracer(instrumentationName).Start(ctx, name, opts...)
+}
+
+// KeyAttrs liefert glass.key nur, wenn TRACING_INCLUDE_KEYS gesetzt ist
+func KeyAttrs(key string) []attribute.KeyValue {
+	if !includeKeys.Load() {
+		return nil
+	}
+	return []attribute.KeyValue{attribute.String("glass.key", key)}
+}
+
+// SetIncludeKeys ist für Tests; sonst setzt Setup den Wert
+func SetIncludeKeys(v bool) { includeKeys.Store(v) }
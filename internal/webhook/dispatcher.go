@@ -0,0 +1,366 @@
+package webhook
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/timgst1/glass/internal/service"
+)
+
+const (
+	maxAttempts  = 12
+	backoffBase  = 10 * time.Second
+	backoffMax   = time.Hour
+	enqueueBatch = 500
+	deliverBatch = 100
+	// maxParallel: so viele Subscriptions werden gleichzeitig beliefert (pro Subscription weiter in Reihenfolge)
+	maxParallel = 8
+	// failedRetention: so lange bleiben aufgegebene Einträge (status='failed') zur Diagnose liegen
+	failedRetention = 7 * 24 * time.Hour
+)
+
+// ChangeSource liefert den Change-Feed (z.B. SQLiteSecretService, ungesichert)
+type ChangeSource interface {
+	ListChanges(ctx context.Context, prefix string, since int64, limit int) (service.ChangeFeed, error)
+}
+
+// Payload ist der Body einer Zustellung – enthält nie Secret-Werte
+type Payload struct {
+	DeliveryID   int64  `json:"delivery_id"`
+	Subscription string `json:"subscription"`
+	Revision     int64  `json:"revision"`
+	Key          string `json:"key"`
+	Type         string `json:"type"`
+	Version      int64  `json:"version,omitempty"`
+	CreatedAt    string `json:"created_at"`
+	CreatedBy    string `json:"created_by"`
+}
+
+// Dispatcher überträgt den Change-Feed in die Outbox und stellt fällige Einträge zu
+type Dispatcher struct {
+	db       *sql.DB
+	subs     Registry
+	changes  ChangeSource
+	client   *http.Client
+	interval time.Duration
+
+	log *slog.Logger
+}
+
+func NewDispatcher(db *sql.DB, subs Registry, changes ChangeSource, interval time.Duration) *Dispatcher {
+	if interval <= 0 {
+		interval = 5 * time.Second
+	}
+	return &Dispatcher{
+		db:       db,
+		subs:     subs,
+		changes:  changes,
+		client:   &http.Client{Timeout: 10 * time.Second},
+		interval: interval,
+		log:      slog.Default(),
+	}
+}
+
+func (d *Dispatcher) Start(ctx context.Context) {
+	go func() {
+		ticker := time.NewTicker(d.interval)
+		defer ticker.Stop()
+
+		d.runOnce(ctx)
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				d.runOnce(ctx)
+			}
+		}
+	}()
+}
+
+func (d *Dispatcher) runOnce(ctx context.Context) {
+	res, err := d.RunOnce(ctx, time.Now())
+	if err != nil {
+		if ctx.Err() == nil {
+			d.log.Error("webhook dispatch failed", "err", err)
+		}
+		return
+	}
+	if res.Enqueued > 0 || res.Delivered > 0 || res.Failed > 0 || res.Pruned > 0 {
+		d.log.Info("webhook dispatch finished", "enqueued", res.Enqueued, "delivered", res.Delivered, "failed", res.Failed, "pruned", res.Pruned)
+	}
+}
+
+type RunResult struct {
+	Enqueued  int
+	Delivered int
+	// Failed: Zustellversuche, die fehlgeschlagen sind (werden ggf. wiederholt)
+	Failed int
+	// Pruned: aufgegebene Einträge, die älter als failedRetention waren und gelöscht wurden
+	Pruned int
+}
+
+func (d *Dispatcher) RunOnce(ctx context.Context, now time.Time) (RunResult, error) {
+	var res RunResult
+	n, err := d.enqueue(ctx, now)
+	res.Enqueued = n
+	if err != nil {
+		return res, err
+	}
+	res.Delivered, res.Failed, err = d.deliver(ctx, now)
+	if err != nil {
+		return res, err
+	}
+	res.Pruned, err = d.pruneFailed(ctx, now)
+	return res, err
+}
+
+// pruneFailed löscht aufgegebene Einträge; next_attempt_at liegt bei ihnen höchstens backoffMax nach dem letzten Versuch
+func (d *Dispatcher) pruneFailed(ctx context.Context, now time.Time) (int, error) {
+	r, err := d.db.ExecContext(ctx, `DELETE FROM webhook_outbox WHERE status = 'failed' AND next_attempt_at <= ?`,
+		now.Add(-failedRetention).UnixMilli())
+	if err != nil {
+		return 0, err
+	}
+	n, err := r.RowsAffected()
+	return int(n), err
+}
+
+// enqueue liest neue Änderungen ab dem gespeicherten Cursor und legt pro passender Subscription einen Outbox-Eintrag an.
+// Outbox-Einträge und Cursor werden in einer Tx geschrieben -> nach einem Neustart geht nichts verloren und nichts doppelt rein.
+func (d *Dispatcher) enqueue(ctx context.Context, now time.Time) (int, error) {
+	var cursor int64
+	err := d.db.QueryRowContext(ctx, `SELECT revision FROM webhook_cursor WHERE id = 1`).Scan(&cursor)
+	if errors.Is(err, sql.ErrNoRows) {
+		// erster Start: bei der aktuellen Revision beginnen statt die ganze Historie zuzustellen
+		feed, err := d.changes.ListChanges(ctx, "", -1, 1)
+		if err != nil {
+			return 0, err
+		}
+		cursor = feed.Revision
+		if _, err := d.db.ExecContext(ctx, `INSERT INTO webhook_cursor(id, revision) VALUES(1, ?)`, cursor); err != nil {
+			return 0, err
+		}
+	} else if err != nil {
+		return 0, err
+	}
+
+	subs, err := d.subs.List(ctx)
+	if err != nil {
+		return 0, err
+	}
+
+	total := 0
+	for {
+		feed, err := d.changes.ListChanges(ctx, "", cursor, enqueueBatch)
+		if err != nil {
+			return total, err
+		}
+		if feed.Revision == cursor {
+			return total, nil
+		}
+
+		n, err := d.enqueueFeed(ctx, subs, feed, now)
+		if err != nil {
+			return total, err
+		}
+		total += n
+		cursor = feed.Revision
+		if !feed.More {
+			return total, nil
+		}
+	}
+}
+
+func (d *Dispatcher) enqueueFeed(ctx context.Context, subs []Subscription, feed service.ChangeFeed, now time.Time) (int, error) {
+	tx, err := d.db.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, err
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	n := 0
+	for _, c := range feed.Changes {
+		for _, s := range subs {
+			if !s.Matches(c) {
+				continue
+			}
+			_, err := tx.ExecContext(ctx, `
+INSERT INTO webhook_outbox(subscription_id, revision, event, key, version, created_at, created_by, next_attempt_at)
+VALUES(?, ?, ?, ?, ?, ?, ?, ?)`,
+				s.ID, c.Revision, string(c.Type), c.Key, c.Version, c.CreatedAt, c.CreatedBy, now.UnixMilli(),
+			)
+			if err != nil {
+				return 0, err
+			}
+			n++
+		}
+	}
+	if _, err := tx.ExecContext(ctx, `UPDATE webhook_cursor SET revision = ? WHERE id = 1`, feed.Revision); err != nil {
+		return 0, err
+	}
+	return n, tx.Commit()
+}
+
+type outboxRow struct {
+	Payload
+	Attempts int
+}
+
+func (d *Dispatcher) deliver(ctx context.Context, now time.Time) (delivered, failed int, err error) {
+	rows, err := d.db.QueryContext(ctx, `
+SELECT id, subscription_id, revision, event, key, version, created_at, created_by, attempts
+FROM webhook_outbox
+WHERE status = 'pending' AND next_attempt_at <= ?
+ORDER BY id
+LIMIT ?`, now.UnixMilli(), deliverBatch)
+	if err != nil {
+		return 0, 0, err
+	}
+	var due []outboxRow
+	for rows.Next() {
+		var r outboxRow
+		if err := rows.Scan(&r.DeliveryID, &r.Subscription, &r.Revision, &r.Type, &r.Key, &r.Version, &r.CreatedAt, &r.CreatedBy, &r.Attempts); err != nil {
+			rows.Close()
+			return 0, 0, err
+		}
+		due = append(due, r)
+	}
+	if err := rows.Err(); err != nil {
+		rows.Close()
+		return 0, 0, err
+	}
+	_ = rows.Close()
+
+	// pro Subscription in Reihenfolge, Subscriptions parallel: ein hängender Endpoint bremst die anderen nicht aus
+	var order []string
+	bySub := map[string][]outboxRow{}
+	for _, r := range due {
+		if _, ok := bySub[r.Subscription]; !ok {
+			order = append(order, r.Subscription)
+		}
+		bySub[r.Subscription] = append(bySub[r.Subscription], r)
+	}
+
+	var (
+		mu       sync.Mutex
+		firstErr error
+		wg       sync.WaitGroup
+		sem      = make(chan struct{}, maxParallel)
+	)
+	for _, id := range order {
+		wg.Add(1)
+		sem <- struct{}{}
+		go func(rows []outboxRow) {
+			defer wg.Done()
+			defer func() { <-sem }()
+			ok, nok, err := d.deliverSubscription(ctx, rows, now)
+			mu.Lock()
+			defer mu.Unlock()
+			delivered += ok
+			failed += nok
+			if err != nil && firstErr == nil {
+				firstErr = err
+			}
+		}(bySub[id])
+	}
+	wg.Wait()
+	return delivered, failed, firstErr
+}
+
+// deliverSubscription stellt die fälligen Einträge einer Subscription nacheinander zu. Nach dem ersten Fehlschlag
+// bleiben die übrigen für den nächsten Lauf liegen (Reihenfolge bleibt erhalten, kein zweiter Timeout pro Lauf).
+func (d *Dispatcher) deliverSubscription(ctx context.Context, rows []outboxRow, now time.Time) (delivered, failed int, err error) {
+	sub, err := d.subs.Get(ctx, rows[0].Subscription)
+	if errors.Is(err, service.ErrNotFound) {
+		for _, r := range rows {
+			if _, err := d.db.ExecContext(ctx, `DELETE FROM webhook_outbox WHERE id = ?`, r.DeliveryID); err != nil {
+				return 0, 0, err
+			}
+		}
+		return 0, 0, nil
+	}
+	if err != nil {
+		return 0, 0, err
+	}
+
+	for _, r := range rows {
+		if derr := d.send(ctx, sub, r.Payload); derr != nil {
+			if ctx.Err() != nil {
+				return delivered, failed, ctx.Err()
+			}
+			failed++
+			return delivered, failed, d.markFailed(ctx, r, derr, now)
+		}
+		delivered++
+		if _, err := d.db.ExecContext(ctx, `DELETE FROM webhook_outbox WHERE id = ?`, r.DeliveryID); err != nil {
+			return delivered, failed, err
+		}
+	}
+	return delivered, failed, nil
+}
+
+// send signiert mit der aktuellen Zeit (nicht der des Laufs), damit Empfänger mit knapper Toleranz nicht ablehnen
+func (d *Dispatcher) send(ctx context.Context, sub Subscription, p Payload) error {
+	body, err := json.Marshal(p)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, sub.URL, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "glass-webhook")
+	req.Header.Set("X-Glass-Event", p.Type)
+	req.Header.Set("X-Glass-Delivery", strconv.FormatInt(p.DeliveryID, 10))
+	req.Header.Set(SignatureHeader, Sign(sub.Secret, time.Now(), body))
+
+	resp, err := d.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	_, _ = io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10))
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("unexpected status %d", resp.StatusCode)
+	}
+	return nil
+}
+
+// markFailed plant den nächsten Versuch mit exponentiellem Backoff; nach maxAttempts bleibt der Eintrag als 'failed' liegen
+func (d *Dispatcher) markFailed(ctx context.Context, r outboxRow, cause error, now time.Time) error {
+	attempts := r.Attempts + 1
+	status := "pending"
+	if attempts >= maxAttempts {
+		status = "failed"
+		d.log.Warn("webhook delivery gave up", "subscription", r.Subscription, "delivery", r.DeliveryID, "err", cause)
+	}
+	_, err := d.db.ExecContext(ctx, `
+UPDATE webhook_outbox SET attempts = ?, status = ?, next_attempt_at = ?, last_error = ? WHERE id = ?`,
+		attempts, status, now.Add(Backoff(attempts)).UnixMilli(), cause.Error(), r.DeliveryID,
+	)
+	return err
+}
+
+// Backoff liefert die Wartezeit nach dem n-ten Fehlversuch: 10s, 20s, 40s, ... max. 1h
+func Backoff(attempts int) time.Duration {
+	d := backoffBase
+	for i := 1; i < attempts; i++ {
+		d *= 2
+		if d >= backoffMax {
+			return backoffMax
+		}
+	}
+	return d
+}
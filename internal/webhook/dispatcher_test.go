@@ -0,0 +1,305 @@
+package webhook_test
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"encoding/base64"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/timgst1/glass/internal/admin"
+	"github.com/timgst1/glass/internal/crypto/envelope"
+	"github.com/timgst1/glass/internal/service"
+	"github.com/timgst1/glass/internal/storage/sqlite"
+	"github.com/timgst1/glass/internal/webhook"
+)
+
+func openDB(t *testing.T) *sql.DB {
+	t.Helper()
+	db, err := sqlite.Open(filepath.Join(t.TempDir(), "glass.db"))
+	if err != nil {
+		t.Fatalf("sqlite.Open: %v", err)
+	}
+	t.Cleanup(func() { _ = db.Close() })
+	if err := sqlite.Migrate(db); err != nil {
+		t.Fatalf("sqlite.Migrate: %v", err)
+	}
+	return db
+}
+
+type receiver struct {
+	mu     sync.Mutex
+	status int
+	bodies [][]byte
+	sigs   []string
+}
+
+func (rc *receiver) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	b, _ := io.ReadAll(r.Body)
+	rc.mu.Lock()
+	defer rc.mu.Unlock()
+	rc.bodies = append(rc.bodies, b)
+	rc.sigs = append(rc.sigs, r.Header.Get(webhook.SignatureHeader))
+	w.WriteHeader(rc.status)
+}
+
+func TestDispatcher_DeliversSignedEventsWithRetry(t *testing.T) {
+	db := openDB(t)
+	svc := service.NewSQLiteSecretService(db, nil)
+	ctx := context.Background()
+
+	rc := &receiver{status: http.StatusInternalServerError}
+	srv := httptest.NewServer(rc)
+	defer srv.Close()
+
+	const secret = "0123456789abcdef-secret"
+	store := webhook.NewStore(db, nil, nil)
+	if _, err := store.Create(ctx, webhook.Subscription{ID: "ci", URL: srv.URL, KeyPrefix: "team-a/", Events: []string{"put"}, Secret: secret}); err != nil {
+		t.Fatalf("Create: %v", err)
+	}
+
+	// vorhandene Historie wird beim ersten Lauf nicht zugestellt
+	if _, err := svc.PutSecret(ctx, "team-a/db", "before"); err != nil {
+		t.Fatalf("PutSecret: %v", err)
+	}
+	now := time.Now()
+	d := webhook.NewDispatcher(db, store, svc, time.Second)
+	if res, err := d.RunOnce(ctx, now); err != nil || res.Enqueued != 0 {
+		t.Fatalf("first run: %+v %v", res, err)
+	}
+
+	if _, err := svc.PutSecret(ctx, "team-a/db", "super-secret-value"); err != nil {
+		t.Fatalf("PutSecret: %v", err)
+	}
+	if _, err := svc.PutSecret(ctx, "team-b/db", "x"); err != nil {
+		t.Fatalf("PutSecret: %v", err)
+	}
+	if _, err := svc.DeleteSecret(ctx, "team-a/db"); err != nil {
+		t.Fatalf("DeleteSecret: %v", err)
+	}
+
+	res, err := d.RunOnce(ctx, now)
+	if err != nil {
+		t.Fatalf("RunOnce: %v", err)
+	}
+	if res.Enqueued != 1 || res.Delivered != 0 || res.Failed != 1 {
+		t.Fatalf("expected 1 enqueued + 1 failed attempt, got %+v", res)
+	}
+
+	// Backoff: noch nicht fällig
+	if res, _ := d.RunOnce(ctx, now.Add(5*time.Second)); res.Failed != 0 || res.Delivered != 0 {
+		t.Fatalf("expected no attempt during backoff, got %+v", res)
+	}
+
+	// "Neustart": neuer Dispatcher auf derselben DB stellt die Outbox zu
+	rc.mu.Lock()
+	rc.status = http.StatusNoContent
+	rc.mu.Unlock()
+	d2 := webhook.NewDispatcher(db, store, svc, time.Second)
+	res, err = d2.RunOnce(ctx, now.Add(webhook.Backoff(1)))
+	if err != nil || res.Delivered != 1 || res.Enqueued != 0 {
+		t.Fatalf("expected delivery after restart, got %+v %v", res, err)
+	}
+
+	rc.mu.Lock()
+	defer rc.mu.Unlock()
+	if len(rc.bodies) != 2 {
+		t.Fatalf("expected 2 attempts, got %d", len(rc.bodies))
+	}
+	body := rc.bodies[1]
+	if err := webhook.Verify(secret, rc.sigs[1], body, time.Now(), time.Minute); err != nil {
+		t.Fatalf("Verify: %v", err)
+	}
+	if err := webhook.Verify("wrong-secret-wrong-secret", rc.sigs[1], body, time.Now(), 0); err == nil {
+		t.Fatalf("expected signature mismatch with wrong secret")
+	}
+	if strings.Contains(string(body), "super-secret-value") {
+		t.Fatalf("payload must not contain secret values: %s", body)
+	}
+	var p webhook.Payload
+	if err := json.Unmarshal(body, &p); err != nil {
+		t.Fatalf("decode payload: %v", err)
+	}
+	if p.Subscription != "ci" || p.Key != "team-a/db" || p.Type != "put" || p.Version != 2 {
+		t.Fatalf("unexpected payload %+v", p)
+	}
+}
+
+func TestBackoff(t *testing.T) {
+	if webhook.Backoff(1) != 10*time.Second || webhook.Backoff(3) != 40*time.Second || webhook.Backoff(20) != time.Hour {
+		t.Fatalf("unexpected backoff: %v %v %v", webhook.Backoff(1), webhook.Backoff(3), webhook.Backoff(20))
+	}
+}
+
+func TestLoadFromFile(t *testing.T) {
+	dir := t.TempDir()
+	secretPath := filepath.Join(dir, "hmac")
+	if err := os.WriteFile(secretPath, []byte("0123456789abcdef0123\n"), 0o600); err != nil {
+		t.Fatalf("write: %v", err)
+	}
+	cfg := filepath.Join(dir, "webhooks.yaml")
+	if err := os.WriteFile(cfg, []byte(`
+subscriptions:
+  - id: restart-app
+    url: https://hooks.example.com/glass
+    keyPrefix: team-a/
+    events: [put, delete]
+    secretFile: `+secretPath+`
+`), 0o600); err != nil {
+		t.Fatalf("write: %v", err)
+	}
+	subs, err := webhook.LoadFromFile(cfg)
+	if err != nil {
+		t.Fatalf("LoadFromFile: %v", err)
+	}
+	if len(subs) != 1 || subs[0].Secret != "0123456789abcdef0123" || subs[0].Source != "file" {
+		t.Fatalf("unexpected subs: %+v", subs)
+	}
+
+	store := webhook.NewStore(openDB(t), subs, nil)
+	if err := store.Delete(context.Background(), "restart-app"); err == nil {
+		t.Fatalf("expected file subscription to be read-only")
+	}
+
+	for _, bad := range []webhook.Subscription{
+		{ID: "x", URL: "ftp://example.com", Secret: "0123456789abcdef"},
+		{ID: "x", URL: "https://example.com", Secret: "short"},
+		{ID: "x", URL: "https://example.com", Secret: "0123456789abcdef", Events: []string{"read"}},
+		{ID: "bad id", URL: "https://example.com", Secret: "0123456789abcdef"},
+	} {
+		if err := webhook.Validate(bad); err == nil {
+			t.Fatalf("expected validation error for %+v", bad)
+		}
+	}
+}
+
+func TestStore_EncryptsAPISecretsAndRewraps(t *testing.T) {
+	db := openDB(t)
+	ctx := context.Background()
+
+	dir := t.TempDir()
+	for id, fill := range map[string]byte{"kek-1": 0x11, "kek-2": 0x22} {
+		raw := base64.StdEncoding.EncodeToString(bytes.Repeat([]byte{fill}, 32))
+		if err := os.WriteFile(filepath.Join(dir, id), []byte(raw), 0o600); err != nil {
+			t.Fatalf("write kek: %v", err)
+		}
+	}
+	keyring := func(active string) *envelope.Envelope {
+		kr, err := envelope.LoadKeyring(dir, active)
+		if err != nil {
+			t.Fatalf("LoadKeyring: %v", err)
+		}
+		return envelope.New(kr)
+	}
+
+	const secret = "0123456789abcdef-hmac"
+	if _, err := webhook.NewStore(db, nil, keyring("kek-1")).Create(ctx, webhook.Subscription{ID: "ci", URL: "https://ci.example.com", Secret: secret}); err != nil {
+		t.Fatalf("Create: %v", err)
+	}
+
+	var stored, kekID string
+	var enc int
+	if err := db.QueryRow(`SELECT secret, secret_enc, secret_kek_id FROM webhook_subscriptions WHERE id = 'ci'`).Scan(&stored, &enc, &kekID); err != nil {
+		t.Fatalf("select: %v", err)
+	}
+	if enc != 1 || kekID != "kek-1" || strings.Contains(stored, secret) {
+		t.Fatalf("secret must be stored encrypted, got enc=%d kek=%q secret=%q", enc, kekID, stored)
+	}
+
+	// nach dem Rewrap auf kek-2 reicht ein Keyring ohne kek-1
+	env2 := keyring("kek-2")
+	res, err := admin.RewrapKEK(ctx, db, env2, admin.RewrapKEKOptions{FromKEKID: "kek-1", ToKEKID: "kek-2"})
+	if err != nil || res.Updated != 1 {
+		t.Fatalf("RewrapKEK: %+v %v", res, err)
+	}
+	if err := os.Remove(filepath.Join(dir, "kek-1")); err != nil {
+		t.Fatalf("remove kek-1: %v", err)
+	}
+	sub, err := webhook.NewStore(db, nil, keyring("kek-2")).Get(ctx, "ci")
+	if err != nil || sub.Secret != secret {
+		t.Fatalf("expected decrypted secret after rewrap, got %q %v", sub.Secret, err)
+	}
+
+	// ohne Encryption lässt sich das Secret nicht lesen (statt Ciphertext als HMAC-Key zu verwenden)
+	if _, err := webhook.NewStore(db, nil, nil).Get(ctx, "ci"); err == nil {
+		t.Fatalf("expected error without envelope")
+	}
+}
+
+func TestDispatcher_SlowEndpointDoesNotBlockOthersAndFailedArePruned(t *testing.T) {
+	db := openDB(t)
+	svc := service.NewSQLiteSecretService(db, nil)
+	ctx := context.Background()
+
+	release := make(chan struct{})
+	slow := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-release
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer slow.Close()
+	fast := &receiver{status: http.StatusNoContent}
+	fastSrv := httptest.NewServer(fast)
+	defer fastSrv.Close()
+
+	store := webhook.NewStore(db, nil, nil)
+	for id, url := range map[string]string{"slow": slow.URL, "fast": fastSrv.URL} {
+		if _, err := store.Create(ctx, webhook.Subscription{ID: id, URL: url, Secret: "0123456789abcdef-" + id}); err != nil {
+			t.Fatalf("Create: %v", err)
+		}
+	}
+	d := webhook.NewDispatcher(db, store, svc, time.Second)
+	if _, err := d.RunOnce(ctx, time.Now()); err != nil {
+		t.Fatalf("first run: %v", err)
+	}
+	if _, err := svc.PutSecret(ctx, "team-a/db", "x"); err != nil {
+		t.Fatalf("PutSecret: %v", err)
+	}
+
+	// aufgegebener Eintrag von vor 8 Tagen
+	old := time.Now().Add(-8 * 24 * time.Hour).UnixMilli()
+	if _, err := db.Exec(`INSERT INTO webhook_outbox(subscription_id, revision, event, key, created_at, created_by, status, attempts, next_attempt_at)
+VALUES('fast', 0, 'put', 'team-a/old', '', '', 'failed', 12, ?)`, old); err != nil {
+		t.Fatalf("insert failed row: %v", err)
+	}
+
+	done := make(chan webhook.RunResult)
+	go func() {
+		res, _ := d.RunOnce(ctx, time.Now())
+		done <- res
+	}()
+
+	// fast wird zugestellt, während slow noch hängt
+	deadline := time.Now().Add(2 * time.Second)
+	for {
+		fast.mu.Lock()
+		n := len(fast.bodies)
+		fast.mu.Unlock()
+		if n == 1 {
+			break
+		}
+		if time.Now().After(deadline) {
+			close(release)
+			t.Fatalf("fast subscription blocked by slow endpoint")
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+	close(release)
+
+	res := <-done
+	if res.Delivered != 2 || res.Pruned != 1 {
+		t.Fatalf("expected 2 deliveries and 1 pruned row, got %+v", res)
+	}
+	var left int
+	if err := db.QueryRow(`SELECT COUNT(*) FROM webhook_outbox`).Scan(&left); err != nil || left != 0 {
+		t.Fatalf("expected empty outbox, got %d %v", left, err)
+	}
+}
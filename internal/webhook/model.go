@@ -0,0 +1,115 @@
+package webhook
+
+import (
+	"fmt"
+	"net/url"
+	"os"
+	"regexp"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+
+	"github.com/timgst1/glass/internal/service"
+)
+
+// Subscription beschreibt einen Webhook-Empfänger für Änderungen unter KeyPrefix
+type Subscription struct {
+	ID        string `yaml:"id"`
+	URL       string `yaml:"url"`
+	KeyPrefix string `yaml:"keyPrefix"`
+	// Events filtert nach Change-Typ (put, delete, undelete, destroy, metadata); leer = alle
+	Events []string `yaml:"events"`
+	// Secret ist der HMAC-Schlüssel; in Dateien besser SecretFile (z.B. K8s-Secret-Mount) verwenden
+	Secret     string `yaml:"secret"`
+	SecretFile string `yaml:"secretFile"`
+
+	// Source: "file" (read-only) oder "api"
+	Source    string `yaml:"-"`
+	CreatedAt string `yaml:"-"`
+	CreatedBy string `yaml:"-"`
+}
+
+// Matches prüft, ob eine Änderung an diese Subscription geht
+func (s Subscription) Matches(c service.Change) bool {
+	if !strings.HasPrefix(c.Key, s.KeyPrefix) {
+		return false
+	}
+	if len(s.Events) == 0 {
+		return true
+	}
+	for _, e := range s.Events {
+		if e == string(c.Type) {
+			return true
+		}
+	}
+	return false
+}
+
+// Config ist das Format von WEBHOOKS_FILE
+type Config struct {
+	Subscriptions []Subscription `yaml:"subscriptions"`
+}
+
+func LoadFromFile(path string) ([]Subscription, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var c Config
+	if err := yaml.Unmarshal(b, &c); err != nil {
+		return nil, err
+	}
+
+	seen := map[string]bool{}
+	for i := range c.Subscriptions {
+		s := &c.Subscriptions[i]
+		if s.SecretFile != "" {
+			if s.Secret != "" {
+				return nil, fmt.Errorf("webhook %q: only one of secret or secretFile may be set", s.ID)
+			}
+			sb, err := os.ReadFile(s.SecretFile)
+			if err != nil {
+				return nil, fmt.Errorf("webhook %q: %w", s.ID, err)
+			}
+			s.Secret = strings.TrimSpace(string(sb))
+		}
+		s.Source = "file"
+		if err := Validate(*s); err != nil {
+			return nil, err
+		}
+		if seen[s.ID] {
+			return nil, fmt.Errorf("webhook: duplicate id %q", s.ID)
+		}
+		seen[s.ID] = true
+	}
+	return c.Subscriptions, nil
+}
+
+var idPattern = regexp.MustCompile(`^[A-Za-z0-9_-]{1,64}$`)
+
+var validEvents = map[string]bool{
+	string(service.ChangePut):      true,
+	string(service.ChangeDelete):   true,
+	string(service.ChangeUndelete): true,
+	string(service.ChangeDestroy):  true,
+	string(service.ChangeMetadata): true,
+}
+
+func Validate(s Subscription) error {
+	if !idPattern.MatchString(s.ID) {
+		return fmt.Errorf("webhook: invalid id %q (allowed: A-Z a-z 0-9 _ -, max 64)", s.ID)
+	}
+	u, err := url.Parse(s.URL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return fmt.Errorf("webhook %q: url must be an absolute http(s) URL", s.ID)
+	}
+	for _, e := range s.Events {
+		if !validEvents[e] {
+			return fmt.Errorf("webhook %q: invalid event %q", s.ID, e)
+		}
+	}
+	if len(s.Secret) < 16 {
+		return fmt.Errorf("webhook %q: secret must be at least 16 characters", s.ID)
+	}
+	return nil
+}
@@ -0,0 +1,78 @@
+package webhook
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/timgst1/glass/internal/audit"
+	"github.com/timgst1/glass/internal/authn"
+	"github.com/timgst1/glass/internal/authz"
+	"github.com/timgst1/glass/internal/service"
+)
+
+// SecuredRegistry verlangt admin auf den KeyPrefix der Subscription
+type SecuredRegistry struct {
+	inner Registry
+	az    authz.Authorizer
+}
+
+func NewSecuredRegistry(inner Registry, az authz.Authorizer) *SecuredRegistry {
+	return &SecuredRegistry{inner: inner, az: az}
+}
+
+func (r *SecuredRegistry) authorize(ctx context.Context, prefix string) error {
+	sub, ok := authn.SubjectFromContext(ctx)
+	if !ok {
+		return fmt.Errorf("%w: subject missing", service.ErrForbidden)
+	}
+	dec := r.az.Evaluate(sub, authz.ActionAdmin, prefix)
+	audit.NoteDecision(ctx, authz.ActionAdmin, prefix, dec)
+	if !dec.Allowed {
+		return fmt.Errorf("%w: %s", service.ErrForbidden, dec.Reason)
+	}
+	return nil
+}
+
+// List liefert nur Subscriptions, deren Prefix der Aufrufer administrieren darf
+func (r *SecuredRegistry) List(ctx context.Context) ([]Subscription, error) {
+	sub, ok := authn.SubjectFromContext(ctx)
+	if !ok {
+		return nil, fmt.Errorf("%w: subject missing", service.ErrForbidden)
+	}
+	all, err := r.inner.List(ctx)
+	if err != nil {
+		return nil, err
+	}
+	out := []Subscription{}
+	for _, s := range all {
+		if r.az.Evaluate(sub, authz.ActionAdmin, s.KeyPrefix).Allowed {
+			out = append(out, s)
+		}
+	}
+	return out, nil
+}
+
+func (r *SecuredRegistry) Get(ctx context.Context, id string) (Subscription, error) {
+	s, err := r.inner.Get(ctx, id)
+	if err != nil {
+		return Subscription{}, err
+	}
+	if err := r.authorize(ctx, s.KeyPrefix); err != nil {
+		return Subscription{}, err
+	}
+	return s, nil
+}
+
+func (r *SecuredRegistry) Create(ctx context.Context, s Subscription) (Subscription, error) {
+	if err := r.authorize(ctx, s.KeyPrefix); err != nil {
+		return Subscription{}, err
+	}
+	return r.inner.Create(ctx, s)
+}
+
+func (r *SecuredRegistry) Delete(ctx context.Context, id string) error {
+	if _, err := r.Get(ctx, id); err != nil {
+		return err
+	}
+	return r.inner.Delete(ctx, id)
+}
@@ -0,0 +1,57 @@
+package webhook
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// SignatureHeader enthält "t=<unix>,v1=<hex(HMAC-SHA256(secret, "<unix>.<body>"))>"
+const SignatureHeader = "X-Glass-Signature"
+
+func Sign(secret string, ts time.Time, body []byte) string {
+	t := strconv.FormatInt(ts.Unix(), 10)
+	return "t=" + t + ",v1=" + mac(secret, t, body)
+}
+
+// Verify prüft eine Signatur (für Empfänger in Go und für Tests); tolerance 0 = Zeitstempel nicht prüfen
+func Verify(secret, header string, body []byte, now time.Time, tolerance time.Duration) error {
+	var t, v1 string
+	for _, part := range strings.Split(header, ",") {
+		k, v, _ := strings.Cut(strings.TrimSpace(part), "=")
+		switch k {
+		case "t":
+			t = v
+		case "v1":
+			v1 = v
+		}
+	}
+	if t == "" || v1 == "" {
+		return fmt.Errorf("webhook signature: malformed header")
+	}
+	if tolerance > 0 {
+		unix, err := strconv.ParseInt(t, 10, 64)
+		if err != nil {
+			return fmt.Errorf("webhook signature: invalid timestamp")
+		}
+		if d := now.Sub(time.Unix(unix, 0)); d > tolerance || d < -tolerance {
+			return fmt.Errorf("webhook signature: timestamp outside tolerance")
+		}
+	}
+	if !hmac.Equal([]byte(v1), []byte(mac(secret, t, body))) {
+		return fmt.Errorf("webhook signature: mismatch")
+	}
+	return nil
+}
+
+func mac(secret, t string, body []byte) string {
+	m := hmac.New(sha256.New, []byte(secret))
+	m.Write([]byte(t))
+	m.Write([]byte("."))
+	m.Write(body)
+	return hex.EncodeToString(m.Sum(nil))
+}
@@ -0,0 +1,205 @@
+package webhook
+
+import (
+	"context"
+	"crypto/rand"
+	"database/sql"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/timgst1/glass/internal/authn"
+	"github.com/timgst1/glass/internal/crypto/envelope"
+	"github.com/timgst1/glass/internal/service"
+)
+
+// Registry verwaltet Subscriptions (Admin-API)
+type Registry interface {
+	List(ctx context.Context) ([]Subscription, error)
+	Get(ctx context.Context, id string) (Subscription, error)
+	// Create legt eine Subscription an; leere ID/Secret werden generiert
+	Create(ctx context.Context, s Subscription) (Subscription, error)
+	Delete(ctx context.Context, id string) error
+}
+
+// Store hält Subscriptions aus der Datei (read-only) und aus SQLite (per API angelegt).
+// Mit enc werden die HMAC-Secrets der API-Subscriptions wie Secret-Werte per Envelope verschlüsselt gespeichert.
+type Store struct {
+	db   *sql.DB
+	file []Subscription
+	enc  *envelope.Envelope
+}
+
+func NewStore(db *sql.DB, fileSubs []Subscription, enc *envelope.Envelope) *Store {
+	return &Store{db: db, file: fileSubs, enc: enc}
+}
+
+// SecretAADKey bindet das verschlüsselte HMAC-Secret an die Subscription (AAD wie key/version bei Secrets)
+func SecretAADKey(id string) string { return "webhook:" + id }
+
+func (s *Store) List(ctx context.Context) ([]Subscription, error) {
+	out := append([]Subscription{}, s.file...)
+
+	rows, err := s.db.QueryContext(ctx, `SELECT `+subscriptionColumns+` FROM webhook_subscriptions`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		sub, err := s.scanSubscription(ctx, rows)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, sub)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	sort.Slice(out, func(i, j int) bool { return out[i].ID < out[j].ID })
+	return out, nil
+}
+
+func (s *Store) Get(ctx context.Context, id string) (Subscription, error) {
+	for _, f := range s.file {
+		if f.ID == id {
+			return f, nil
+		}
+	}
+	row := s.db.QueryRowContext(ctx, `SELECT `+subscriptionColumns+` FROM webhook_subscriptions WHERE id = ?`, id)
+	sub, err := s.scanSubscription(ctx, row)
+	if errors.Is(err, sql.ErrNoRows) {
+		return Subscription{}, service.ErrNotFound
+	}
+	return sub, err
+}
+
+func (s *Store) Create(ctx context.Context, sub Subscription) (Subscription, error) {
+	if sub.ID == "" {
+		sub.ID = "wh_" + randomHex(8)
+	}
+	if sub.Secret == "" {
+		sub.Secret = randomHex(32)
+	}
+	sub.SecretFile = ""
+	sub.Source = "api"
+	sub.CreatedAt = time.Now().UTC().Format(time.RFC3339Nano)
+	sub.CreatedBy = subjectString(ctx)
+	if err := Validate(sub); err != nil {
+		return Subscription{}, fmt.Errorf("%w: %s", service.ErrInvalid, err)
+	}
+	for _, f := range s.file {
+		if f.ID == sub.ID {
+			return Subscription{}, fmt.Errorf("%w: webhook %q already exists", service.ErrConflict, sub.ID)
+		}
+	}
+
+	events, err := json.Marshal(orEmpty(sub.Events))
+	if err != nil {
+		return Subscription{}, err
+	}
+	ev := envelope.EncryptedValue{Ciphertext: sub.Secret}
+	if s.enc != nil {
+		ev, err = s.enc.Encrypt(ctx, SecretAADKey(sub.ID), 0, []byte(sub.Secret))
+		if err != nil {
+			return Subscription{}, err
+		}
+	}
+	_, err = s.db.ExecContext(ctx, `
+INSERT INTO webhook_subscriptions(id, url, key_prefix, events, secret, secret_enc, secret_nonce, secret_wrapped_dek, secret_wrap_nonce, secret_kek_id, created_at, created_by)
+VALUES(?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+		sub.ID, sub.URL, sub.KeyPrefix, string(events), ev.Ciphertext, ev.Enc, ev.Nonce, ev.WrappedDEK, ev.WrapNonce, ev.KekID, sub.CreatedAt, sub.CreatedBy,
+	)
+	if err != nil {
+		if strings.Contains(err.Error(), "UNIQUE constraint failed") {
+			return Subscription{}, fmt.Errorf("%w: webhook %q already exists", service.ErrConflict, sub.ID)
+		}
+		return Subscription{}, err
+	}
+	return sub, nil
+}
+
+func (s *Store) Delete(ctx context.Context, id string) error {
+	for _, f := range s.file {
+		if f.ID == id {
+			return fmt.Errorf("%w: webhook %q is defined in WEBHOOKS_FILE", service.ErrConflict, id)
+		}
+	}
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	res, err := tx.ExecContext(ctx, `DELETE FROM webhook_subscriptions WHERE id = ?`, id)
+	if err != nil {
+		return err
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return service.ErrNotFound
+	}
+	// offene und aufgegebene Zustellungen verwerfen
+	if _, err := tx.ExecContext(ctx, `DELETE FROM webhook_outbox WHERE subscription_id = ?`, id); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+type rowScanner interface {
+	Scan(dest ...any) error
+}
+
+const subscriptionColumns = `id, url, key_prefix, events, secret, secret_enc, secret_nonce, secret_wrapped_dek, secret_wrap_nonce, secret_kek_id, created_at, created_by`
+
+// scanSubscription entschlüsselt das HMAC-Secret; secret_enc=0 (ohne Encryption angelegt) bleibt Klartext
+func (s *Store) scanSubscription(ctx context.Context, r rowScanner) (Subscription, error) {
+	var (
+		sub    Subscription
+		events string
+		ev     envelope.EncryptedValue
+	)
+	if err := r.Scan(&sub.ID, &sub.URL, &sub.KeyPrefix, &events, &ev.Ciphertext, &ev.Enc, &ev.Nonce, &ev.WrappedDEK, &ev.WrapNonce, &ev.KekID, &sub.CreatedAt, &sub.CreatedBy); err != nil {
+		return Subscription{}, err
+	}
+	if err := json.Unmarshal([]byte(events), &sub.Events); err != nil {
+		return Subscription{}, err
+	}
+	sub.Secret = ev.Ciphertext
+	if ev.Enc == 1 {
+		if s.enc == nil {
+			return Subscription{}, fmt.Errorf("webhook %q: secret is encrypted but encryption is not configured", sub.ID)
+		}
+		pt, err := s.enc.Decrypt(ctx, SecretAADKey(sub.ID), 0, ev)
+		if err != nil {
+			return Subscription{}, fmt.Errorf("webhook %q: decrypt secret: %w", sub.ID, err)
+		}
+		sub.Secret = string(pt)
+	}
+	sub.Source = "api"
+	return sub, nil
+}
+
+func orEmpty(s []string) []string {
+	if s == nil {
+		return []string{}
+	}
+	return s
+}
+
+func randomHex(n int) string {
+	b := make([]byte, n)
+	_, _ = rand.Read(b)
+	return hex.EncodeToString(b)
+}
+
+func subjectString(ctx context.Context) string {
+	if sub, ok := authn.SubjectFromContext(ctx); ok {
+		return sub.Kind + ":" + sub.Name
+	}
+	return "unknown"
+}